@@ -23,6 +23,7 @@ type Expression interface {
 
 type Program struct {
 	Statements []Statement
+	Comments   []*Comment // every comment in the source, in order
 }
 
 func (p *Program) TokenLiteral() string {
@@ -43,6 +44,15 @@ func (p *Program) String() string {
 	return out.String()
 }
 
+// for // comments. these aren't part of any statement, they are only kept
+// so that tools like the formatter can print them again
+type Comment struct {
+	Token token.Token // the token.COMMENT token, Literal includes the //
+}
+
+func (c *Comment) TokenLiteral() string { return c.Token.Literal }
+func (c *Comment) String() string       { return c.Token.Literal }
+
 // for expressions
 type ExpressionStatement struct {
 	Token      token.Token
@@ -124,10 +134,14 @@ func (num *IntegerLiteral) String() string       { return num.Token.Literal }
 // for let statements
 type LetStatement struct {
 	Token token.Token
-	Name  *Identifier
+	Name  Expression // an *Identifier or an array or hash pattern, see MatchArm
 	Value Expression
 }
 
+// IsConst reports whether the statement is a const declaration, which is
+// parsed into a LetStatement with the const token
+func (ls *LetStatement) IsConst() bool { return ls.Token.Type == token.CONST }
+
 func (ls *LetStatement) statementNode()       {}
 func (ls *LetStatement) TokenLiteral() string { return ls.Token.Literal }
 func (ls *LetStatement) String() string {
@@ -197,8 +211,9 @@ func (ie *IfExpression) String() string {
 }
 
 type BlockStatement struct {
-	Token      token.Token
+	Token      token.Token // the { token
 	Statements []Statement
+	Rbrace     token.Token // the closing } token
 }
 
 func (bs *BlockStatement) statementNode()       {}
@@ -211,10 +226,37 @@ func (bs *BlockStatement) String() string {
 	return out.String()
 }
 
+// Declares reports whether a let or const in the block binds a name in
+// it, which makes the block a scope of its own. a nil block, like a
+// missing else, declares nothing
+func (bs *BlockStatement) Declares() bool {
+	if bs == nil {
+		return false
+	}
+	for _, stmt := range bs.Statements {
+		if _, ok := stmt.(*LetStatement); ok {
+			return true
+		}
+	}
+	return false
+}
+
 type FunctionLiteral struct {
 	Token      token.Token
 	Parameters []*Identifier
-	Body       *BlockStatement
+	// the default value of each parameter, nil for those without one. the
+	// whole slice is nil if no parameter has a default
+	Defaults []Expression
+	Rest     *Identifier // the ...rest parameter that collects extra arguments, if any
+	Body     *BlockStatement
+}
+
+// Default returns the default value of parameter i, nil if it has none
+func (fl *FunctionLiteral) Default(i int) Expression {
+	if i < len(fl.Defaults) {
+		return fl.Defaults[i]
+	}
+	return nil
 }
 
 func (fl *FunctionLiteral) expressionNode()      {}
@@ -222,21 +264,32 @@ func (fl *FunctionLiteral) TokenLiteral() string { return fl.Token.Literal }
 func (fl *FunctionLiteral) String() string {
 	var out bytes.Buffer
 
-	params := []string{}
-
-	for _, p := range fl.Parameters {
-		params = append(params, p.String())
-	}
-
 	out.WriteString(fl.TokenLiteral())
 	out.WriteString("(")
-	out.WriteString(strings.Join(params, ", "))
+	out.WriteString(ParameterList(fl.Parameters, fl.Defaults, fl.Rest))
 	out.WriteString(") ")
 	out.WriteString(fl.Body.String())
 
 	return out.String()
 }
 
+// ParameterList shows parameters the way they are written in a function
+// literal, like a, b = 10, ...rest
+func ParameterList(parameters []*Identifier, defaults []Expression, rest *Identifier) string {
+	params := []string{}
+	for i, p := range parameters {
+		if i < len(defaults) && defaults[i] != nil {
+			params = append(params, p.String()+" = "+defaults[i].String())
+		} else {
+			params = append(params, p.String())
+		}
+	}
+	if rest != nil {
+		params = append(params, "..."+rest.String())
+	}
+	return strings.Join(params, ", ")
+}
+
 type CallExpression struct {
 	Token     token.Token // The '(' token
 	Function  Expression
@@ -257,3 +310,305 @@ func (ce *CallExpression) String() string {
 	out.WriteString(")")
 	return out.String()
 }
+
+// for strings, Value has the escapes in the source replaced
+type StringLiteral struct {
+	Token token.Token
+	Value string
+}
+
+func (sl *StringLiteral) expressionNode()      {}
+func (sl *StringLiteral) TokenLiteral() string { return sl.Token.Literal }
+func (sl *StringLiteral) String() string       { return sl.Token.Literal }
+
+type ArrayLiteral struct {
+	Token    token.Token // the [ token
+	Elements []Expression
+}
+
+func (al *ArrayLiteral) expressionNode()      {}
+func (al *ArrayLiteral) TokenLiteral() string { return al.Token.Literal }
+func (al *ArrayLiteral) String() string {
+	var out bytes.Buffer
+	elements := []string{}
+	for _, el := range al.Elements {
+		elements = append(elements, el.String())
+	}
+	out.WriteString("[")
+	out.WriteString(strings.Join(elements, ", "))
+	out.WriteString("]")
+	return out.String()
+}
+
+type IndexExpression struct {
+	Token token.Token // the [ token
+	Left  Expression
+	Index Expression
+}
+
+func (ie *IndexExpression) expressionNode()      {}
+func (ie *IndexExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *IndexExpression) String() string {
+	var out bytes.Buffer
+	out.WriteString("(")
+	out.WriteString(ie.Left.String())
+	out.WriteString("[")
+	out.WriteString(ie.Index.String())
+	out.WriteString("])")
+	return out.String()
+}
+
+// object.property, a field of a hash or a method of the value. the method
+// is called like any function, object.method(args)
+type MemberExpression struct {
+	Token    token.Token // the . token
+	Object   Expression
+	Property *Identifier
+}
+
+func (me *MemberExpression) expressionNode()      {}
+func (me *MemberExpression) TokenLiteral() string { return me.Token.Literal }
+func (me *MemberExpression) String() string {
+	return "(" + me.Object.String() + "." + me.Property.String() + ")"
+}
+
+type NullLiteral struct {
+	Token token.Token
+}
+
+func (nl *NullLiteral) expressionNode()      {}
+func (nl *NullLiteral) TokenLiteral() string { return nl.Token.Literal }
+func (nl *NullLiteral) String() string       { return nl.Token.Literal }
+
+// ...value. as an argument of a call it passes the elements of an array
+// as arguments of their own. at the end of an array pattern value is the
+// name the rest of the array is bound to
+type SpreadExpression struct {
+	Token token.Token // the ... token
+	Value Expression
+}
+
+func (se *SpreadExpression) expressionNode()      {}
+func (se *SpreadExpression) TokenLiteral() string { return se.Token.Literal }
+func (se *SpreadExpression) String() string       { return "..." + se.Value.String() }
+
+// name: value, an argument of a call that is passed to the parameter with
+// that name instead of by its position
+type NamedArgument struct {
+	Token token.Token // the name
+	Name  *Identifier
+	Value Expression
+}
+
+func (na *NamedArgument) expressionNode()      {}
+func (na *NamedArgument) TokenLiteral() string { return na.Token.Literal }
+func (na *NamedArgument) String() string       { return na.Name.String() + ": " + na.Value.String() }
+
+// condition ? consequence : alternative
+type ConditionalExpression struct {
+	Token       token.Token // the ? token
+	Condition   Expression
+	Consequence Expression
+	Alternative Expression
+}
+
+func (ce *ConditionalExpression) expressionNode()      {}
+func (ce *ConditionalExpression) TokenLiteral() string { return ce.Token.Literal }
+func (ce *ConditionalExpression) String() string {
+	return "(" + ce.Condition.String() + " ? " + ce.Consequence.String() + " : " + ce.Alternative.String() + ")"
+}
+
+// x = value, or a compound assignment like x += value. the target is an
+// identifier or an index expression like a[i]
+type AssignExpression struct {
+	Token    token.Token // the = or compound operator token
+	Target   Expression
+	Operator string
+	Value    Expression
+}
+
+func (ae *AssignExpression) expressionNode()      {}
+func (ae *AssignExpression) TokenLiteral() string { return ae.Token.Literal }
+func (ae *AssignExpression) String() string {
+	return "(" + ae.Target.String() + " " + ae.Operator + " " + ae.Value.String() + ")"
+}
+
+// the pairs are kept in the order they were written
+type HashLiteral struct {
+	Token token.Token // the { token
+	Pairs []HashPair
+}
+
+type HashPair struct {
+	Key   Expression
+	Value Expression
+}
+
+func (hl *HashLiteral) expressionNode()      {}
+func (hl *HashLiteral) TokenLiteral() string { return hl.Token.Literal }
+func (hl *HashLiteral) String() string {
+	var out bytes.Buffer
+	pairs := []string{}
+	for _, pair := range hl.Pairs {
+		pairs = append(pairs, pair.Key.String()+": "+pair.Value.String())
+	}
+	out.WriteString("{")
+	out.WriteString(strings.Join(pairs, ", "))
+	out.WriteString("}")
+	return out.String()
+}
+
+// for throw statements, they raise Value as an error
+type ThrowStatement struct {
+	Token token.Token
+	Value Expression
+}
+
+func (ts *ThrowStatement) statementNode()       {}
+func (ts *ThrowStatement) TokenLiteral() string { return ts.Token.Literal }
+func (ts *ThrowStatement) String() string {
+	var out bytes.Buffer
+	out.WriteString(ts.TokenLiteral() + " ")
+	if ts.Value != nil {
+		out.WriteString(ts.Value.String())
+	}
+	out.WriteString(";")
+	return out.String()
+}
+
+// try { ... } catch (e) { ... } finally { ... }, either the catch or the
+// finally block can be left out
+type TryExpression struct {
+	Token     token.Token
+	Block     *BlockStatement
+	Parameter *Identifier // the name the caught error is bound to
+	Catch     *BlockStatement
+	Finally   *BlockStatement
+}
+
+func (te *TryExpression) expressionNode()      {}
+func (te *TryExpression) TokenLiteral() string { return te.Token.Literal }
+func (te *TryExpression) String() string {
+	var out bytes.Buffer
+	out.WriteString("try ")
+	out.WriteString(te.Block.String())
+	if te.Catch != nil {
+		out.WriteString("catch(")
+		out.WriteString(te.Parameter.String())
+		out.WriteString(") ")
+		out.WriteString(te.Catch.String())
+	}
+	if te.Finally != nil {
+		out.WriteString("finally ")
+		out.WriteString(te.Finally.String())
+	}
+	return out.String()
+}
+
+// the postfix ? operator, it unwraps an Ok result and returns an Err from
+// the function it is in
+type PropagateExpression struct {
+	Token token.Token // the ? token
+	Left  Expression
+}
+
+func (pe *PropagateExpression) expressionNode()      {}
+func (pe *PropagateExpression) TokenLiteral() string { return pe.Token.Literal }
+func (pe *PropagateExpression) String() string {
+	return "(" + pe.Left.String() + "?)"
+}
+
+type WhileStatement struct {
+	Token     token.Token
+	Condition Expression
+	Body      *BlockStatement
+}
+
+func (ws *WhileStatement) statementNode()       {}
+func (ws *WhileStatement) TokenLiteral() string { return ws.Token.Literal }
+func (ws *WhileStatement) String() string {
+	return "while" + ws.Condition.String() + " " + ws.Body.String()
+}
+
+// for (x in iterable) { ... }
+type ForStatement struct {
+	Token    token.Token
+	Variable *Identifier
+	Iterable Expression
+	Body     *BlockStatement
+}
+
+func (fs *ForStatement) statementNode()       {}
+func (fs *ForStatement) TokenLiteral() string { return fs.Token.Literal }
+func (fs *ForStatement) String() string {
+	return "for(" + fs.Variable.String() + " in " + fs.Iterable.String() + ") " + fs.Body.String()
+}
+
+type BreakStatement struct {
+	Token token.Token
+}
+
+func (bs *BreakStatement) statementNode()       {}
+func (bs *BreakStatement) TokenLiteral() string { return bs.Token.Literal }
+func (bs *BreakStatement) String() string       { return bs.Token.Literal + ";" }
+
+type ContinueStatement struct {
+	Token token.Token
+}
+
+func (cs *ContinueStatement) statementNode()       {}
+func (cs *ContinueStatement) TokenLiteral() string { return cs.Token.Literal }
+func (cs *ContinueStatement) String() string       { return cs.Token.Literal + ";" }
+
+// match (value) { pattern => result, ... }. the arms are tried in order and
+// the first one that matches gives the value of the match
+type MatchExpression struct {
+	Token  token.Token // the match token
+	Value  Expression
+	Arms   []*MatchArm
+	Rbrace token.Token // the closing } token
+}
+
+// A pattern is an expression of the few kinds that can be matched against:
+// literals, which match equal values, names, which match anything and bind
+// it, _ which matches anything without binding it, and array and hash
+// literals of patterns. an array pattern matches arrays of the same length,
+// or at least as long if it ends in a ...rest that binds the elements
+// left over. a hash pattern matches hashes that have all of its keys, in
+// {name} the key is the string "name" and the value is bound to name
+type MatchArm struct {
+	Pattern Expression
+	Guard   Expression // the condition after if, nil if there is none
+	Body    Statement  // a *BlockStatement or an *ExpressionStatement
+}
+
+func (me *MatchExpression) expressionNode()      {}
+func (me *MatchExpression) TokenLiteral() string { return me.Token.Literal }
+func (me *MatchExpression) String() string {
+	var out bytes.Buffer
+	out.WriteString("match(")
+	out.WriteString(me.Value.String())
+	out.WriteString(") {")
+	arms := []string{}
+	for _, arm := range me.Arms {
+		arms = append(arms, arm.String())
+	}
+	out.WriteString(strings.Join(arms, ", "))
+	out.WriteString("}")
+	return out.String()
+}
+
+func (ma *MatchArm) String() string {
+	var out bytes.Buffer
+	out.WriteString(ma.Pattern.String())
+	if ma.Guard != nil {
+		out.WriteString(" if " + ma.Guard.String())
+	}
+	out.WriteString(" => ")
+	if block, ok := ma.Body.(*BlockStatement); ok {
+		out.WriteString("{" + block.String() + "}")
+	} else {
+		out.WriteString(ma.Body.String())
+	}
+	return out.String()
+}
@@ -25,3 +25,23 @@ func TestString(t *testing.T) {
 		t.Errorf("program.String() wrong. got=%q", program.String())
 	}
 }
+
+func TestDeclares(t *testing.T) {
+	let := &LetStatement{Name: &Identifier{Value: "x"}, Value: &IntegerLiteral{Value: 1}}
+	expr := &ExpressionStatement{Expression: &Identifier{Value: "x"}}
+
+	tests := []struct {
+		block    *BlockStatement
+		expected bool
+	}{
+		{nil, false},
+		{&BlockStatement{}, false},
+		{&BlockStatement{Statements: []Statement{expr}}, false},
+		{&BlockStatement{Statements: []Statement{expr, let}}, true},
+	}
+	for i, tt := range tests {
+		if got := tt.block.Declares(); got != tt.expected {
+			t.Errorf("test %d: Declares() = %t, want=%t", i, got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,802 @@
+package ast
+
+import (
+	"Go-interpreter/token"
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// Every node is encoded as a JSON object whose "kind" field names the node
+// type, e.g. {"kind": "InfixExpression", "token": {...}, "operator": "+",
+// "left": {...}, "right": {...}}. Tokens keep their type, literal and
+// position, so decoding gives back exactly the tree that was encoded.
+//
+// Missing children, like the else block of an if, are encoded as null.
+
+// MarshalNode returns the JSON encoding of node and everything below it
+func MarshalNode(node Node) ([]byte, error) {
+	return json.Marshal(encodeNode(node))
+}
+
+// UnmarshalNode decodes a node encoded with MarshalNode
+func UnmarshalNode(data []byte) (Node, error) {
+	return decodeNode(data)
+}
+
+func (p *Program) MarshalJSON() ([]byte, error) {
+	return MarshalNode(p)
+}
+
+func (p *Program) UnmarshalJSON(data []byte) error {
+	node, err := decodeNode(data)
+	if err != nil {
+		return err
+	}
+	program, ok := node.(*Program)
+	if !ok {
+		return fmt.Errorf("ast: expected Program, got %T", node)
+	}
+	*p = *program
+	return nil
+}
+
+// jsonObject is a JSON object that keeps its fields in the order they were
+// added, so "kind" always comes first
+type jsonObject []jsonField
+
+type jsonField struct {
+	key   string
+	value interface{}
+}
+
+func (o jsonObject) MarshalJSON() ([]byte, error) {
+	var out bytes.Buffer
+	out.WriteString("{")
+	for i, field := range o {
+		if i > 0 {
+			out.WriteString(",")
+		}
+		key, _ := json.Marshal(field.key)
+		value, err := json.Marshal(field.value)
+		if err != nil {
+			return nil, err
+		}
+		out.Write(key)
+		out.WriteString(":")
+		out.Write(value)
+	}
+	out.WriteString("}")
+	return out.Bytes(), nil
+}
+
+func newObject(kind string, tok token.Token) jsonObject {
+	return jsonObject{{"kind", kind}, {"token", tok}}
+}
+
+func (o jsonObject) add(key string, value interface{}) jsonObject {
+	return append(o, jsonField{key, value})
+}
+
+func encodeNode(node Node) interface{} {
+	switch n := node.(type) {
+	case *Program:
+		return jsonObject{
+			{"kind", "Program"},
+			{"statements", encodeStatements(n.Statements)},
+			{"comments", encodeComments(n.Comments)},
+		}
+	case *Comment:
+		return newObject("Comment", n.Token)
+	case *ExpressionStatement:
+		return newObject("ExpressionStatement", n.Token).
+			add("expression", encodeExpression(n.Expression))
+	case *LetStatement:
+		return newObject("LetStatement", n.Token).
+			add("name", encodeExpression(n.Name)).
+			add("value", encodeExpression(n.Value))
+	case *ReturnStatement:
+		return newObject("ReturnStatement", n.Token).
+			add("returnValue", encodeExpression(n.ReturnValue))
+	case *BlockStatement:
+		return newObject("BlockStatement", n.Token).
+			add("statements", encodeStatements(n.Statements)).
+			add("rbrace", n.Rbrace)
+	case *Identifier:
+		return newObject("Identifier", n.Token).add("value", n.Value)
+	case *IntegerLiteral:
+		return newObject("IntegerLiteral", n.Token).add("value", n.Value)
+	case *Boolean:
+		return newObject("Boolean", n.Token).add("value", n.Value)
+	case *PrefixExpression:
+		return newObject("PrefixExpression", n.Token).
+			add("operator", n.Operator).
+			add("right", encodeExpression(n.Right))
+	case *InfixExpression:
+		return newObject("InfixExpression", n.Token).
+			add("operator", n.Operator).
+			add("left", encodeExpression(n.Left)).
+			add("right", encodeExpression(n.Right))
+	case *IfExpression:
+		return newObject("IfExpression", n.Token).
+			add("condition", encodeExpression(n.Condition)).
+			add("then", encodeBlock(n.Then)).
+			add("else", encodeBlock(n.Else))
+	case *FunctionLiteral:
+		params := []interface{}{}
+		for _, param := range n.Parameters {
+			params = append(params, encodeNode(param))
+		}
+		var rest interface{}
+		if n.Rest != nil {
+			rest = encodeNode(n.Rest)
+		}
+		return newObject("FunctionLiteral", n.Token).
+			add("parameters", params).
+			add("defaults", encodeExpressions(n.Defaults)).
+			add("rest", rest).
+			add("body", encodeBlock(n.Body))
+	case *CallExpression:
+		return newObject("CallExpression", n.Token).
+			add("function", encodeExpression(n.Function)).
+			add("arguments", encodeExpressions(n.Arguments))
+	case *StringLiteral:
+		return newObject("StringLiteral", n.Token).add("value", n.Value)
+	case *ArrayLiteral:
+		return newObject("ArrayLiteral", n.Token).
+			add("elements", encodeExpressions(n.Elements))
+	case *IndexExpression:
+		return newObject("IndexExpression", n.Token).
+			add("left", encodeExpression(n.Left)).
+			add("index", encodeExpression(n.Index))
+	case *MemberExpression:
+		return newObject("MemberExpression", n.Token).
+			add("object", encodeExpression(n.Object)).
+			add("property", encodeExpression(n.Property))
+	case *HashLiteral:
+		pairs := []interface{}{}
+		for _, pair := range n.Pairs {
+			pairs = append(pairs, jsonObject{
+				{"key", encodeExpression(pair.Key)},
+				{"value", encodeExpression(pair.Value)},
+			})
+		}
+		return newObject("HashLiteral", n.Token).add("pairs", pairs)
+	case *WhileStatement:
+		return newObject("WhileStatement", n.Token).
+			add("condition", encodeExpression(n.Condition)).
+			add("body", encodeBlock(n.Body))
+	case *ForStatement:
+		var variable interface{}
+		if n.Variable != nil {
+			variable = encodeNode(n.Variable)
+		}
+		return newObject("ForStatement", n.Token).
+			add("variable", variable).
+			add("iterable", encodeExpression(n.Iterable)).
+			add("body", encodeBlock(n.Body))
+	case *NullLiteral:
+		return newObject("NullLiteral", n.Token)
+	case *ConditionalExpression:
+		return newObject("ConditionalExpression", n.Token).
+			add("condition", encodeExpression(n.Condition)).
+			add("consequence", encodeExpression(n.Consequence)).
+			add("alternative", encodeExpression(n.Alternative))
+	case *SpreadExpression:
+		return newObject("SpreadExpression", n.Token).add("value", encodeExpression(n.Value))
+	case *NamedArgument:
+		return newObject("NamedArgument", n.Token).
+			add("name", encodeExpression(n.Name)).
+			add("value", encodeExpression(n.Value))
+	case *MatchExpression:
+		arms := []interface{}{}
+		for _, arm := range n.Arms {
+			var body interface{}
+			if arm.Body != nil {
+				body = encodeNode(arm.Body)
+			}
+			arms = append(arms, jsonObject{
+				{"pattern", encodeExpression(arm.Pattern)},
+				{"guard", encodeExpression(arm.Guard)},
+				{"body", body},
+			})
+		}
+		return newObject("MatchExpression", n.Token).
+			add("value", encodeExpression(n.Value)).
+			add("arms", arms).
+			add("rbrace", n.Rbrace)
+	case *AssignExpression:
+		return newObject("AssignExpression", n.Token).
+			add("operator", n.Operator).
+			add("target", encodeExpression(n.Target)).
+			add("value", encodeExpression(n.Value))
+	case *BreakStatement:
+		return newObject("BreakStatement", n.Token)
+	case *ContinueStatement:
+		return newObject("ContinueStatement", n.Token)
+	case *PropagateExpression:
+		return newObject("PropagateExpression", n.Token).
+			add("left", encodeExpression(n.Left))
+	case *ThrowStatement:
+		return newObject("ThrowStatement", n.Token).
+			add("value", encodeExpression(n.Value))
+	case *TryExpression:
+		var param interface{}
+		if n.Parameter != nil {
+			param = encodeNode(n.Parameter)
+		}
+		return newObject("TryExpression", n.Token).
+			add("block", encodeBlock(n.Block)).
+			add("parameter", param).
+			add("catch", encodeBlock(n.Catch)).
+			add("finally", encodeBlock(n.Finally))
+	}
+	return nil
+}
+
+// the helpers below turn nil children into JSON null rather than a typed nil
+func encodeExpression(exp Expression) interface{} {
+	if exp == nil {
+		return nil
+	}
+	return encodeNode(exp)
+}
+
+func encodeExpressions(exps []Expression) []interface{} {
+	encoded := []interface{}{}
+	for _, exp := range exps {
+		encoded = append(encoded, encodeExpression(exp))
+	}
+	return encoded
+}
+
+func encodeBlock(block *BlockStatement) interface{} {
+	if block == nil {
+		return nil
+	}
+	return encodeNode(block)
+}
+
+func encodeStatements(stmts []Statement) []interface{} {
+	encoded := []interface{}{}
+	for _, stmt := range stmts {
+		encoded = append(encoded, encodeNode(stmt))
+	}
+	return encoded
+}
+
+func encodeComments(comments []*Comment) []interface{} {
+	encoded := []interface{}{}
+	for _, comment := range comments {
+		encoded = append(encoded, encodeNode(comment))
+	}
+	return encoded
+}
+
+// jsonFields holds the fields of one encoded node while it's decoded
+type jsonFields struct {
+	kind   string
+	fields map[string]json.RawMessage
+}
+
+func decodeNode(data []byte) (Node, error) {
+	if isNull(data) {
+		return nil, nil
+	}
+	f := &jsonFields{}
+	if err := json.Unmarshal(data, &f.fields); err != nil {
+		return nil, err
+	}
+	f.kind = "node"
+	if err := f.value("kind", &f.kind); err != nil {
+		return nil, err
+	}
+
+	switch f.kind {
+	case "Program":
+		n := &Program{}
+		var err error
+		if n.Statements, err = f.statements("statements"); err != nil {
+			return nil, err
+		}
+		var comments []json.RawMessage
+		if err := f.value("comments", &comments); err != nil {
+			return nil, err
+		}
+		for _, raw := range comments {
+			node, err := decodeNode(raw)
+			if err != nil {
+				return nil, err
+			}
+			comment, ok := node.(*Comment)
+			if !ok {
+				return nil, fmt.Errorf("ast: Program comments: expected Comment, got %T", node)
+			}
+			n.Comments = append(n.Comments, comment)
+		}
+		return n, nil
+
+	case "Comment":
+		n := &Comment{}
+		return n, f.value("token", &n.Token)
+
+	case "ExpressionStatement":
+		n := &ExpressionStatement{}
+		if err := f.value("token", &n.Token); err != nil {
+			return nil, err
+		}
+		var err error
+		n.Expression, err = f.expression("expression")
+		return n, err
+
+	case "LetStatement":
+		n := &LetStatement{}
+		if err := f.value("token", &n.Token); err != nil {
+			return nil, err
+		}
+		var err error
+		if n.Name, err = f.expression("name"); err != nil {
+			return nil, err
+		}
+		n.Value, err = f.expression("value")
+		return n, err
+
+	case "ReturnStatement":
+		n := &ReturnStatement{}
+		if err := f.value("token", &n.Token); err != nil {
+			return nil, err
+		}
+		var err error
+		n.ReturnValue, err = f.expression("returnValue")
+		return n, err
+
+	case "BlockStatement":
+		n := &BlockStatement{}
+		if err := f.value("token", &n.Token); err != nil {
+			return nil, err
+		}
+		if err := f.value("rbrace", &n.Rbrace); err != nil {
+			return nil, err
+		}
+		var err error
+		n.Statements, err = f.statements("statements")
+		return n, err
+
+	case "Identifier":
+		n := &Identifier{}
+		if err := f.value("token", &n.Token); err != nil {
+			return nil, err
+		}
+		return n, f.value("value", &n.Value)
+
+	case "IntegerLiteral":
+		n := &IntegerLiteral{}
+		if err := f.value("token", &n.Token); err != nil {
+			return nil, err
+		}
+		return n, f.value("value", &n.Value)
+
+	case "Boolean":
+		n := &Boolean{}
+		if err := f.value("token", &n.Token); err != nil {
+			return nil, err
+		}
+		return n, f.value("value", &n.Value)
+
+	case "PrefixExpression":
+		n := &PrefixExpression{}
+		if err := f.value("token", &n.Token); err != nil {
+			return nil, err
+		}
+		if err := f.value("operator", &n.Operator); err != nil {
+			return nil, err
+		}
+		var err error
+		n.Right, err = f.expression("right")
+		return n, err
+
+	case "InfixExpression":
+		n := &InfixExpression{}
+		if err := f.value("token", &n.Token); err != nil {
+			return nil, err
+		}
+		if err := f.value("operator", &n.Operator); err != nil {
+			return nil, err
+		}
+		var err error
+		if n.Left, err = f.expression("left"); err != nil {
+			return nil, err
+		}
+		n.Right, err = f.expression("right")
+		return n, err
+
+	case "IfExpression":
+		n := &IfExpression{}
+		if err := f.value("token", &n.Token); err != nil {
+			return nil, err
+		}
+		var err error
+		if n.Condition, err = f.expression("condition"); err != nil {
+			return nil, err
+		}
+		if n.Then, err = f.block("then"); err != nil {
+			return nil, err
+		}
+		n.Else, err = f.block("else")
+		return n, err
+
+	case "FunctionLiteral":
+		n := &FunctionLiteral{Parameters: []*Identifier{}}
+		if err := f.value("token", &n.Token); err != nil {
+			return nil, err
+		}
+		var params []json.RawMessage
+		if err := f.value("parameters", &params); err != nil {
+			return nil, err
+		}
+		for _, raw := range params {
+			param, err := decodeIdentifier(raw)
+			if err != nil {
+				return nil, err
+			}
+			n.Parameters = append(n.Parameters, param)
+		}
+		var err error
+		if n.Defaults, err = f.expressions("defaults"); err != nil {
+			return nil, err
+		}
+		// the parser leaves Defaults nil when there are none
+		if len(n.Defaults) == 0 {
+			n.Defaults = nil
+		}
+		if n.Rest, err = f.identifier("rest"); err != nil {
+			return nil, err
+		}
+		n.Body, err = f.block("body")
+		return n, err
+
+	case "CallExpression":
+		n := &CallExpression{Arguments: []Expression{}}
+		if err := f.value("token", &n.Token); err != nil {
+			return nil, err
+		}
+		var err error
+		if n.Function, err = f.expression("function"); err != nil {
+			return nil, err
+		}
+		n.Arguments, err = f.expressions("arguments")
+		return n, err
+
+	case "StringLiteral":
+		n := &StringLiteral{}
+		if err := f.value("token", &n.Token); err != nil {
+			return nil, err
+		}
+		return n, f.value("value", &n.Value)
+
+	case "ArrayLiteral":
+		n := &ArrayLiteral{}
+		if err := f.value("token", &n.Token); err != nil {
+			return nil, err
+		}
+		var err error
+		n.Elements, err = f.expressions("elements")
+		return n, err
+
+	case "IndexExpression":
+		n := &IndexExpression{}
+		if err := f.value("token", &n.Token); err != nil {
+			return nil, err
+		}
+		var err error
+		if n.Left, err = f.expression("left"); err != nil {
+			return nil, err
+		}
+		n.Index, err = f.expression("index")
+		return n, err
+
+	case "MemberExpression":
+		n := &MemberExpression{}
+		if err := f.value("token", &n.Token); err != nil {
+			return nil, err
+		}
+		var err error
+		if n.Object, err = f.expression("object"); err != nil {
+			return nil, err
+		}
+		n.Property, err = f.identifier("property")
+		return n, err
+
+	case "HashLiteral":
+		n := &HashLiteral{Pairs: []HashPair{}}
+		if err := f.value("token", &n.Token); err != nil {
+			return nil, err
+		}
+		var pairs []map[string]json.RawMessage
+		if err := f.value("pairs", &pairs); err != nil {
+			return nil, err
+		}
+		for _, raw := range pairs {
+			key, err := decodeExpression(raw["key"])
+			if err != nil {
+				return nil, fmt.Errorf("ast: HashLiteral.pairs: %w", err)
+			}
+			value, err := decodeExpression(raw["value"])
+			if err != nil {
+				return nil, fmt.Errorf("ast: HashLiteral.pairs: %w", err)
+			}
+			n.Pairs = append(n.Pairs, HashPair{Key: key, Value: value})
+		}
+		return n, nil
+
+	case "WhileStatement":
+		n := &WhileStatement{}
+		if err := f.value("token", &n.Token); err != nil {
+			return nil, err
+		}
+		var err error
+		if n.Condition, err = f.expression("condition"); err != nil {
+			return nil, err
+		}
+		n.Body, err = f.block("body")
+		return n, err
+
+	case "ForStatement":
+		n := &ForStatement{}
+		if err := f.value("token", &n.Token); err != nil {
+			return nil, err
+		}
+		var err error
+		if n.Variable, err = f.identifier("variable"); err != nil {
+			return nil, err
+		}
+		if n.Iterable, err = f.expression("iterable"); err != nil {
+			return nil, err
+		}
+		n.Body, err = f.block("body")
+		return n, err
+
+	case "NullLiteral":
+		n := &NullLiteral{}
+		return n, f.value("token", &n.Token)
+
+	case "NamedArgument":
+		n := &NamedArgument{}
+		if err := f.value("token", &n.Token); err != nil {
+			return nil, err
+		}
+		var err error
+		if n.Name, err = f.identifier("name"); err != nil {
+			return nil, err
+		}
+		n.Value, err = f.expression("value")
+		return n, err
+
+	case "SpreadExpression":
+		n := &SpreadExpression{}
+		if err := f.value("token", &n.Token); err != nil {
+			return nil, err
+		}
+		var err error
+		n.Value, err = f.expression("value")
+		return n, err
+
+	case "MatchExpression":
+		n := &MatchExpression{Arms: []*MatchArm{}}
+		if err := f.value("token", &n.Token); err != nil {
+			return nil, err
+		}
+		if err := f.value("rbrace", &n.Rbrace); err != nil {
+			return nil, err
+		}
+		var err error
+		if n.Value, err = f.expression("value"); err != nil {
+			return nil, err
+		}
+		var arms []map[string]json.RawMessage
+		if err := f.value("arms", &arms); err != nil {
+			return nil, err
+		}
+		for _, raw := range arms {
+			arm := &MatchArm{}
+			if arm.Pattern, err = decodeExpression(raw["pattern"]); err != nil {
+				return nil, fmt.Errorf("ast: MatchExpression.arms: %w", err)
+			}
+			if arm.Guard, err = decodeExpression(raw["guard"]); err != nil {
+				return nil, fmt.Errorf("ast: MatchExpression.arms: %w", err)
+			}
+			body, err := decodeNode(raw["body"])
+			if err != nil {
+				return nil, fmt.Errorf("ast: MatchExpression.arms: %w", err)
+			}
+			if body != nil {
+				stmt, ok := body.(Statement)
+				if !ok {
+					return nil, fmt.Errorf("ast: MatchExpression.arms: expected a statement, got %T", body)
+				}
+				arm.Body = stmt
+			}
+			n.Arms = append(n.Arms, arm)
+		}
+		return n, nil
+
+	case "ConditionalExpression":
+		n := &ConditionalExpression{}
+		if err := f.value("token", &n.Token); err != nil {
+			return nil, err
+		}
+		var err error
+		if n.Condition, err = f.expression("condition"); err != nil {
+			return nil, err
+		}
+		if n.Consequence, err = f.expression("consequence"); err != nil {
+			return nil, err
+		}
+		n.Alternative, err = f.expression("alternative")
+		return n, err
+
+	case "AssignExpression":
+		n := &AssignExpression{}
+		if err := f.value("token", &n.Token); err != nil {
+			return nil, err
+		}
+		if err := f.value("operator", &n.Operator); err != nil {
+			return nil, err
+		}
+		var err error
+		if n.Target, err = f.expression("target"); err != nil {
+			return nil, err
+		}
+		n.Value, err = f.expression("value")
+		return n, err
+
+	case "BreakStatement":
+		n := &BreakStatement{}
+		return n, f.value("token", &n.Token)
+
+	case "ContinueStatement":
+		n := &ContinueStatement{}
+		return n, f.value("token", &n.Token)
+
+	case "PropagateExpression":
+		n := &PropagateExpression{}
+		if err := f.value("token", &n.Token); err != nil {
+			return nil, err
+		}
+		var err error
+		n.Left, err = f.expression("left")
+		return n, err
+
+	case "ThrowStatement":
+		n := &ThrowStatement{}
+		if err := f.value("token", &n.Token); err != nil {
+			return nil, err
+		}
+		var err error
+		n.Value, err = f.expression("value")
+		return n, err
+
+	case "TryExpression":
+		n := &TryExpression{}
+		if err := f.value("token", &n.Token); err != nil {
+			return nil, err
+		}
+		var err error
+		if n.Block, err = f.block("block"); err != nil {
+			return nil, err
+		}
+		if n.Parameter, err = f.identifier("parameter"); err != nil {
+			return nil, err
+		}
+		if n.Catch, err = f.block("catch"); err != nil {
+			return nil, err
+		}
+		n.Finally, err = f.block("finally")
+		return n, err
+	}
+	return nil, fmt.Errorf("ast: unknown node kind %q", f.kind)
+}
+
+func isNull(data []byte) bool {
+	return len(data) == 0 || string(bytes.TrimSpace(data)) == "null"
+}
+
+// value decodes a plain field like a token, string or number
+func (f *jsonFields) value(key string, v interface{}) error {
+	raw, ok := f.fields[key]
+	if !ok {
+		return fmt.Errorf("ast: %s is missing field %q", f.kind, key)
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return fmt.Errorf("ast: %s.%s: %s", f.kind, key, err)
+	}
+	return nil
+}
+
+func (f *jsonFields) expression(key string) (Expression, error) {
+	exp, err := decodeExpression(f.fields[key])
+	if err != nil {
+		return nil, fmt.Errorf("ast: %s.%s: %w", f.kind, key, err)
+	}
+	return exp, nil
+}
+
+func (f *jsonFields) expressions(key string) ([]Expression, error) {
+	var raws []json.RawMessage
+	if err := f.value(key, &raws); err != nil {
+		return nil, err
+	}
+	exps := []Expression{}
+	for _, raw := range raws {
+		exp, err := decodeExpression(raw)
+		if err != nil {
+			return nil, fmt.Errorf("ast: %s.%s: %w", f.kind, key, err)
+		}
+		exps = append(exps, exp)
+	}
+	return exps, nil
+}
+
+func (f *jsonFields) identifier(key string) (*Identifier, error) {
+	ident, err := decodeIdentifier(f.fields[key])
+	if err != nil {
+		return nil, fmt.Errorf("ast: %s.%s: %w", f.kind, key, err)
+	}
+	return ident, nil
+}
+
+func (f *jsonFields) block(key string) (*BlockStatement, error) {
+	node, err := decodeNode(f.fields[key])
+	if err != nil || node == nil {
+		return nil, err
+	}
+	block, ok := node.(*BlockStatement)
+	if !ok {
+		return nil, fmt.Errorf("ast: %s.%s: expected BlockStatement, got %T", f.kind, key, node)
+	}
+	return block, nil
+}
+
+func (f *jsonFields) statements(key string) ([]Statement, error) {
+	var raws []json.RawMessage
+	if err := f.value(key, &raws); err != nil {
+		return nil, err
+	}
+	stmts := []Statement{}
+	for _, raw := range raws {
+		node, err := decodeNode(raw)
+		if err != nil {
+			return nil, err
+		}
+		stmt, ok := node.(Statement)
+		if !ok {
+			return nil, fmt.Errorf("ast: %s.%s: expected a statement, got %T", f.kind, key, node)
+		}
+		stmts = append(stmts, stmt)
+	}
+	return stmts, nil
+}
+
+func decodeExpression(data []byte) (Expression, error) {
+	node, err := decodeNode(data)
+	if err != nil || node == nil {
+		return nil, err
+	}
+	exp, ok := node.(Expression)
+	if !ok {
+		return nil, fmt.Errorf("expected an expression, got %T", node)
+	}
+	return exp, nil
+}
+
+func decodeIdentifier(data []byte) (*Identifier, error) {
+	node, err := decodeNode(data)
+	if err != nil || node == nil {
+		return nil, err
+	}
+	ident, ok := node.(*Identifier)
+	if !ok {
+		return nil, fmt.Errorf("expected Identifier, got %T", node)
+	}
+	return ident, nil
+}
@@ -0,0 +1,161 @@
+package ast_test
+
+import (
+	"Go-interpreter/ast"
+	"Go-interpreter/lexer"
+	"Go-interpreter/parser"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// uses every node type in ast.go at least once
+const jsonTestInput = `// leading comment
+let add = fn(a, b) { return a + b; };
+let noArgs = fn() {};
+let x = -add(1, 2) * 3; // trailing comment
+if (!(x < 10) == true) { x } else { false }
+if (x != 5) { add(x, 1) }
+let h = {"a\tb": [1, 2][0], "c": []};
+try { throw h; } catch (e) { e } finally { 1 }
+add(1, 2)?;
+while (x < 10) { if (x) { break; } continue; }
+for (i in [1]) { i }
+x = h["c"][0] += 1;
+const c = 1;
+x == 1 ? null : x ?? 2;
+match (x) { 1 => 2, [a, _] if a => { a }, {"k": -1} => null }
+let [first, {name}, ...others] = x;
+fn(a, b = 2, ...c) { a }(...x, b: 1);
+x.y.len();
+`
+
+func parse(t *testing.T, input string) *ast.Program {
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+	return program
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	program := parse(t, jsonTestInput)
+
+	data, err := json.Marshal(program)
+	if err != nil {
+		t.Fatalf("json.Marshal failed: %s", err)
+	}
+	decoded := &ast.Program{}
+	if err := json.Unmarshal(data, decoded); err != nil {
+		t.Fatalf("json.Unmarshal failed: %s", err)
+	}
+
+	if !reflect.DeepEqual(program, decoded) {
+		t.Errorf("program changed after round trip.\nexpected=%s\ngot=     %s", program, decoded)
+	}
+
+	again, err := json.Marshal(decoded)
+	if err != nil {
+		t.Fatalf("json.Marshal failed: %s", err)
+	}
+	if string(data) != string(again) {
+		t.Errorf("encoding changed after round trip.\nfirst= %s\nsecond=%s", data, again)
+	}
+}
+
+func TestJSONCoversAllNodes(t *testing.T) {
+	program := parse(t, jsonTestInput)
+	data, err := ast.MarshalNode(program)
+	if err != nil {
+		t.Fatalf("MarshalNode failed: %s", err)
+	}
+
+	kinds := []string{
+		"Program", "Comment", "ExpressionStatement", "LetStatement",
+		"ReturnStatement", "BlockStatement", "Identifier", "IntegerLiteral",
+		"Boolean", "PrefixExpression", "InfixExpression", "IfExpression",
+		"FunctionLiteral", "CallExpression", "StringLiteral", "ArrayLiteral",
+		"IndexExpression", "HashLiteral", "ThrowStatement", "TryExpression",
+		"PropagateExpression", "WhileStatement", "ForStatement",
+		"BreakStatement", "ContinueStatement", "AssignExpression",
+		"NullLiteral", "ConditionalExpression", "MatchExpression", "SpreadExpression", "NamedArgument",
+		"MemberExpression",
+	}
+	for _, kind := range kinds {
+		if !strings.Contains(string(data), fmt.Sprintf(`"kind":%q`, kind)) {
+			t.Errorf("encoding has no %s node", kind)
+		}
+	}
+}
+
+func TestJSONNodes(t *testing.T) {
+	tests := []struct {
+		input    ast.Node
+		expected string
+	}{
+		{
+			parse(t, "5").Statements[0].(*ast.ExpressionStatement).Expression,
+			`{"kind":"IntegerLiteral","token":{"type":"INT","literal":"5","line":1,"column":1},"value":5}`,
+		},
+		{
+			parse(t, "-x").Statements[0].(*ast.ExpressionStatement).Expression,
+			`{"kind":"PrefixExpression","token":{"type":"-","literal":"-","line":1,"column":1},"operator":"-",` +
+				`"right":{"kind":"Identifier","token":{"type":"IDENT","literal":"x","line":1,"column":2},"value":"x"}}`,
+		},
+		{
+			&ast.IfExpression{Condition: &ast.Boolean{Value: true}, Then: &ast.BlockStatement{}},
+			`{"kind":"IfExpression","token":{"type":"","literal":"","line":0,"column":0},` +
+				`"condition":{"kind":"Boolean","token":{"type":"","literal":"","line":0,"column":0},"value":true},` +
+				`"then":{"kind":"BlockStatement","token":{"type":"","literal":"","line":0,"column":0},"statements":[],` +
+				`"rbrace":{"type":"","literal":"","line":0,"column":0}},"else":null}`,
+		},
+	}
+	for _, tt := range tests {
+		data, err := ast.MarshalNode(tt.input)
+		if err != nil {
+			t.Fatalf("MarshalNode failed: %s", err)
+		}
+		if string(data) != tt.expected {
+			t.Errorf("wrong encoding.\nexpected=%s\ngot=     %s", tt.expected, data)
+		}
+		node, err := ast.UnmarshalNode(data)
+		if err != nil {
+			t.Fatalf("UnmarshalNode failed: %s", err)
+		}
+		if node.String() != tt.input.String() {
+			t.Errorf("wrong node after decoding. expected=%q, got=%q", tt.input, node)
+		}
+	}
+}
+
+func TestJSONErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`{"kind":"Nope"}`, `ast: unknown node kind "Nope"`},
+		{`{"token":{}}`, `ast: node is missing field "kind"`},
+		{`{"kind":"Identifier","token":{}}`, `ast: Identifier is missing field "value"`},
+		{
+			`{"kind":"ExpressionStatement","token":{},"expression":{"kind":"Comment","token":{}}}`,
+			`ast: ExpressionStatement.expression: expected an expression, got *ast.Comment`,
+		},
+	}
+	for _, tt := range tests {
+		_, err := ast.UnmarshalNode([]byte(tt.input))
+		if err == nil {
+			t.Errorf("expected an error for %s", tt.input)
+			continue
+		}
+		if err.Error() != tt.expected {
+			t.Errorf("wrong error. expected=%q, got=%q", tt.expected, err.Error())
+		}
+	}
+
+	if err := json.Unmarshal([]byte(`{"kind":"Boolean","token":{},"value":true}`), &ast.Program{}); err == nil {
+		t.Errorf("expected an error decoding a Boolean into a Program")
+	}
+}
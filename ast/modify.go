@@ -0,0 +1,218 @@
+package ast
+
+import "fmt"
+
+// A ModifierFunc is called with every node Modify reaches and returns the
+// node that should take its place
+type ModifierFunc func(Node) Node
+
+// Modify rewrites the AST bottom up: the children of a node are modified
+// before the node itself is passed to modifier, and the result replaces it.
+//
+// The replacement has to fit where the old node was, an expression for an
+// expression and a block for a block, and Modify panics if it doesn't.
+// Statements that modifier turns into nil are removed from the program or
+// block they were in, any other child turned into nil is left empty
+func Modify(node Node, modifier ModifierFunc) Node {
+	switch n := node.(type) {
+	case *Program:
+		n.Statements = modifyStatements(n.Statements, modifier)
+
+	case *ExpressionStatement:
+		n.Expression = modifyExpression(n.Expression, modifier)
+
+	case *LetStatement:
+		n.Name = modifyExpression(n.Name, modifier)
+		n.Value = modifyExpression(n.Value, modifier)
+
+	case *ReturnStatement:
+		n.ReturnValue = modifyExpression(n.ReturnValue, modifier)
+
+	case *BlockStatement:
+		n.Statements = modifyStatements(n.Statements, modifier)
+
+	case *PrefixExpression:
+		n.Right = modifyExpression(n.Right, modifier)
+
+	case *InfixExpression:
+		n.Left = modifyExpression(n.Left, modifier)
+		n.Right = modifyExpression(n.Right, modifier)
+
+	case *IfExpression:
+		n.Condition = modifyExpression(n.Condition, modifier)
+		n.Then = modifyBlock(n.Then, modifier)
+		n.Else = modifyBlock(n.Else, modifier)
+
+	case *FunctionLiteral:
+		for i, param := range n.Parameters {
+			n.Parameters[i] = modifyIdentifier(param, modifier)
+		}
+		for i, def := range n.Defaults {
+			n.Defaults[i] = modifyExpression(def, modifier)
+		}
+		if n.Rest != nil {
+			n.Rest = modifyIdentifier(n.Rest, modifier)
+		}
+		n.Body = modifyBlock(n.Body, modifier)
+
+	case *CallExpression:
+		n.Function = modifyExpression(n.Function, modifier)
+		for i, arg := range n.Arguments {
+			n.Arguments[i] = modifyExpression(arg, modifier)
+		}
+
+	case *ArrayLiteral:
+		for i, el := range n.Elements {
+			n.Elements[i] = modifyExpression(el, modifier)
+		}
+
+	case *IndexExpression:
+		n.Left = modifyExpression(n.Left, modifier)
+		n.Index = modifyExpression(n.Index, modifier)
+
+	case *MemberExpression:
+		n.Object = modifyExpression(n.Object, modifier)
+		if n.Property != nil {
+			n.Property = modifyIdentifier(n.Property, modifier)
+		}
+
+	case *ConditionalExpression:
+		n.Condition = modifyExpression(n.Condition, modifier)
+		n.Consequence = modifyExpression(n.Consequence, modifier)
+		n.Alternative = modifyExpression(n.Alternative, modifier)
+
+	case *SpreadExpression:
+		n.Value = modifyExpression(n.Value, modifier)
+
+	case *NamedArgument:
+		if n.Name != nil {
+			n.Name = modifyIdentifier(n.Name, modifier)
+		}
+		n.Value = modifyExpression(n.Value, modifier)
+
+	case *MatchExpression:
+		n.Value = modifyExpression(n.Value, modifier)
+		for _, arm := range n.Arms {
+			arm.Pattern = modifyExpression(arm.Pattern, modifier)
+			arm.Guard = modifyExpression(arm.Guard, modifier)
+			if arm.Body != nil {
+				arm.Body = modifyStatement(arm.Body, modifier)
+			}
+		}
+
+	case *AssignExpression:
+		n.Target = modifyExpression(n.Target, modifier)
+		n.Value = modifyExpression(n.Value, modifier)
+
+	case *HashLiteral:
+		for i, pair := range n.Pairs {
+			n.Pairs[i].Key = modifyExpression(pair.Key, modifier)
+			n.Pairs[i].Value = modifyExpression(pair.Value, modifier)
+		}
+
+	case *WhileStatement:
+		n.Condition = modifyExpression(n.Condition, modifier)
+		n.Body = modifyBlock(n.Body, modifier)
+
+	case *ForStatement:
+		if n.Variable != nil {
+			n.Variable = modifyIdentifier(n.Variable, modifier)
+		}
+		n.Iterable = modifyExpression(n.Iterable, modifier)
+		n.Body = modifyBlock(n.Body, modifier)
+
+	case *PropagateExpression:
+		n.Left = modifyExpression(n.Left, modifier)
+
+	case *ThrowStatement:
+		n.Value = modifyExpression(n.Value, modifier)
+
+	case *TryExpression:
+		n.Block = modifyBlock(n.Block, modifier)
+		if n.Parameter != nil {
+			n.Parameter = modifyIdentifier(n.Parameter, modifier)
+		}
+		n.Catch = modifyBlock(n.Catch, modifier)
+		n.Finally = modifyBlock(n.Finally, modifier)
+	}
+
+	return modifier(node)
+}
+
+func modifyStatements(stmts []Statement, modifier ModifierFunc) []Statement {
+	modified := stmts[:0]
+	for _, stmt := range stmts {
+		if stmt == nil {
+			continue
+		}
+		if s := modifyStatement(stmt, modifier); s != nil {
+			modified = append(modified, s)
+		}
+	}
+	return modified
+}
+
+// the helpers below check that a replacement fits where the old node was.
+// one that doesn't is a bug in the modifier, storing it as nil would only
+// hide it until something trips over the hole
+
+func modifyStatement(stmt Statement, modifier ModifierFunc) Statement {
+	modified := Modify(stmt, modifier)
+	if modified == nil {
+		return nil
+	}
+	s, ok := modified.(Statement)
+	if !ok {
+		misfit(stmt, modified, "a statement")
+	}
+	return s
+}
+
+func modifyExpression(exp Expression, modifier ModifierFunc) Expression {
+	if exp == nil {
+		return nil
+	}
+	modified := Modify(exp, modifier)
+	if modified == nil {
+		return nil
+	}
+	e, ok := modified.(Expression)
+	if !ok {
+		misfit(exp, modified, "an expression")
+	}
+	return e
+}
+
+func modifyBlock(block *BlockStatement, modifier ModifierFunc) *BlockStatement {
+	if block == nil {
+		return nil
+	}
+	modified := Modify(block, modifier)
+	if modified == nil {
+		return nil
+	}
+	b, ok := modified.(*BlockStatement)
+	if !ok {
+		misfit(block, modified, "a block")
+	}
+	return b
+}
+
+func modifyIdentifier(id *Identifier, modifier ModifierFunc) *Identifier {
+	if id == nil {
+		return nil
+	}
+	modified := Modify(id, modifier)
+	if modified == nil {
+		return nil
+	}
+	i, ok := modified.(*Identifier)
+	if !ok {
+		misfit(id, modified, "an identifier")
+	}
+	return i
+}
+
+func misfit(old, replacement Node, want string) {
+	panic(fmt.Sprintf("ast: Modify: cannot replace %T with %T, it has to be %s", old, replacement, want))
+}
@@ -0,0 +1,298 @@
+package ast
+
+import (
+	"Go-interpreter/token"
+	"reflect"
+	"testing"
+)
+
+func TestModify(t *testing.T) {
+	one := func() Expression { return integer(1) }
+	two := func() Expression { return integer(2) }
+
+	turnOneIntoTwo := func(node Node) Node {
+		integer, ok := node.(*IntegerLiteral)
+		if !ok {
+			return node
+		}
+		if integer.Value != 1 {
+			return integer
+		}
+		return two()
+	}
+
+	tests := []struct {
+		input    Node
+		expected Node
+	}{
+		{one(), two()},
+		{
+			&Program{Statements: []Statement{expStmt(one())}},
+			&Program{Statements: []Statement{expStmt(two())}},
+		},
+		{infix(one(), "+", two()), infix(two(), "+", two())},
+		{infix(two(), "+", one()), infix(two(), "+", two())},
+		{
+			&PrefixExpression{Operator: "-", Right: one()},
+			&PrefixExpression{Operator: "-", Right: two()},
+		},
+		{
+			&IfExpression{
+				Condition: one(),
+				Then:      block(expStmt(one())),
+				Else:      block(expStmt(one())),
+			},
+			&IfExpression{
+				Condition: two(),
+				Then:      block(expStmt(two())),
+				Else:      block(expStmt(two())),
+			},
+		},
+		{
+			&IfExpression{Condition: one(), Then: block(expStmt(one()))},
+			&IfExpression{Condition: two(), Then: block(expStmt(two()))},
+		},
+		{
+			&ReturnStatement{ReturnValue: one()},
+			&ReturnStatement{ReturnValue: two()},
+		},
+		{
+			&LetStatement{Name: ident("x"), Value: one()},
+			&LetStatement{Name: ident("x"), Value: two()},
+		},
+		{
+			&FunctionLiteral{
+				Parameters: []*Identifier{ident("x")},
+				Body:       block(expStmt(one())),
+			},
+			&FunctionLiteral{
+				Parameters: []*Identifier{ident("x")},
+				Body:       block(expStmt(two())),
+			},
+		},
+		{
+			&CallExpression{Function: ident("f"), Arguments: []Expression{one(), two(), one()}},
+			&CallExpression{Function: ident("f"), Arguments: []Expression{two(), two(), two()}},
+		},
+		{
+			&ArrayLiteral{Elements: []Expression{one(), one()}},
+			&ArrayLiteral{Elements: []Expression{two(), two()}},
+		},
+		{
+			&IndexExpression{Left: one(), Index: one()},
+			&IndexExpression{Left: two(), Index: two()},
+		},
+		{
+			&ConditionalExpression{Condition: one(), Consequence: one(), Alternative: one()},
+			&ConditionalExpression{Condition: two(), Consequence: two(), Alternative: two()},
+		},
+		{
+			&LetStatement{Name: &ArrayLiteral{Elements: []Expression{one(), &SpreadExpression{Value: one()}}}, Value: one()},
+			&LetStatement{Name: &ArrayLiteral{Elements: []Expression{two(), &SpreadExpression{Value: two()}}}, Value: two()},
+		},
+		{
+			&FunctionLiteral{
+				Parameters: []*Identifier{ident("x"), ident("y")},
+				Defaults:   []Expression{nil, one()},
+				Body:       block(expStmt(one())),
+			},
+			&FunctionLiteral{
+				Parameters: []*Identifier{ident("x"), ident("y")},
+				Defaults:   []Expression{nil, two()},
+				Body:       block(expStmt(two())),
+			},
+		},
+		{
+			&CallExpression{Function: ident("f"), Arguments: []Expression{&NamedArgument{Name: ident("x"), Value: one()}}},
+			&CallExpression{Function: ident("f"), Arguments: []Expression{&NamedArgument{Name: ident("x"), Value: two()}}},
+		},
+		{
+			&MemberExpression{Object: one(), Property: ident("x")},
+			&MemberExpression{Object: two(), Property: ident("x")},
+		},
+		{
+			&MatchExpression{Value: one(), Arms: []*MatchArm{{Pattern: one(), Guard: one(), Body: expStmt(one())}}},
+			&MatchExpression{Value: two(), Arms: []*MatchArm{{Pattern: two(), Guard: two(), Body: expStmt(two())}}},
+		},
+		{
+			&AssignExpression{Target: &IndexExpression{Left: ident("a"), Index: one()}, Operator: "=", Value: one()},
+			&AssignExpression{Target: &IndexExpression{Left: ident("a"), Index: two()}, Operator: "=", Value: two()},
+		},
+		{
+			&WhileStatement{Condition: one(), Body: block(expStmt(one()))},
+			&WhileStatement{Condition: two(), Body: block(expStmt(two()))},
+		},
+		{
+			&ForStatement{Variable: ident("x"), Iterable: one(), Body: block(expStmt(one()))},
+			&ForStatement{Variable: ident("x"), Iterable: two(), Body: block(expStmt(two()))},
+		},
+		{
+			&PropagateExpression{Left: one()},
+			&PropagateExpression{Left: two()},
+		},
+		{
+			&HashLiteral{Pairs: []HashPair{{Key: one(), Value: one()}}},
+			&HashLiteral{Pairs: []HashPair{{Key: two(), Value: two()}}},
+		},
+		{
+			&ThrowStatement{Value: one()},
+			&ThrowStatement{Value: two()},
+		},
+		{
+			&TryExpression{
+				Block:     block(expStmt(one())),
+				Parameter: ident("e"),
+				Catch:     block(expStmt(one())),
+				Finally:   block(expStmt(one())),
+			},
+			&TryExpression{
+				Block:     block(expStmt(two())),
+				Parameter: ident("e"),
+				Catch:     block(expStmt(two())),
+				Finally:   block(expStmt(two())),
+			},
+		},
+		{
+			&TryExpression{Block: block(expStmt(one())), Parameter: ident("e"), Catch: block(expStmt(one()))},
+			&TryExpression{Block: block(expStmt(two())), Parameter: ident("e"), Catch: block(expStmt(two()))},
+		},
+	}
+
+	for _, tt := range tests {
+		modified := Modify(tt.input, turnOneIntoTwo)
+		if !reflect.DeepEqual(modified, tt.expected) {
+			t.Errorf("not equal. got=%#v, want=%#v", modified, tt.expected)
+		}
+	}
+}
+
+func TestModifyRemovesStatements(t *testing.T) {
+	program := &Program{Statements: []Statement{
+		expStmt(integer(1)),
+		&ReturnStatement{ReturnValue: integer(2)},
+		expStmt(&FunctionLiteral{Token: token.Token{Type: token.FUNCTION, Literal: "fn"}, Body: block(
+			&ReturnStatement{ReturnValue: integer(3)},
+			expStmt(integer(4)),
+		)}),
+	}}
+
+	dropReturns := func(node Node) Node {
+		if _, ok := node.(*ReturnStatement); ok {
+			return nil
+		}
+		return node
+	}
+	Modify(program, dropReturns)
+
+	if program.String() != "1fn() 4" {
+		t.Errorf("return statements not removed. got=%q", program.String())
+	}
+}
+
+func TestModifyReplacesNodeType(t *testing.T) {
+	// replace identifiers with the integer they are bound to
+	program := &Program{Statements: []Statement{
+		expStmt(infix(ident("x"), "*", &CallExpression{
+			Function:  ident("f"),
+			Arguments: []Expression{ident("x")},
+		})),
+	}}
+	Modify(program, func(node Node) Node {
+		if id, ok := node.(*Identifier); ok && id.Value == "x" {
+			return integer(7)
+		}
+		return node
+	})
+
+	if program.String() != "(7 * f(7))" {
+		t.Errorf("identifiers not replaced. got=%q", program.String())
+	}
+}
+
+func TestModifyCoversAllNodes(t *testing.T) {
+	program := everyNodeProgram()
+	original := map[Node]bool{}
+	for _, node := range allNodes(program) {
+		original[node] = true
+	}
+
+	// swap every node for a copy of itself, anything Modify skips stays
+	// the original
+	copyNode := func(node Node) Node {
+		v := reflect.ValueOf(node)
+		c := reflect.New(v.Elem().Type())
+		c.Elem().Set(v.Elem())
+		return c.Interface().(Node)
+	}
+	modified := Modify(program, copyNode)
+
+	nodes := allNodes(modified)
+	if len(nodes) != len(original) {
+		t.Errorf("wrong number of nodes. expected=%d, got=%d", len(original), len(nodes))
+	}
+	for _, node := range nodes {
+		if original[node] {
+			t.Errorf("Modify didn't replace a %T: %s", node, node.String())
+		}
+	}
+}
+
+func TestModifyRejectsMisfits(t *testing.T) {
+	tests := []struct {
+		input    Node
+		modifier ModifierFunc
+		expected string
+	}{
+		{
+			infix(integer(1), "+", integer(2)),
+			func(node Node) Node {
+				if _, ok := node.(*IntegerLiteral); ok {
+					return &ReturnStatement{}
+				}
+				return node
+			},
+			"ast: Modify: cannot replace *ast.IntegerLiteral with *ast.ReturnStatement, it has to be an expression",
+		},
+		{
+			&IfExpression{Condition: ident("x"), Then: block(expStmt(integer(1)))},
+			func(node Node) Node {
+				if _, ok := node.(*BlockStatement); ok {
+					return expStmt(integer(1))
+				}
+				return node
+			},
+			"ast: Modify: cannot replace *ast.BlockStatement with *ast.ExpressionStatement, it has to be a block",
+		},
+		{
+			&ForStatement{Variable: ident("x"), Iterable: ident("xs"), Body: block()},
+			func(node Node) Node {
+				if id, ok := node.(*Identifier); ok && id.Value == "x" {
+					return integer(1)
+				}
+				return node
+			},
+			"ast: Modify: cannot replace *ast.Identifier with *ast.IntegerLiteral, it has to be an identifier",
+		},
+		{
+			&Program{Statements: []Statement{expStmt(integer(1))}},
+			func(node Node) Node {
+				if stmt, ok := node.(*ExpressionStatement); ok {
+					return stmt.Expression
+				}
+				return node
+			},
+			"ast: Modify: cannot replace *ast.ExpressionStatement with *ast.IntegerLiteral, it has to be a statement",
+		},
+	}
+
+	for _, tt := range tests {
+		func() {
+			defer func() {
+				if r := recover(); r != tt.expected {
+					t.Errorf("wrong panic. expected=%q, got=%v", tt.expected, r)
+				}
+			}()
+			Modify(tt.input, tt.modifier)
+		}()
+	}
+}
@@ -0,0 +1,75 @@
+package ast
+
+import "Go-interpreter/token"
+
+// TokenOf returns the token a node was parsed from, which gives its
+// position in the source. for infix expressions that is the operator and
+// for calls and index expressions the opening bracket. a Program has no
+// token of its own
+func TokenOf(node Node) token.Token {
+	switch n := node.(type) {
+	case *Comment:
+		return n.Token
+	case *ExpressionStatement:
+		return n.Token
+	case *LetStatement:
+		return n.Token
+	case *ReturnStatement:
+		return n.Token
+	case *BlockStatement:
+		return n.Token
+	case *Identifier:
+		return n.Token
+	case *IntegerLiteral:
+		return n.Token
+	case *Boolean:
+		return n.Token
+	case *PrefixExpression:
+		return n.Token
+	case *InfixExpression:
+		return n.Token
+	case *IfExpression:
+		return n.Token
+	case *FunctionLiteral:
+		return n.Token
+	case *CallExpression:
+		return n.Token
+	case *StringLiteral:
+		return n.Token
+	case *ArrayLiteral:
+		return n.Token
+	case *IndexExpression:
+		return n.Token
+	case *MemberExpression:
+		return n.Token
+	case *AssignExpression:
+		return n.Token
+	case *NullLiteral:
+		return n.Token
+	case *ConditionalExpression:
+		return n.Token
+	case *MatchExpression:
+		return n.Token
+	case *SpreadExpression:
+		return n.Token
+	case *NamedArgument:
+		return n.Token
+	case *HashLiteral:
+		return n.Token
+	case *ThrowStatement:
+		return n.Token
+	case *TryExpression:
+		return n.Token
+	case *PropagateExpression:
+		return n.Token
+	case *WhileStatement:
+		return n.Token
+	case *ForStatement:
+		return n.Token
+	case *BreakStatement:
+		return n.Token
+	case *ContinueStatement:
+		return n.Token
+	}
+	return token.Token{}
+}
@@ -0,0 +1,245 @@
+package ast
+
+// A Visitor's Visit method is called for every node Walk reaches. If the
+// visitor it returns is not nil, Walk visits the children of node with it
+// and then calls Visit(nil) on it
+type Visitor interface {
+	Visit(node Node) (w Visitor)
+}
+
+// Walk traverses the AST in depth-first order, starting by calling
+// v.Visit(node). Nil children, like a missing else block, are skipped
+func Walk(v Visitor, node Node) {
+	if v = v.Visit(node); v == nil {
+		return
+	}
+
+	switch n := node.(type) {
+	case *Program:
+		walkStatements(v, n.Statements)
+
+	case *ExpressionStatement:
+		if n.Expression != nil {
+			Walk(v, n.Expression)
+		}
+
+	case *LetStatement:
+		if n.Name != nil {
+			Walk(v, n.Name)
+		}
+		if n.Value != nil {
+			Walk(v, n.Value)
+		}
+
+	case *ReturnStatement:
+		if n.ReturnValue != nil {
+			Walk(v, n.ReturnValue)
+		}
+
+	case *BlockStatement:
+		walkStatements(v, n.Statements)
+
+	case *PrefixExpression:
+		if n.Right != nil {
+			Walk(v, n.Right)
+		}
+
+	case *InfixExpression:
+		if n.Left != nil {
+			Walk(v, n.Left)
+		}
+		if n.Right != nil {
+			Walk(v, n.Right)
+		}
+
+	case *IfExpression:
+		if n.Condition != nil {
+			Walk(v, n.Condition)
+		}
+		if n.Then != nil {
+			Walk(v, n.Then)
+		}
+		if n.Else != nil {
+			Walk(v, n.Else)
+		}
+
+	case *FunctionLiteral:
+		for i, param := range n.Parameters {
+			Walk(v, param)
+			if def := n.Default(i); def != nil {
+				Walk(v, def)
+			}
+		}
+		if n.Rest != nil {
+			Walk(v, n.Rest)
+		}
+		if n.Body != nil {
+			Walk(v, n.Body)
+		}
+
+	case *CallExpression:
+		if n.Function != nil {
+			Walk(v, n.Function)
+		}
+		walkExpressions(v, n.Arguments)
+
+	case *ArrayLiteral:
+		walkExpressions(v, n.Elements)
+
+	case *IndexExpression:
+		if n.Left != nil {
+			Walk(v, n.Left)
+		}
+		if n.Index != nil {
+			Walk(v, n.Index)
+		}
+
+	case *MemberExpression:
+		if n.Object != nil {
+			Walk(v, n.Object)
+		}
+		if n.Property != nil {
+			Walk(v, n.Property)
+		}
+
+	case *ConditionalExpression:
+		if n.Condition != nil {
+			Walk(v, n.Condition)
+		}
+		if n.Consequence != nil {
+			Walk(v, n.Consequence)
+		}
+		if n.Alternative != nil {
+			Walk(v, n.Alternative)
+		}
+
+	case *SpreadExpression:
+		if n.Value != nil {
+			Walk(v, n.Value)
+		}
+
+	case *NamedArgument:
+		if n.Name != nil {
+			Walk(v, n.Name)
+		}
+		if n.Value != nil {
+			Walk(v, n.Value)
+		}
+
+	case *MatchExpression:
+		if n.Value != nil {
+			Walk(v, n.Value)
+		}
+		for _, arm := range n.Arms {
+			if arm.Pattern != nil {
+				Walk(v, arm.Pattern)
+			}
+			if arm.Guard != nil {
+				Walk(v, arm.Guard)
+			}
+			if arm.Body != nil {
+				Walk(v, arm.Body)
+			}
+		}
+
+	case *AssignExpression:
+		if n.Target != nil {
+			Walk(v, n.Target)
+		}
+		if n.Value != nil {
+			Walk(v, n.Value)
+		}
+
+	case *HashLiteral:
+		for _, pair := range n.Pairs {
+			if pair.Key != nil {
+				Walk(v, pair.Key)
+			}
+			if pair.Value != nil {
+				Walk(v, pair.Value)
+			}
+		}
+
+	case *ThrowStatement:
+		if n.Value != nil {
+			Walk(v, n.Value)
+		}
+
+	case *TryExpression:
+		if n.Block != nil {
+			Walk(v, n.Block)
+		}
+		if n.Parameter != nil {
+			Walk(v, n.Parameter)
+		}
+		if n.Catch != nil {
+			Walk(v, n.Catch)
+		}
+		if n.Finally != nil {
+			Walk(v, n.Finally)
+		}
+
+	case *WhileStatement:
+		if n.Condition != nil {
+			Walk(v, n.Condition)
+		}
+		if n.Body != nil {
+			Walk(v, n.Body)
+		}
+
+	case *ForStatement:
+		if n.Variable != nil {
+			Walk(v, n.Variable)
+		}
+		if n.Iterable != nil {
+			Walk(v, n.Iterable)
+		}
+		if n.Body != nil {
+			Walk(v, n.Body)
+		}
+
+	case *PropagateExpression:
+		if n.Left != nil {
+			Walk(v, n.Left)
+		}
+
+	case *Identifier, *IntegerLiteral, *Boolean, *StringLiteral, *Comment,
+		*NullLiteral, *BreakStatement, *ContinueStatement:
+		// nothing to do
+
+	}
+
+	v.Visit(nil)
+}
+
+func walkStatements(v Visitor, stmts []Statement) {
+	for _, stmt := range stmts {
+		if stmt != nil {
+			Walk(v, stmt)
+		}
+	}
+}
+
+func walkExpressions(v Visitor, exps []Expression) {
+	for _, exp := range exps {
+		if exp != nil {
+			Walk(v, exp)
+		}
+	}
+}
+
+type inspector func(Node) bool
+
+func (f inspector) Visit(node Node) Visitor {
+	if f(node) {
+		return f
+	}
+	return nil
+}
+
+// Inspect traverses the AST in depth-first order, calling f(node) for every
+// node. If f returns true, Inspect visits the children of node and then
+// calls f(nil)
+func Inspect(node Node, f func(Node) bool) {
+	Walk(inspector(f), node)
+}
@@ -0,0 +1,315 @@
+package ast
+
+import (
+	"Go-interpreter/token"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func ident(name string) *Identifier {
+	return &Identifier{Token: token.Token{Type: token.IDENT, Literal: name}, Value: name}
+}
+
+func integer(value int64) *IntegerLiteral {
+	literal := fmt.Sprintf("%d", value)
+	return &IntegerLiteral{Token: token.Token{Type: token.INT, Literal: literal}, Value: value}
+}
+
+func infix(left Expression, operator string, right Expression) *InfixExpression {
+	return &InfixExpression{Token: token.Token{Literal: operator}, Left: left, Operator: operator, Right: right}
+}
+
+func block(stmts ...Statement) *BlockStatement {
+	return &BlockStatement{Token: token.Token{Type: token.LBRACE, Literal: "{"}, Statements: stmts}
+}
+
+func expStmt(exp Expression) *ExpressionStatement {
+	return &ExpressionStatement{Expression: exp}
+}
+
+// let f = fn(x) { if (x > 1) { return x; } else { g(x, 2) } };
+func walkTestProgram() *Program {
+	return &Program{Statements: []Statement{
+		&LetStatement{
+			Token: token.Token{Type: token.LET, Literal: "let"},
+			Name:  ident("f"),
+			Value: &FunctionLiteral{
+				Token:      token.Token{Type: token.FUNCTION, Literal: "fn"},
+				Parameters: []*Identifier{ident("x")},
+				Body: block(expStmt(&IfExpression{
+					Token:     token.Token{Type: token.IF, Literal: "if"},
+					Condition: infix(ident("x"), ">", integer(1)),
+					Then: block(&ReturnStatement{
+						Token:       token.Token{Type: token.RETURN, Literal: "return"},
+						ReturnValue: ident("x"),
+					}),
+					Else: block(expStmt(&CallExpression{
+						Token:     token.Token{Type: token.LPAREN, Literal: "("},
+						Function:  ident("g"),
+						Arguments: []Expression{ident("x"), integer(2)},
+					})),
+				})),
+			},
+		},
+	}}
+}
+
+func TestInspect(t *testing.T) {
+	var visited []string
+	Inspect(walkTestProgram(), func(node Node) bool {
+		if node == nil {
+			visited = append(visited, "end")
+			return false
+		}
+		visited = append(visited, fmt.Sprintf("%T", node))
+		return true
+	})
+
+	expected := []string{
+		"*ast.Program",
+		"*ast.LetStatement",
+		"*ast.Identifier", "end",
+		"*ast.FunctionLiteral",
+		"*ast.Identifier", "end",
+		"*ast.BlockStatement",
+		"*ast.ExpressionStatement",
+		"*ast.IfExpression",
+		"*ast.InfixExpression",
+		"*ast.Identifier", "end",
+		"*ast.IntegerLiteral", "end",
+		"end",
+		"*ast.BlockStatement",
+		"*ast.ReturnStatement",
+		"*ast.Identifier", "end",
+		"end",
+		"end",
+		"*ast.BlockStatement",
+		"*ast.ExpressionStatement",
+		"*ast.CallExpression",
+		"*ast.Identifier", "end",
+		"*ast.Identifier", "end",
+		"*ast.IntegerLiteral", "end",
+		"end",
+		"end",
+		"end",
+		"end",
+		"end",
+		"end",
+		"end",
+		"end",
+		"end",
+	}
+	if !reflect.DeepEqual(visited, expected) {
+		t.Errorf("wrong visiting order.\nexpected=%v\ngot=     %v", expected, visited)
+	}
+}
+
+func TestInspectSkipsChildren(t *testing.T) {
+	identifiers := []string{}
+	Inspect(walkTestProgram(), func(node Node) bool {
+		// don't look inside the else branch
+		if ifExp, ok := node.(*IfExpression); ok {
+			Inspect(ifExp.Condition, func(node Node) bool {
+				if id, ok := node.(*Identifier); ok {
+					identifiers = append(identifiers, id.Value)
+				}
+				return true
+			})
+			return false
+		}
+		if id, ok := node.(*Identifier); ok {
+			identifiers = append(identifiers, id.Value)
+		}
+		return true
+	})
+
+	expected := []string{"f", "x", "x"}
+	if !reflect.DeepEqual(identifiers, expected) {
+		t.Errorf("wrong identifiers. expected=%v, got=%v", expected, identifiers)
+	}
+}
+
+// counter counts the nodes it sees, with one counter per depth
+type counter struct {
+	depth  int
+	counts map[int]int
+}
+
+func (c *counter) Visit(node Node) Visitor {
+	if node == nil {
+		return nil
+	}
+	c.counts[c.depth]++
+	return &counter{depth: c.depth + 1, counts: c.counts}
+}
+
+func TestWalk(t *testing.T) {
+	c := &counter{counts: map[int]int{}}
+	Walk(c, walkTestProgram())
+
+	// program, let, name and fn, param and body
+	expected := map[int]int{0: 1, 1: 1, 2: 2, 3: 2}
+	for depth, count := range expected {
+		if c.counts[depth] != count {
+			t.Errorf("wrong number of nodes at depth %d. expected=%d, got=%d", depth, count, c.counts[depth])
+		}
+	}
+
+	total := 0
+	for _, count := range c.counts {
+		total += count
+	}
+	if total != 20 {
+		t.Errorf("wrong number of nodes. expected=20, got=%d", total)
+	}
+}
+
+// a program with every kind of node in it and every optional child filled
+// in, it doesn't have to make sense, only to be complete
+func everyNodeProgram() *Program {
+	return &Program{Statements: []Statement{
+		&LetStatement{Name: ident("f"), Value: &FunctionLiteral{
+			Parameters: []*Identifier{ident("a"), ident("b")},
+			Defaults:   []Expression{nil, integer(1)},
+			Rest:       ident("rest"),
+			Body: block(&ReturnStatement{ReturnValue: &PrefixExpression{
+				Operator: "-",
+				Right:    infix(ident("a"), "+", integer(2)),
+			}}),
+		}},
+		expStmt(&IfExpression{
+			Condition: &Boolean{Value: true},
+			Then:      block(&BreakStatement{}),
+			Else:      block(&ContinueStatement{}),
+		}),
+		expStmt(&CallExpression{
+			Function: &MemberExpression{Object: ident("obj"), Property: ident("method")},
+			Arguments: []Expression{
+				&SpreadExpression{Value: &ArrayLiteral{Elements: []Expression{&StringLiteral{Value: "s"}, &NullLiteral{}}}},
+				&NamedArgument{Name: ident("named"), Value: integer(3)},
+			},
+		}),
+		expStmt(&IndexExpression{
+			Left:  &HashLiteral{Pairs: []HashPair{{Key: integer(4), Value: integer(5)}}},
+			Index: integer(4),
+		}),
+		expStmt(&ConditionalExpression{Condition: ident("c"), Consequence: integer(6), Alternative: integer(7)}),
+		expStmt(&AssignExpression{Target: ident("x"), Operator: "=", Value: &PropagateExpression{Left: ident("y")}}),
+		&ThrowStatement{Value: integer(8)},
+		expStmt(&TryExpression{
+			Block:     block(expStmt(integer(9))),
+			Parameter: ident("e"),
+			Catch:     block(expStmt(ident("e"))),
+			Finally:   block(expStmt(integer(10))),
+		}),
+		&WhileStatement{Condition: ident("w"), Body: block(expStmt(integer(11)))},
+		&ForStatement{Variable: ident("i"), Iterable: ident("list"), Body: block(expStmt(ident("i")))},
+		expStmt(&MatchExpression{Value: ident("m"), Arms: []*MatchArm{
+			{Pattern: integer(12), Guard: ident("g"), Body: expStmt(integer(13))},
+			{Pattern: ident("other"), Body: block(expStmt(ident("other")))},
+		}}),
+	}}
+}
+
+// children finds the nodes held by a node from its fields, so it doesn't
+// share any blind spots with Walk and Modify. comments sit next to the
+// tree in Program.Comments, they aren't anyone's children
+func children(node Node) []Node {
+	var found []Node
+	var collect func(v reflect.Value)
+	collect = func(v reflect.Value) {
+		switch v.Kind() {
+		case reflect.Interface, reflect.Pointer:
+			if v.IsNil() {
+				return
+			}
+			if n, ok := v.Interface().(Node); ok {
+				found = append(found, n)
+				return
+			}
+			collect(v.Elem())
+		case reflect.Slice:
+			for i := 0; i < v.Len(); i++ {
+				collect(v.Index(i))
+			}
+		case reflect.Struct:
+			for i := 0; i < v.NumField(); i++ {
+				if v.Type().Field(i).Name != "Comments" {
+					collect(v.Field(i))
+				}
+			}
+		}
+	}
+	collect(reflect.ValueOf(node).Elem())
+	return found
+}
+
+// allNodes lists node and everything under it, as children sees it
+func allNodes(node Node) []Node {
+	nodes := []Node{node}
+	for _, child := range children(node) {
+		nodes = append(nodes, allNodes(child)...)
+	}
+	return nodes
+}
+
+// nodeTypes reads the names of the node types from the package source, a
+// node type is anything with a TokenLiteral method
+func nodeTypes(t *testing.T) []string {
+	t.Helper()
+	files, err := filepath.Glob("*.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	method := regexp.MustCompile(`(?m)^func \(\w+ \*(\w+)\) TokenLiteral\(\)`)
+	var types []string
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+		src, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, m := range method.FindAllStringSubmatch(string(src), -1) {
+			types = append(types, m[1])
+		}
+	}
+	return types
+}
+
+func TestWalkCoversAllNodes(t *testing.T) {
+	program := everyNodeProgram()
+
+	visited := map[Node]bool{}
+	kinds := map[string]bool{}
+	Inspect(program, func(node Node) bool {
+		if node != nil {
+			visited[node] = true
+			kinds[reflect.TypeOf(node).Elem().Name()] = true
+		}
+		return true
+	})
+
+	for _, name := range nodeTypes(t) {
+		if name == "Comment" {
+			continue
+		}
+		if !kinds[name] {
+			t.Errorf("no %s in the test program, or Walk never reached it", name)
+		}
+	}
+
+	for _, node := range allNodes(program) {
+		for _, child := range children(node) {
+			if !visited[child] {
+				t.Errorf("Walk skipped a %T under a %T", child, node)
+			}
+		}
+	}
+}
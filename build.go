@@ -0,0 +1,58 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// runBuild compiles a source file and writes the bytecode to a file that
+// monkey run can load without parsing it again. the output defaults to the
+// source path with a .mkc extension
+func runBuild(args []string) int {
+	flags := flag.NewFlagSet("build", flag.ContinueOnError)
+	output := flags.String("o", "", "write the compiled program to this file")
+	optimize := flags.Bool("optimize", false, "simplify the program before compiling it")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	// flags can also come after the file
+	var files []string
+	for flags.NArg() > 0 {
+		files = append(files, flags.Arg(0))
+		if err := flags.Parse(flags.Args()[1:]); err != nil {
+			return 2
+		}
+	}
+	if len(files) != 1 || files[0] == "-" && *output == "" {
+		fmt.Fprintln(os.Stderr, "usage: monkey build [--optimize] file [-o output]")
+		return 2
+	}
+	path := files[0]
+	if *output == "" {
+		*output = strings.TrimSuffix(path, filepath.Ext(path)) + ".mkc"
+	}
+
+	src, err := readSource(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "build: %s\n", err)
+		return 1
+	}
+	bytecode, err := loadBytecode(path, src, *optimize)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "build: %s\n", err)
+		return 1
+	}
+	data, err := bytecode.MarshalBinary()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "build: %s\n", err)
+		return 1
+	}
+	if err := os.WriteFile(*output, data, 0o644); err != nil {
+		fmt.Fprintf(os.Stderr, "build: %s\n", err)
+		return 1
+	}
+	return 0
+}
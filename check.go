@@ -0,0 +1,37 @@
+package main
+
+import (
+	"Go-interpreter/checker"
+	"flag"
+	"fmt"
+	"os"
+)
+
+// runCheck reports the mistakes the checker finds in the given files
+// without running them, like assignments to constants. the exit code is 1
+// if there are any
+func runCheck(args []string) int {
+	flags := flag.NewFlagSet("check", flag.ContinueOnError)
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "usage: monkey check file ...")
+		return 2
+	}
+
+	status := 0
+	for _, path := range flags.Args() {
+		program, err := parseSource(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "check: %s\n", err)
+			status = 1
+			continue
+		}
+		for _, problem := range checker.Check(program) {
+			fmt.Fprintf(os.Stderr, "%s:%s\n", path, problem)
+			status = 1
+		}
+	}
+	return status
+}
@@ -0,0 +1,211 @@
+// Package checker finds mistakes in a program without running it.
+//
+// For now it reports what would fail at runtime because of const: an
+// assignment to a constant and a let or const that declares a name again
+// that is a constant in the same scope. Scopes are the ones the evaluator
+// uses, the program, every function and every block, with the variable of
+// a for loop in the scope of its body and the names a match pattern binds
+// in a scope of their own around the guard and body of that arm. The body
+// of a function is checked after the rest of the scope it is defined in,
+// as it usually runs after that, so it sees every name declared there.
+package checker
+
+import (
+	"Go-interpreter/ast"
+	"Go-interpreter/token"
+	"fmt"
+	"sort"
+)
+
+// Problem is a mistake found in the program
+type Problem struct {
+	Token   token.Token // where in the source it is
+	Message string
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("%d:%d: %s", p.Token.Line, p.Token.Column, p.Message)
+}
+
+// Check returns the problems in program in the order they appear in the
+// source
+func Check(program *ast.Program) []Problem {
+	c := &checker{}
+	c.walk(program, newScope(nil))
+	// functions found while checking a scope add more
+	for len(c.functions) > 0 {
+		fn := c.functions[0]
+		c.functions = c.functions[1:]
+		inner := newScope(fn.scope)
+		// a default sees the parameters before it
+		for i, param := range fn.literal.Parameters {
+			if def := fn.literal.Default(i); def != nil {
+				c.walk(def, inner)
+			}
+			inner.names[param.Value] = false
+		}
+		if fn.literal.Rest != nil {
+			inner.names[fn.literal.Rest.Value] = false
+		}
+		// the body shares the scope of the parameters
+		for _, stmt := range fn.literal.Body.Statements {
+			c.walk(stmt, inner)
+		}
+	}
+	sort.SliceStable(c.problems, func(i, j int) bool {
+		a, b := c.problems[i].Token, c.problems[j].Token
+		return a.Line < b.Line || a.Line == b.Line && a.Column < b.Column
+	})
+	return c.problems
+}
+
+// scope maps the names declared in it to whether they are constants
+type scope struct {
+	names map[string]bool
+	outer *scope
+}
+
+func newScope(outer *scope) *scope {
+	return &scope{names: make(map[string]bool), outer: outer}
+}
+
+// isConst reports whether the nearest declaration of name is a constant
+func (s *scope) isConst(name string) bool {
+	for ; s != nil; s = s.outer {
+		if constant, ok := s.names[name]; ok {
+			return constant
+		}
+	}
+	return false
+}
+
+type function struct {
+	literal *ast.FunctionLiteral
+	scope   *scope // where it was defined
+}
+
+type checker struct {
+	problems  []Problem
+	functions []function // still to be checked
+}
+
+func (c *checker) walk(node ast.Node, s *scope) {
+	ast.Walk(&visitor{c, s}, node)
+}
+
+func (c *checker) report(tok token.Token, format string, a ...interface{}) {
+	c.problems = append(c.problems, Problem{Token: tok, Message: fmt.Sprintf(format, a...)})
+}
+
+// visitor checks the nodes of one scope
+type visitor struct {
+	c     *checker
+	scope *scope
+}
+
+func (v *visitor) Visit(node ast.Node) ast.Visitor {
+	switch node := node.(type) {
+	case *ast.LetStatement:
+		// the value doesn't see the name it is bound to yet
+		if node.Value != nil {
+			v.c.walk(node.Value, v.scope)
+		}
+		for _, name := range boundNames(node.Name) {
+			v.declare(name, node.IsConst())
+		}
+		return nil
+
+	case *ast.BlockStatement:
+		v.block(node, newScope(v.scope))
+		return nil
+
+	case *ast.ForStatement:
+		if node.Iterable != nil {
+			v.c.walk(node.Iterable, v.scope)
+		}
+		inner := newScope(v.scope)
+		inner.names[node.Variable.Value] = false
+		v.block(node.Body, inner)
+		return nil
+
+	case *ast.AssignExpression:
+		if ident, ok := node.Target.(*ast.Identifier); ok {
+			v.assign(ident)
+		}
+
+	case *ast.FunctionLiteral:
+		v.c.functions = append(v.c.functions, function{node, v.scope})
+		return nil
+
+	case *ast.MatchExpression:
+		if node.Value != nil {
+			v.c.walk(node.Value, v.scope)
+		}
+		for _, arm := range node.Arms {
+			inner := newScope(v.scope)
+			for _, name := range boundNames(arm.Pattern) {
+				if name.Value != "_" {
+					inner.names[name.Value] = false
+				}
+			}
+			if arm.Guard != nil {
+				v.c.walk(arm.Guard, inner)
+			}
+			if arm.Body != nil {
+				v.c.walk(arm.Body, inner)
+			}
+		}
+		return nil
+
+	case *ast.TryExpression:
+		v.c.walk(node.Block, v.scope)
+		if node.Catch != nil {
+			inner := newScope(v.scope)
+			inner.names[node.Parameter.Value] = false
+			v.c.walk(node.Catch, inner)
+		}
+		if node.Finally != nil {
+			v.c.walk(node.Finally, v.scope)
+		}
+		return nil
+	}
+	return v
+}
+
+// boundNames returns the names a let or a match pattern binds. every name
+// in a pattern binds, hash keys are literals. _ in a pattern binds nothing,
+// but let _ = x binds it
+func boundNames(pattern ast.Expression) []*ast.Identifier {
+	if ident, ok := pattern.(*ast.Identifier); ok {
+		return []*ast.Identifier{ident}
+	}
+	names := []*ast.Identifier{}
+	ast.Inspect(pattern, func(n ast.Node) bool {
+		if ident, ok := n.(*ast.Identifier); ok && ident.Value != "_" {
+			names = append(names, ident)
+		}
+		return true
+	})
+	return names
+}
+
+// block checks the statements of a block in scope s
+func (v *visitor) block(block *ast.BlockStatement, s *scope) {
+	for _, stmt := range block.Statements {
+		v.c.walk(stmt, s)
+	}
+}
+
+func (v *visitor) declare(name *ast.Identifier, constant bool) {
+	if v.scope.names[name.Value] {
+		v.c.report(name.Token, "cannot redeclare constant %s", name.Value)
+		return
+	}
+	v.scope.names[name.Value] = constant
+}
+
+func (v *visitor) assign(name *ast.Identifier) {
+	if v.scope.isConst(name.Value) {
+		v.c.report(name.Token, "cannot assign to constant %s", name.Value)
+	}
+}
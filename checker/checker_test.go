@@ -0,0 +1,61 @@
+package checker
+
+import (
+	"Go-interpreter/lexer"
+	"Go-interpreter/parser"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"const x = 1; let y = x; y = 2;", []string{}},
+		{"const x = 1;\nx = 2;", []string{"2:1: cannot assign to constant x"}},
+		{"const x = 1; x += 2;", []string{"1:14: cannot assign to constant x"}},
+		{"const x = 1; let x = 2;", []string{"1:18: cannot redeclare constant x"}},
+		{"const x = 1; const x = 2;", []string{"1:20: cannot redeclare constant x"}},
+		{"let x = 1; const x = 2; x = 3;", []string{"1:25: cannot assign to constant x"}},
+		{"const x = 1; for (y in [1]) { x = y }", []string{"1:31: cannot assign to constant x"}},
+		// blocks are scopes of their own
+		{"const x = 1; for (x in [1]) { x = 2 }", []string{}},
+		{"const x = 1; if (true) { let x = 2; x = 3 }; x = 4;", []string{"1:46: cannot assign to constant x"}},
+		{"if (true) { const x = 1; x = 2 }; let x = 3; x = 4;", []string{"1:26: cannot assign to constant x"}},
+		{"if (true) { const x = 1; if (true) { const x = 2; } const x = 3; }", []string{"1:59: cannot redeclare constant x"}},
+		// functions see the names declared after them in the same scope
+		{"let f = fn() { x = 2 }; const x = 1;", []string{"1:16: cannot assign to constant x"}},
+		// shadowing in an inner scope is fine
+		{"const x = 1; let f = fn(x) { x = 2 };", []string{}},
+		{"const x = 1; let f = fn() { let x = 2; x = 3 };", []string{}},
+		{"const x = 1; try { 1 } catch (x) { x = 2 }", []string{}},
+		{"const e = 1; try { 1 } catch (x) { e = 2 }", []string{"1:36: cannot assign to constant e"}},
+		{"const [a, {b}] = [1, {\"b\": 2}]; a = 3; let [_, ...b] = [];", []string{"1:33: cannot assign to constant a", "1:51: cannot redeclare constant b"}},
+		{"let {x} = {}; x = 1; const [y] = [x]; let f = fn() { let [y] = []; y = 2 };", []string{}},
+		{"const x = 1; let f = fn(a = x = 2, x = 3, ...y) { x = 4; y = 5 };", []string{"1:29: cannot assign to constant x"}},
+		{"const y = 1; let f = fn(...y) { y = 2 };", []string{}},
+		{"const x = 1; match ([2]) { [x] if (x = 3) => x = 4 }", []string{}},
+		{"const x = 1; match (2) { _ => x = 3, y => x = y }", []string{"1:31: cannot assign to constant x", "1:43: cannot assign to constant x"}},
+		{
+			"const a = 1; let f = fn() { const b = 2; fn() { a = 1; b = 2 } };",
+			[]string{"1:49: cannot assign to constant a", "1:56: cannot assign to constant b"},
+		},
+	}
+	for _, tt := range tests {
+		p := parser.New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		if len(p.Errors()) != 0 {
+			t.Fatalf("%q: parser errors: %v", tt.input, p.Errors())
+		}
+		problems := Check(program)
+		if len(problems) != len(tt.expected) {
+			t.Errorf("%q: wrong number of problems. want=%v, got=%v", tt.input, tt.expected, problems)
+			continue
+		}
+		for i, problem := range problems {
+			if problem.String() != tt.expected[i] {
+				t.Errorf("%q: wrong problem. want=%q, got=%q", tt.input, tt.expected[i], problem)
+			}
+		}
+	}
+}
@@ -0,0 +1,265 @@
+// Package code defines the bytecode instructions shared by the compiler
+// and the virtual machine.
+//
+// An instruction is a one byte opcode followed by its operands, which are
+// big endian and one or two bytes wide depending on the opcode.
+package code
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"sort"
+)
+
+type Instructions []byte
+
+type Opcode byte
+
+const (
+	// pushes the constant at operand 0 in the constant pool
+	OpConstant Opcode = iota
+
+	// pop two values, push the result
+	OpAdd
+	OpSub
+	OpMul
+	OpDiv
+	OpMod
+	OpEqual
+	OpNotEqual
+	OpGreaterThan
+	OpLessThan
+
+	// pop one value, push the result
+	OpMinus
+	OpBang
+
+	// pops the top of the stack, used after expression statements
+	OpPop
+
+	OpTrue
+	OpFalse
+	OpNull
+
+	// jump to the absolute offset in operand 0, OpJumpNotTruthy only jumps
+	// if the value it pops is false or null
+	OpJumpNotTruthy
+	OpJump
+	// jumps to operand 0 if the top of the stack isn't null and leaves it
+	// there, pops it otherwise. used for ??
+	OpJumpNotNull
+
+	// read or write the global, local or free variable at operand 0
+	OpGetGlobal
+	OpSetGlobal
+	OpGetLocal
+	OpSetLocal
+	OpGetFree
+
+	// calls the function below the operand 0 arguments on the stack
+	OpCall
+	// returns the top of the stack from the current function
+	OpReturnValue
+	// returns null, or nothing from the main program
+	OpReturn
+
+	// makes a closure from the function constant at operand 0 and the
+	// operand 1 free variables on the stack
+	OpClosure
+	// pushes the closure that is running, used for recursive calls
+	OpCurrentClosure
+
+	// pops operand 0 elements and pushes an array of them
+	OpArray
+	// pops operand 0 values, keys and values taking turns, and pushes a
+	// hash of them
+	OpHash
+	// pops an index and the value below it, pushes the element at that
+	// index
+	OpIndex
+
+	// pushes the builtin at operand 0, in the order of
+	// evaluator.BuiltinNames
+	OpGetBuiltin
+)
+
+type Definition struct {
+	Name          string
+	OperandWidths []int
+}
+
+var definitions = map[Opcode]*Definition{
+	OpConstant:       {"OpConstant", []int{2}},
+	OpAdd:            {"OpAdd", []int{}},
+	OpSub:            {"OpSub", []int{}},
+	OpMul:            {"OpMul", []int{}},
+	OpDiv:            {"OpDiv", []int{}},
+	OpMod:            {"OpMod", []int{}},
+	OpEqual:          {"OpEqual", []int{}},
+	OpNotEqual:       {"OpNotEqual", []int{}},
+	OpGreaterThan:    {"OpGreaterThan", []int{}},
+	OpLessThan:       {"OpLessThan", []int{}},
+	OpMinus:          {"OpMinus", []int{}},
+	OpBang:           {"OpBang", []int{}},
+	OpPop:            {"OpPop", []int{}},
+	OpTrue:           {"OpTrue", []int{}},
+	OpFalse:          {"OpFalse", []int{}},
+	OpNull:           {"OpNull", []int{}},
+	OpJumpNotTruthy:  {"OpJumpNotTruthy", []int{2}},
+	OpJump:           {"OpJump", []int{2}},
+	OpJumpNotNull:    {"OpJumpNotNull", []int{2}},
+	OpGetGlobal:      {"OpGetGlobal", []int{2}},
+	OpSetGlobal:      {"OpSetGlobal", []int{2}},
+	OpGetLocal:       {"OpGetLocal", []int{1}},
+	OpSetLocal:       {"OpSetLocal", []int{1}},
+	OpGetFree:        {"OpGetFree", []int{1}},
+	OpCall:           {"OpCall", []int{1}},
+	OpReturnValue:    {"OpReturnValue", []int{}},
+	OpReturn:         {"OpReturn", []int{}},
+	OpClosure:        {"OpClosure", []int{2, 1}},
+	OpCurrentClosure: {"OpCurrentClosure", []int{}},
+	OpArray:          {"OpArray", []int{2}},
+	OpHash:           {"OpHash", []int{2}},
+	OpIndex:          {"OpIndex", []int{}},
+	OpGetBuiltin:     {"OpGetBuiltin", []int{1}},
+}
+
+func Lookup(op byte) (*Definition, error) {
+	def, ok := definitions[Opcode(op)]
+	if !ok {
+		return nil, fmt.Errorf("opcode %d undefined", op)
+	}
+	return def, nil
+}
+
+// Make encodes one instruction. Operands that don't fit their width are
+// truncated, the compiler checks the limits before calling it
+func Make(op Opcode, operands ...int) []byte {
+	def, ok := definitions[op]
+	if !ok {
+		return []byte{}
+	}
+
+	instructionLen := 1
+	for _, w := range def.OperandWidths {
+		instructionLen += w
+	}
+
+	instruction := make([]byte, instructionLen)
+	instruction[0] = byte(op)
+
+	offset := 1
+	for i, o := range operands {
+		width := def.OperandWidths[i]
+		switch width {
+		case 2:
+			binary.BigEndian.PutUint16(instruction[offset:], uint16(o))
+		case 1:
+			instruction[offset] = byte(o)
+		}
+		offset += width
+	}
+
+	return instruction
+}
+
+// ReadOperands decodes the operands of an instruction and returns them with
+// the number of bytes they took up
+func ReadOperands(def *Definition, ins Instructions) ([]int, int) {
+	operands := make([]int, len(def.OperandWidths))
+	offset := 0
+
+	for i, width := range def.OperandWidths {
+		switch width {
+		case 2:
+			operands[i] = int(ReadUint16(ins[offset:]))
+		case 1:
+			operands[i] = int(ReadUint8(ins[offset:]))
+		}
+		offset += width
+	}
+
+	return operands, offset
+}
+
+func ReadUint16(ins Instructions) uint16 {
+	return binary.BigEndian.Uint16(ins)
+}
+
+func ReadUint8(ins Instructions) uint8 {
+	return uint8(ins[0])
+}
+
+// String lists the instructions one per line with their offsets
+func (ins Instructions) String() string {
+	var out bytes.Buffer
+
+	i := 0
+	for i < len(ins) {
+		def, operands, width, err := ReadInstruction(ins, i)
+		if err != nil {
+			fmt.Fprintf(&out, "ERROR: %s\n", err)
+			return out.String()
+		}
+
+		fmt.Fprintf(&out, "%04d %s\n", i, FormatInstruction(def, operands))
+
+		i += width
+	}
+
+	return out.String()
+}
+
+// ReadInstruction decodes the instruction at offset and returns its
+// definition, its operands and how many bytes it takes up
+func ReadInstruction(ins Instructions, offset int) (*Definition, []int, int, error) {
+	def, err := Lookup(ins[offset])
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	operands, read := ReadOperands(def, ins[offset+1:])
+	return def, operands, 1 + read, nil
+}
+
+// FormatInstruction prints an instruction as its name and operands
+func FormatInstruction(def *Definition, operands []int) string {
+	operandCount := len(def.OperandWidths)
+
+	if len(operands) != operandCount {
+		return fmt.Sprintf("ERROR: operand len %d does not match defined %d\n",
+			len(operands), operandCount)
+	}
+
+	switch operandCount {
+	case 0:
+		return def.Name
+	case 1:
+		return fmt.Sprintf("%s %d", def.Name, operands[0])
+	case 2:
+		return fmt.Sprintf("%s %d %d", def.Name, operands[0], operands[1])
+	}
+
+	return fmt.Sprintf("ERROR: unhandled operandCount for %s\n", def.Name)
+}
+
+// SourceLine says that the instructions from Offset up to the next entry
+// of a SourceMap were compiled from Line
+type SourceLine struct {
+	Offset int
+	Line   int
+}
+
+// SourceMap maps instruction offsets back to source lines, sorted by offset
+type SourceMap []SourceLine
+
+// Line returns the source line of the instruction at offset, or 0 if it
+// isn't known
+func (m SourceMap) Line(offset int) int {
+	// the first entry that starts after offset, the one before it covers it
+	i := sort.Search(len(m), func(i int) bool { return m[i].Offset > offset })
+	if i == 0 {
+		return 0
+	}
+	return m[i-1].Line
+}
@@ -0,0 +1,333 @@
+package compiler
+
+import (
+	"Go-interpreter/code"
+	"Go-interpreter/evaluator"
+	"Go-interpreter/object"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"math"
+)
+
+// The binary form of a compiled program, as written by monkey build:
+//
+//	magic    "MKC\x00"
+//	version  uint16, big endian
+//	globals  count, then each name
+//	main     instructions, source map
+//	constants count, then each constant as a tag byte and its fields
+//	checksum CRC-32 (IEEE) of everything before it, uint32 big endian
+//
+// Counts, lengths and other numbers are unsigned varints, integers are
+// signed varints, strings and instructions are a length and their bytes
+// and a source map is a count and an offset and line per entry. Function
+// constants keep their name, source, parameter and local counts and local
+// names, so loaded programs behave and report errors like freshly
+// compiled ones.
+//
+// FormatVersion must be bumped whenever the layout or the opcodes change,
+// older files are then rejected instead of being run wrongly.
+const FormatVersion = 3
+
+var magic = []byte("MKC\x00")
+
+const (
+	tagInteger  byte = 1
+	tagFunction byte = 2
+	tagString   byte = 3
+)
+
+// IsBinary reports whether data looks like a compiled program rather than
+// source code
+func IsBinary(data []byte) bool {
+	return bytes.HasPrefix(data, magic)
+}
+
+// MarshalBinary encodes the bytecode in the format described above
+func (b *Bytecode) MarshalBinary() ([]byte, error) {
+	e := &encoder{}
+	e.buf.Write(magic)
+	binary.Write(&e.buf, binary.BigEndian, uint16(FormatVersion))
+
+	e.uint(len(b.GlobalNames))
+	for _, name := range b.GlobalNames {
+		e.string(name)
+	}
+	e.bytes(b.Instructions)
+	e.sourceMap(b.SourceMap)
+
+	e.uint(len(b.Constants))
+	for _, c := range b.Constants {
+		switch c := c.(type) {
+		case *object.Integer:
+			e.buf.WriteByte(tagInteger)
+			e.int(c.Value)
+		case *object.String:
+			e.buf.WriteByte(tagString)
+			e.string(c.Value)
+		case *object.CompiledFunction:
+			e.buf.WriteByte(tagFunction)
+			e.string(c.Name)
+			e.string(c.Source)
+			e.uint(c.NumParameters)
+			e.uint(c.NumLocals)
+			e.uint(len(c.LocalNames))
+			for _, name := range c.LocalNames {
+				e.string(name)
+			}
+			e.bytes(c.Instructions)
+			e.sourceMap(c.SourceMap)
+		default:
+			return nil, fmt.Errorf("bytecode: cannot encode constant of type %s", c.Type())
+		}
+	}
+
+	binary.Write(&e.buf, binary.BigEndian, crc32.ChecksumIEEE(e.buf.Bytes()))
+	return e.buf.Bytes(), nil
+}
+
+type encoder struct {
+	buf bytes.Buffer
+}
+
+func (e *encoder) uint(n int) {
+	e.buf.Write(binary.AppendUvarint(nil, uint64(n)))
+}
+
+func (e *encoder) int(n int64) {
+	e.buf.Write(binary.AppendVarint(nil, n))
+}
+
+func (e *encoder) bytes(b []byte) {
+	e.uint(len(b))
+	e.buf.Write(b)
+}
+
+func (e *encoder) string(s string) {
+	e.bytes([]byte(s))
+}
+
+func (e *encoder) sourceMap(m code.SourceMap) {
+	e.uint(len(m))
+	for _, l := range m {
+		e.uint(l.Offset)
+		e.uint(l.Line)
+	}
+}
+
+var errTruncated = errors.New("bytecode: file is truncated")
+
+// UnmarshalBinary decodes bytecode written by MarshalBinary. Files from
+// another format version, corrupted files and instructions that would
+// make the vm read past its constants or globals are rejected
+func (b *Bytecode) UnmarshalBinary(data []byte) error {
+	if !IsBinary(data) {
+		return errors.New("bytecode: not a compiled monkey program")
+	}
+	if len(data) < len(magic)+2+4 {
+		return errTruncated
+	}
+	version := binary.BigEndian.Uint16(data[len(magic):])
+	if version != FormatVersion {
+		return fmt.Errorf("bytecode: compiled with format version %d, this monkey only runs version %d; rebuild it from source",
+			version, FormatVersion)
+	}
+	body, checksum := data[:len(data)-4], binary.BigEndian.Uint32(data[len(data)-4:])
+	if crc32.ChecksumIEEE(body) != checksum {
+		return errors.New("bytecode: checksum mismatch, the file is corrupt")
+	}
+
+	d := &decoder{data: body[len(magic)+2:]}
+
+	globals := make([]string, d.count())
+	for i := range globals {
+		globals[i] = d.string()
+	}
+	instructions := code.Instructions(d.bytes())
+	sourceMap := d.sourceMap()
+
+	constants := make([]object.Object, d.count())
+	for i := range constants {
+		switch tag := d.byte(); tag {
+		case tagInteger:
+			constants[i] = &object.Integer{Value: d.int()}
+		case tagString:
+			constants[i] = &object.String{Value: d.string()}
+		case tagFunction:
+			fn := &object.CompiledFunction{
+				Name:          d.string(),
+				Source:        d.string(),
+				NumParameters: d.uint(),
+				NumLocals:     d.uint(),
+			}
+			fn.LocalNames = make([]string, d.count())
+			for j := range fn.LocalNames {
+				fn.LocalNames[j] = d.string()
+			}
+			fn.Instructions = d.bytes()
+			fn.SourceMap = d.sourceMap()
+			constants[i] = fn
+		default:
+			if d.err == nil {
+				d.err = fmt.Errorf("bytecode: unknown constant tag %d", tag)
+			}
+		}
+	}
+	if d.err != nil {
+		return d.err
+	}
+	if len(d.data) != 0 {
+		return errors.New("bytecode: unexpected data after the constants")
+	}
+
+	if err := validate(instructions, len(globals), constants, 0); err != nil {
+		return fmt.Errorf("bytecode: main program: %s", err)
+	}
+	for i, c := range constants {
+		if fn, ok := c.(*object.CompiledFunction); ok {
+			if fn.NumParameters > fn.NumLocals || len(fn.LocalNames) != fn.NumLocals {
+				return fmt.Errorf("bytecode: constant %d: inconsistent local counts", i)
+			}
+			if err := validate(fn.Instructions, len(globals), constants, fn.NumLocals); err != nil {
+				return fmt.Errorf("bytecode: constant %d: %s", i, err)
+			}
+		}
+	}
+
+	*b = Bytecode{
+		Instructions: instructions,
+		Constants:    constants,
+		GlobalNames:  globals,
+		SourceMap:    sourceMap,
+	}
+	return nil
+}
+
+// decoder reads the fields back in order. after the first error every
+// read returns a zero value and the error is kept in err
+type decoder struct {
+	data []byte
+	err  error
+}
+
+func (d *decoder) fail() {
+	if d.err == nil {
+		d.err = errTruncated
+	}
+	d.data = nil
+}
+
+func (d *decoder) byte() byte {
+	if len(d.data) == 0 {
+		d.fail()
+		return 0
+	}
+	b := d.data[0]
+	d.data = d.data[1:]
+	return b
+}
+
+func (d *decoder) uint() int {
+	n, read := binary.Uvarint(d.data)
+	if read <= 0 || n > math.MaxInt32 {
+		d.fail()
+		return 0
+	}
+	d.data = d.data[read:]
+	return int(n)
+}
+
+func (d *decoder) int() int64 {
+	n, read := binary.Varint(d.data)
+	if read <= 0 {
+		d.fail()
+		return 0
+	}
+	d.data = d.data[read:]
+	return n
+}
+
+// count reads the length of a list, it can't be more than the bytes left
+// so a corrupt length doesn't allocate a huge slice
+func (d *decoder) count() int {
+	n := d.uint()
+	if n > len(d.data) {
+		d.fail()
+		return 0
+	}
+	return n
+}
+
+func (d *decoder) bytes() []byte {
+	n := d.count()
+	b := d.data[:n:n]
+	d.data = d.data[n:]
+	return b
+}
+
+func (d *decoder) string() string {
+	return string(d.bytes())
+}
+
+func (d *decoder) sourceMap() code.SourceMap {
+	m := make(code.SourceMap, d.count())
+	for i := range m {
+		m[i] = code.SourceLine{Offset: d.uint(), Line: d.uint()}
+	}
+	return m
+}
+
+// validate checks that every instruction is complete and that the
+// constants, globals and locals it refers to exist
+func validate(ins code.Instructions, numGlobals int, constants []object.Object, numLocals int) error {
+	for offset := 0; offset < len(ins); {
+		def, err := code.Lookup(ins[offset])
+		if err != nil {
+			return fmt.Errorf("offset %04d: %s", offset, err)
+		}
+		width := 1
+		for _, w := range def.OperandWidths {
+			width += w
+		}
+		if offset+width > len(ins) {
+			return fmt.Errorf("offset %04d: %s is cut off", offset, def.Name)
+		}
+		operands, _ := code.ReadOperands(def, ins[offset+1:])
+
+		switch code.Opcode(ins[offset]) {
+		case code.OpConstant:
+			if operands[0] >= len(constants) {
+				return fmt.Errorf("offset %04d: no constant %d", offset, operands[0])
+			}
+		case code.OpClosure:
+			if operands[0] >= len(constants) {
+				return fmt.Errorf("offset %04d: no constant %d", offset, operands[0])
+			}
+			if _, ok := constants[operands[0]].(*object.CompiledFunction); !ok {
+				return fmt.Errorf("offset %04d: constant %d is not a function", offset, operands[0])
+			}
+		case code.OpGetGlobal, code.OpSetGlobal:
+			if operands[0] >= numGlobals {
+				return fmt.Errorf("offset %04d: no global %d", offset, operands[0])
+			}
+		case code.OpGetLocal, code.OpSetLocal:
+			if operands[0] >= numLocals {
+				return fmt.Errorf("offset %04d: no local %d", offset, operands[0])
+			}
+		case code.OpGetBuiltin:
+			if operands[0] >= len(evaluator.BuiltinNames) {
+				return fmt.Errorf("offset %04d: no builtin %d", offset, operands[0])
+			}
+		case code.OpJump, code.OpJumpNotTruthy, code.OpJumpNotNull:
+			if operands[0] > len(ins) {
+				return fmt.Errorf("offset %04d: jump out of range", offset)
+			}
+		}
+
+		offset += width
+	}
+	return nil
+}
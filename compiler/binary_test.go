@@ -0,0 +1,115 @@
+package compiler
+
+import (
+	"Go-interpreter/code"
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"strings"
+	"testing"
+)
+
+func compileBinary(t *testing.T, input string) (*Bytecode, []byte) {
+	t.Helper()
+
+	compiler := New()
+	if err := compiler.Compile(parse(input)); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+	bytecode := compiler.Bytecode()
+	data, err := bytecode.MarshalBinary()
+	if err != nil {
+		t.Fatalf("marshal error: %s", err)
+	}
+	return bytecode, data
+}
+
+func disassemble(t *testing.T, bytecode *Bytecode) string {
+	t.Helper()
+
+	var out bytes.Buffer
+	if err := Disassemble(&out, bytecode); err != nil {
+		t.Fatalf("disassemble error: %s", err)
+	}
+	return out.String()
+}
+
+func TestBinaryRoundTrip(t *testing.T) {
+	input := `let big = 100000000000;
+let neg = -5;
+let counter = fn(n) {
+	let step = fn(x) { x + n };
+	if (n > 0) { counter(n - 1) } else { step(big) }
+};
+let greeting = "héllo";
+[counter(3) + neg, {greeting: len(greeting)}]`
+
+	original, data := compileBinary(t, input)
+	if !IsBinary(data) {
+		t.Fatalf("encoded program isn't recognized as binary")
+	}
+
+	var loaded Bytecode
+	if err := loaded.UnmarshalBinary(data); err != nil {
+		t.Fatalf("unmarshal error: %s", err)
+	}
+
+	// the disassembly shows every field but the function sources
+	if want, got := disassemble(t, original), disassemble(t, &loaded); want != got {
+		t.Errorf("loaded program differs.\nwant=\n%s\ngot=\n%s", want, got)
+	}
+	for i, c := range original.Constants {
+		if c.Inspect() != loaded.Constants[i].Inspect() {
+			t.Errorf("constant %d differs. want=%q, got=%q", i, c.Inspect(), loaded.Constants[i].Inspect())
+		}
+	}
+}
+
+// reseal fixes the checksum of data after it was modified
+func reseal(data []byte) []byte {
+	body := data[:len(data)-4]
+	return binary.BigEndian.AppendUint32(body, crc32.ChecksumIEEE(body))
+}
+
+func TestBinaryErrors(t *testing.T) {
+	_, data := compileBinary(t, "let f = fn(a) { a * 2 }; f(21)")
+
+	clone := func() []byte { return append([]byte{}, data...) }
+
+	newerVersion := clone()
+	binary.BigEndian.PutUint16(newerVersion[len(magic):], FormatVersion+1)
+
+	corrupted := clone()
+	corrupted[len(corrupted)/2] ^= 0xff
+
+	// a valid checksum but a global index that doesn't exist
+	badGlobal := clone()
+	i := bytes.Index(badGlobal, []byte{byte(code.OpSetGlobal), 0, 0})
+	badGlobal[i+2] = 9
+	badGlobal = reseal(badGlobal)
+
+	tests := []struct {
+		data     []byte
+		expected string
+	}{
+		{[]byte("let a = 1;"), "bytecode: not a compiled monkey program"},
+		{newerVersion, "bytecode: compiled with format version 4, this monkey only runs version 3; rebuild it from source"},
+		{corrupted, "bytecode: checksum mismatch, the file is corrupt"},
+		{data[:len(data)-1], "bytecode: checksum mismatch, the file is corrupt"},
+		{reseal(clone()[:len(data)-8]), "bytecode: file is truncated"},
+		{data[:len(magic)+3], "bytecode: file is truncated"},
+		{badGlobal, "bytecode: main program: offset 0004: no global 9"},
+	}
+
+	for _, tt := range tests {
+		var b Bytecode
+		err := b.UnmarshalBinary(tt.data)
+		if err == nil {
+			t.Errorf("expected error %q, got none", tt.expected)
+			continue
+		}
+		if !strings.HasPrefix(err.Error(), tt.expected) {
+			t.Errorf("wrong error. want=%q, got=%q", tt.expected, err)
+		}
+	}
+}
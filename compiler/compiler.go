@@ -0,0 +1,608 @@
+// Package compiler turns an AST into bytecode for the vm package.
+//
+// The compiled program behaves exactly like the tree-walking evaluator,
+// including its error messages. Names that can't be resolved while
+// compiling are treated as globals that are defined later, so using an
+// undefined name is a runtime error just like in the evaluator. Declaring
+// a constant again in the same scope is refused while compiling, where
+// the evaluator only fails when it gets to the declaration.
+//
+// Not everything has bytecode yet. Loops, assignment, match, throw and
+// try, the ? operator, method calls, destructuring and default, rest,
+// spread and named arguments fail to compile with an error that says so,
+// programs using them can only be run by the evaluator.
+package compiler
+
+import (
+	"Go-interpreter/ast"
+	"Go-interpreter/code"
+	"Go-interpreter/evaluator"
+	"Go-interpreter/object"
+	"fmt"
+	"math"
+)
+
+type Compiler struct {
+	constants   []object.Object
+	symbolTable *SymbolTable
+
+	scopes     []CompilationScope
+	scopeIndex int
+
+	line int // the source line of the node being compiled
+}
+
+// CompilationScope holds the instructions of the function being compiled
+type CompilationScope struct {
+	instructions        code.Instructions
+	sourceMap           code.SourceMap
+	lastInstruction     EmittedInstruction
+	previousInstruction EmittedInstruction
+}
+
+type EmittedInstruction struct {
+	Opcode   code.Opcode
+	Position int
+}
+
+// Bytecode is everything the vm needs to run a program
+type Bytecode struct {
+	Instructions code.Instructions
+	Constants    []object.Object
+	GlobalNames  []string // the name of each global slot, for error messages
+	SourceMap    code.SourceMap
+}
+
+func New() *Compiler {
+	mainScope := CompilationScope{
+		instructions:        code.Instructions{},
+		lastInstruction:     EmittedInstruction{},
+		previousInstruction: EmittedInstruction{},
+	}
+
+	return &Compiler{
+		constants:   []object.Object{},
+		symbolTable: NewSymbolTable(),
+		scopes:      []CompilationScope{mainScope},
+		scopeIndex:  0,
+	}
+}
+
+// Compile compiles a whole program. The value of its last expression
+// statement, or of a top level return, is the result of running it
+func (c *Compiler) Compile(program *ast.Program) error {
+	for _, s := range program.Statements {
+		if err := c.compile(s); err != nil {
+			return err
+		}
+	}
+
+	// global indexes are two bytes wide like constant indexes
+	if numGlobals := len(c.symbolTable.global().slots); numGlobals > math.MaxUint16+1 {
+		return fmt.Errorf("too many globals: %d", numGlobals)
+	}
+
+	if c.lastInstructionIs(code.OpPop) {
+		c.replaceLastPopWithReturn()
+	}
+	if !c.lastInstructionIs(code.OpReturnValue) {
+		c.emit(code.OpReturn)
+	}
+	return nil
+}
+
+func (c *Compiler) Bytecode() *Bytecode {
+	return &Bytecode{
+		Instructions: c.currentInstructions(),
+		Constants:    c.constants,
+		GlobalNames:  c.symbolTable.global().names(),
+		SourceMap:    c.scopes[c.scopeIndex].sourceMap,
+	}
+}
+
+func (c *Compiler) compile(node ast.Node) error {
+	// instructions are mapped to the line of the innermost node they are
+	// compiled from
+	if line := ast.TokenOf(node).Line; line != 0 && line != c.line {
+		outer := c.line
+		c.line = line
+		defer func() { c.line = outer }()
+	}
+
+	switch node := node.(type) {
+	case *ast.ExpressionStatement:
+		if err := c.compile(node.Expression); err != nil {
+			return err
+		}
+		c.emit(code.OpPop)
+
+	case *ast.LetStatement:
+		name, ok := node.Name.(*ast.Identifier)
+		if !ok {
+			return fmt.Errorf("cannot compile destructuring let %s", node.Name)
+		}
+		// checked before the value is compiled, the evaluator doesn't
+		// evaluate it either
+		if c.symbolTable.IsConst(name.Value) {
+			return fmt.Errorf("cannot redeclare constant %s", name.Value)
+		}
+		// the value is compiled first so that it still sees the binding the
+		// name had before, like in the evaluator
+		if err := c.compileLetValue(node); err != nil {
+			return err
+		}
+		var symbol Symbol
+		if node.IsConst() {
+			symbol = c.symbolTable.DefineConst(name.Value)
+		} else {
+			symbol = c.symbolTable.Define(name.Value)
+		}
+		if symbol.Scope == GlobalScope {
+			c.emit(code.OpSetGlobal, symbol.Index)
+		} else {
+			c.emit(code.OpSetLocal, symbol.Index)
+		}
+
+	case *ast.ReturnStatement:
+		if err := c.compile(node.ReturnValue); err != nil {
+			return err
+		}
+		c.emit(code.OpReturnValue)
+
+	case *ast.BlockStatement:
+		for _, s := range node.Statements {
+			if err := c.compile(s); err != nil {
+				return err
+			}
+		}
+
+	case *ast.IntegerLiteral:
+		integer := &object.Integer{Value: node.Value}
+		return c.emitConstant(integer)
+
+	case *ast.StringLiteral:
+		str := &object.String{Value: node.Value}
+		return c.emitConstant(str)
+
+	case *ast.ArrayLiteral:
+		for _, el := range node.Elements {
+			if err := c.compile(el); err != nil {
+				return err
+			}
+		}
+		if len(node.Elements) > math.MaxUint16 {
+			return fmt.Errorf("too many elements: %d", len(node.Elements))
+		}
+		c.emit(code.OpArray, len(node.Elements))
+
+	case *ast.HashLiteral:
+		// key first, pair by pair, in the order the evaluator goes
+		for _, pair := range node.Pairs {
+			if err := c.compile(pair.Key); err != nil {
+				return err
+			}
+			if err := c.compile(pair.Value); err != nil {
+				return err
+			}
+		}
+		if 2*len(node.Pairs) > math.MaxUint16 {
+			return fmt.Errorf("too many pairs: %d", len(node.Pairs))
+		}
+		c.emit(code.OpHash, 2*len(node.Pairs))
+
+	case *ast.IndexExpression:
+		if err := c.compile(node.Left); err != nil {
+			return err
+		}
+		if err := c.compile(node.Index); err != nil {
+			return err
+		}
+		c.emit(code.OpIndex)
+
+	case *ast.Boolean:
+		if node.Value {
+			c.emit(code.OpTrue)
+		} else {
+			c.emit(code.OpFalse)
+		}
+
+	case *ast.NullLiteral:
+		c.emit(code.OpNull)
+
+	case *ast.ConditionalExpression:
+		return c.compileConditionalExpression(node)
+
+	case *ast.PrefixExpression:
+		if err := c.compile(node.Right); err != nil {
+			return err
+		}
+		switch node.Operator {
+		case "!":
+			c.emit(code.OpBang)
+		case "-":
+			c.emit(code.OpMinus)
+		default:
+			return fmt.Errorf("unknown operator %s", node.Operator)
+		}
+
+	case *ast.InfixExpression:
+		if node.Operator == "??" {
+			return c.compileCoalesceExpression(node)
+		}
+		if err := c.compile(node.Left); err != nil {
+			return err
+		}
+		if err := c.compile(node.Right); err != nil {
+			return err
+		}
+		op, ok := infixOpcodes[node.Operator]
+		if !ok {
+			return fmt.Errorf("unknown operator %s", node.Operator)
+		}
+		c.emit(op)
+
+	case *ast.IfExpression:
+		return c.compileIfExpression(node)
+
+	case *ast.Identifier:
+		symbol, ok := c.symbolTable.Resolve(node.Value)
+		if !ok {
+			// a builtin unless a binding hides it, otherwise it might be
+			// defined later and the vm reports it if it isn't
+			if index := builtinIndex(node.Value); index >= 0 {
+				c.emit(code.OpGetBuiltin, index)
+				return nil
+			}
+			symbol = c.symbolTable.global().defineLater(node.Value)
+		}
+		c.loadSymbol(symbol)
+
+	case *ast.FunctionLiteral:
+		return c.compileFunctionLiteral(node, "")
+
+	case *ast.CallExpression:
+		if err := c.compile(node.Function); err != nil {
+			return err
+		}
+		for _, a := range node.Arguments {
+			if err := c.compile(a); err != nil {
+				return err
+			}
+		}
+		if len(node.Arguments) > math.MaxUint8 {
+			return fmt.Errorf("too many arguments: %d", len(node.Arguments))
+		}
+		c.emit(code.OpCall, len(node.Arguments))
+
+	default:
+		return fmt.Errorf("cannot compile %T", node)
+	}
+
+	return nil
+}
+
+var infixOpcodes = map[string]code.Opcode{
+	"+":  code.OpAdd,
+	"-":  code.OpSub,
+	"*":  code.OpMul,
+	"/":  code.OpDiv,
+	"%":  code.OpMod,
+	"==": code.OpEqual,
+	"!=": code.OpNotEqual,
+	">":  code.OpGreaterThan,
+	"<":  code.OpLessThan,
+}
+
+// builtinIndex is the index of the builtin called name, -1 if there is
+// none
+func builtinIndex(name string) int {
+	for i, builtin := range evaluator.BuiltinNames {
+		if builtin == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// functions bound with let can call themselves by that name
+func (c *Compiler) compileLetValue(node *ast.LetStatement) error {
+	if fn, ok := node.Value.(*ast.FunctionLiteral); ok {
+		return c.compileFunctionLiteral(fn, node.Name.String())
+	}
+	return c.compile(node.Value)
+}
+
+// if is an expression, so both branches leave exactly one value on the
+// stack. a missing else or a branch that ends without a value gives null
+func (c *Compiler) compileIfExpression(node *ast.IfExpression) error {
+	if err := c.compile(node.Condition); err != nil {
+		return err
+	}
+
+	// the target is patched in once we know where the else branch starts
+	jumpNotTruthyPos := c.emit(code.OpJumpNotTruthy, 9999)
+
+	if err := c.compileBlockValue(node.Then); err != nil {
+		return err
+	}
+
+	jumpPos := c.emit(code.OpJump, 9999)
+	if err := c.patchJump(jumpNotTruthyPos); err != nil {
+		return err
+	}
+
+	if node.Else == nil {
+		c.emit(code.OpNull)
+	} else if err := c.compileBlockValue(node.Else); err != nil {
+		return err
+	}
+
+	return c.patchJump(jumpPos)
+}
+
+// c ? a : b is compiled like an if with both branches, only with
+// expressions in place of the blocks
+func (c *Compiler) compileConditionalExpression(node *ast.ConditionalExpression) error {
+	if err := c.compile(node.Condition); err != nil {
+		return err
+	}
+	jumpNotTruthyPos := c.emit(code.OpJumpNotTruthy, 9999)
+	if err := c.compile(node.Consequence); err != nil {
+		return err
+	}
+	jumpPos := c.emit(code.OpJump, 9999)
+	if err := c.patchJump(jumpNotTruthyPos); err != nil {
+		return err
+	}
+	if err := c.compile(node.Alternative); err != nil {
+		return err
+	}
+	return c.patchJump(jumpPos)
+}
+
+// a ?? b leaves a on the stack unless it is null, b is only run if it is
+func (c *Compiler) compileCoalesceExpression(node *ast.InfixExpression) error {
+	if err := c.compile(node.Left); err != nil {
+		return err
+	}
+	jumpNotNullPos := c.emit(code.OpJumpNotNull, 9999)
+	if err := c.compile(node.Right); err != nil {
+		return err
+	}
+	return c.patchJump(jumpNotNullPos)
+}
+
+// compileBlockValue compiles a block that is used as a value. like in the
+// evaluator the block is a scope of its own
+func (c *Compiler) compileBlockValue(block *ast.BlockStatement) error {
+	c.symbolTable.EnterBlock()
+	err := c.compile(block)
+	c.symbolTable.LeaveBlock()
+	if err != nil {
+		return err
+	}
+	if c.lastInstructionIs(code.OpPop) && endsWithExpression(block) {
+		c.removeLastPop()
+	} else {
+		c.emit(code.OpNull)
+	}
+	return nil
+}
+
+func endsWithExpression(block *ast.BlockStatement) bool {
+	if len(block.Statements) == 0 {
+		return false
+	}
+	_, ok := block.Statements[len(block.Statements)-1].(*ast.ExpressionStatement)
+	return ok
+}
+
+func (c *Compiler) compileFunctionLiteral(node *ast.FunctionLiteral, name string) error {
+	if node.Defaults != nil || node.Rest != nil {
+		return fmt.Errorf("cannot compile default or rest parameters: fn(%s)",
+			ast.ParameterList(node.Parameters, node.Defaults, node.Rest))
+	}
+	c.enterScope()
+
+	if name != "" {
+		c.symbolTable.DefineFunctionName(name)
+	}
+	for _, p := range node.Parameters {
+		c.symbolTable.Define(p.Value)
+	}
+
+	if err := c.compile(node.Body); err != nil {
+		return err
+	}
+
+	if c.lastInstructionIs(code.OpPop) && endsWithExpression(node.Body) {
+		c.replaceLastPopWithReturn()
+	}
+	if !c.lastInstructionIs(code.OpReturnValue) {
+		c.emit(code.OpReturn)
+	}
+
+	freeSymbols := c.symbolTable.FreeSymbols
+	numLocals := c.symbolTable.numDefinitions
+	localNames := c.symbolTable.names()
+	sourceMap := c.scopes[c.scopeIndex].sourceMap
+	instructions := c.leaveScope()
+
+	if numLocals > math.MaxUint8 {
+		return fmt.Errorf("too many local bindings in function: %d", numLocals)
+	}
+	if len(freeSymbols) > math.MaxUint8 {
+		return fmt.Errorf("too many free variables in function: %d", len(freeSymbols))
+	}
+
+	// push the captured variables so OpClosure can take them off the stack
+	for _, s := range freeSymbols {
+		c.loadSymbol(s)
+	}
+
+	compiledFn := &object.CompiledFunction{
+		Instructions:  instructions,
+		NumLocals:     numLocals,
+		NumParameters: len(node.Parameters),
+		LocalNames:    localNames,
+		Name:          name,
+		Source:        object.FunctionSource(ast.ParameterList(node.Parameters, nil, nil), node.Body),
+		SourceMap:     sourceMap,
+	}
+
+	index, err := c.addConstant(compiledFn)
+	if err != nil {
+		return err
+	}
+	c.emit(code.OpClosure, index, len(freeSymbols))
+	return nil
+}
+
+func (c *Compiler) loadSymbol(s Symbol) {
+	switch s.Scope {
+	case GlobalScope:
+		c.emit(code.OpGetGlobal, s.Index)
+	case LocalScope:
+		c.emit(code.OpGetLocal, s.Index)
+	case FreeScope:
+		c.emit(code.OpGetFree, s.Index)
+	case FunctionScope:
+		c.emit(code.OpCurrentClosure)
+	}
+}
+
+func (c *Compiler) addConstant(obj object.Object) (int, error) {
+	if len(c.constants) > math.MaxUint16 {
+		return 0, fmt.Errorf("too many constants")
+	}
+	c.constants = append(c.constants, obj)
+	return len(c.constants) - 1, nil
+}
+
+func (c *Compiler) emitConstant(obj object.Object) error {
+	index, err := c.addConstant(obj)
+	if err != nil {
+		return err
+	}
+	c.emit(code.OpConstant, index)
+	return nil
+}
+
+// emit adds an instruction and returns its position
+func (c *Compiler) emit(op code.Opcode, operands ...int) int {
+	ins := code.Make(op, operands...)
+	pos := c.addInstruction(ins)
+
+	c.setLastInstruction(op, pos)
+
+	return pos
+}
+
+func (c *Compiler) addInstruction(ins []byte) int {
+	posNewInstruction := len(c.currentInstructions())
+	updatedInstructions := append(c.currentInstructions(), ins...)
+
+	c.scopes[c.scopeIndex].instructions = updatedInstructions
+
+	sourceMap := c.scopes[c.scopeIndex].sourceMap
+	if len(sourceMap) == 0 || sourceMap[len(sourceMap)-1].Line != c.line {
+		line := code.SourceLine{Offset: posNewInstruction, Line: c.line}
+		c.scopes[c.scopeIndex].sourceMap = append(sourceMap, line)
+	}
+
+	return posNewInstruction
+}
+
+func (c *Compiler) setLastInstruction(op code.Opcode, pos int) {
+	previous := c.scopes[c.scopeIndex].lastInstruction
+	last := EmittedInstruction{Opcode: op, Position: pos}
+
+	c.scopes[c.scopeIndex].previousInstruction = previous
+	c.scopes[c.scopeIndex].lastInstruction = last
+}
+
+func (c *Compiler) currentInstructions() code.Instructions {
+	return c.scopes[c.scopeIndex].instructions
+}
+
+func (c *Compiler) lastInstructionIs(op code.Opcode) bool {
+	if len(c.currentInstructions()) == 0 {
+		return false
+	}
+
+	return c.scopes[c.scopeIndex].lastInstruction.Opcode == op
+}
+
+func (c *Compiler) removeLastPop() {
+	last := c.scopes[c.scopeIndex].lastInstruction
+	previous := c.scopes[c.scopeIndex].previousInstruction
+
+	old := c.currentInstructions()
+	new := old[:last.Position]
+
+	c.scopes[c.scopeIndex].instructions = new
+	c.scopes[c.scopeIndex].lastInstruction = previous
+
+	// forget the line of the pop if it started a new entry
+	sourceMap := c.scopes[c.scopeIndex].sourceMap
+	for len(sourceMap) > 0 && sourceMap[len(sourceMap)-1].Offset >= last.Position {
+		sourceMap = sourceMap[:len(sourceMap)-1]
+	}
+	c.scopes[c.scopeIndex].sourceMap = sourceMap
+}
+
+func (c *Compiler) replaceLastPopWithReturn() {
+	lastPos := c.scopes[c.scopeIndex].lastInstruction.Position
+	c.replaceInstruction(lastPos, code.Make(code.OpReturnValue))
+
+	c.scopes[c.scopeIndex].lastInstruction.Opcode = code.OpReturnValue
+}
+
+func (c *Compiler) replaceInstruction(pos int, newInstruction []byte) {
+	ins := c.currentInstructions()
+
+	for i := 0; i < len(newInstruction); i++ {
+		ins[pos+i] = newInstruction[i]
+	}
+}
+
+// patchJump points the jump at pos to the end of the instructions so far.
+// jump operands are two bytes wide, a jump can't go any further than that
+func (c *Compiler) patchJump(pos int) error {
+	target := len(c.currentInstructions())
+	if target > math.MaxUint16 {
+		return fmt.Errorf("cannot compile jump to offset %d, the limit is %d", target, math.MaxUint16)
+	}
+	c.changeOperand(pos, target)
+	return nil
+}
+
+func (c *Compiler) changeOperand(opPos int, operand int) {
+	op := code.Opcode(c.currentInstructions()[opPos])
+	newInstruction := code.Make(op, operand)
+
+	c.replaceInstruction(opPos, newInstruction)
+}
+
+func (c *Compiler) enterScope() {
+	scope := CompilationScope{
+		instructions:        code.Instructions{},
+		lastInstruction:     EmittedInstruction{},
+		previousInstruction: EmittedInstruction{},
+	}
+	c.scopes = append(c.scopes, scope)
+	c.scopeIndex++
+
+	c.symbolTable = NewEnclosedSymbolTable(c.symbolTable)
+}
+
+func (c *Compiler) leaveScope() code.Instructions {
+	instructions := c.currentInstructions()
+
+	c.scopes = c.scopes[:len(c.scopes)-1]
+	c.scopeIndex--
+
+	c.symbolTable = c.symbolTable.Outer
+
+	return instructions
+}
@@ -0,0 +1,118 @@
+package compiler
+
+import (
+	"Go-interpreter/code"
+	"Go-interpreter/evaluator"
+	"Go-interpreter/object"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Disassemble prints the bytecode of a program in a readable form: the
+// main program, the constant pool and then every compiled function.
+//
+// Each instruction is printed with its offset, the source line it came
+// from ("|" when it's the same as the one above) and its operands. When
+// an operand refers to a constant, a global or a local, a comment after
+// the instruction says which one.
+func Disassemble(w io.Writer, bytecode *Bytecode) error {
+	d := &disassembler{w: w, constants: bytecode.Constants, globals: bytecode.GlobalNames}
+
+	main := &object.CompiledFunction{
+		Instructions: bytecode.Instructions,
+		SourceMap:    bytecode.SourceMap,
+	}
+	fmt.Fprintln(w, "== main ==")
+	if err := d.function(main); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(w, "\n== constants ==")
+	for i, c := range bytecode.Constants {
+		fmt.Fprintf(w, "%4d %s %s\n", i, c.Type(), d.constant(i))
+	}
+
+	for i, c := range bytecode.Constants {
+		fn, ok := c.(*object.CompiledFunction)
+		if !ok {
+			continue
+		}
+		fmt.Fprintf(w, "\n== %s (constant %d) ==\n", d.constant(i), i)
+		fmt.Fprintf(w, "parameters %d, locals %d\n", fn.NumParameters, fn.NumLocals)
+		if err := d.function(fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type disassembler struct {
+	w         io.Writer
+	constants []object.Object
+	globals   []string
+}
+
+func (d *disassembler) function(fn *object.CompiledFunction) error {
+	ins := fn.Instructions
+	lastLine := -1
+
+	for offset := 0; offset < len(ins); {
+		def, operands, width, err := code.ReadInstruction(ins, offset)
+		if err != nil {
+			return fmt.Errorf("offset %04d: %s", offset, err)
+		}
+
+		line := "   |"
+		if l := fn.SourceMap.Line(offset); l != lastLine {
+			line = fmt.Sprintf("%4d", l)
+			lastLine = l
+		}
+
+		text := code.FormatInstruction(def, operands)
+		if comment := d.comment(fn, code.Opcode(ins[offset]), operands); comment != "" {
+			text = fmt.Sprintf("%-24s ; %s", text, comment)
+		}
+		fmt.Fprintf(d.w, "%04d %s %s\n", offset, line, text)
+
+		offset += width
+	}
+	return nil
+}
+
+// comment explains what the operands of an instruction refer to
+func (d *disassembler) comment(fn *object.CompiledFunction, op code.Opcode, operands []int) string {
+	switch op {
+	case code.OpConstant, code.OpClosure:
+		return d.constant(operands[0])
+	case code.OpGetGlobal, code.OpSetGlobal:
+		if operands[0] < len(d.globals) {
+			return d.globals[operands[0]]
+		}
+	case code.OpGetLocal, code.OpSetLocal:
+		if operands[0] < len(fn.LocalNames) {
+			return fn.LocalNames[operands[0]]
+		}
+	case code.OpGetBuiltin:
+		if operands[0] < len(evaluator.BuiltinNames) {
+			return evaluator.BuiltinNames[operands[0]]
+		}
+	}
+	return ""
+}
+
+// constant describes a constant in one line, functions by their name
+func (d *disassembler) constant(index int) string {
+	if index >= len(d.constants) {
+		return fmt.Sprintf("<missing constant %d>", index)
+	}
+	switch c := d.constants[index].(type) {
+	case *object.CompiledFunction:
+		if c.Name == "" {
+			return "<fn>"
+		}
+		return "<fn " + c.Name + ">"
+	default:
+		return strings.ReplaceAll(c.Inspect(), "\n", " ")
+	}
+}
@@ -0,0 +1,109 @@
+package compiler
+
+import (
+	"Go-interpreter/code"
+	"Go-interpreter/object"
+	"bytes"
+	"testing"
+)
+
+func TestSourceMap(t *testing.T) {
+	input := `let a = 1;
+let b = fn(x) {
+	x +
+	a
+};
+b(a)`
+
+	compiler := New()
+	if err := compiler.Compile(parse(input)); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+	bytecode := compiler.Bytecode()
+
+	expected := code.SourceMap{
+		{Offset: 0, Line: 1},  // OpConstant 1, OpSetGlobal a
+		{Offset: 6, Line: 2},  // OpClosure, OpSetGlobal b
+		{Offset: 13, Line: 6}, // the call
+	}
+	if !equalSourceMaps(bytecode.SourceMap, expected) {
+		t.Errorf("wrong main source map.\nwant=%v\ngot=%v", expected, bytecode.SourceMap)
+	}
+
+	fn, ok := bytecode.Constants[1].(*object.CompiledFunction)
+	if !ok {
+		t.Fatalf("constant 1 is not a function: %T", bytecode.Constants[1])
+	}
+	expected = code.SourceMap{
+		{Offset: 0, Line: 3}, // OpGetLocal x
+		{Offset: 2, Line: 4}, // OpGetGlobal a
+		{Offset: 5, Line: 3}, // OpAdd on the line of the operator
+	}
+	if !equalSourceMaps(fn.SourceMap, expected) {
+		t.Errorf("wrong function source map.\nwant=%v\ngot=%v", expected, fn.SourceMap)
+	}
+}
+
+func equalSourceMaps(a, b code.SourceMap) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestDisassemble(t *testing.T) {
+	input := `let add = fn(a, b) {
+	a + b
+};
+add(1, 2);
+fn() { 3 }`
+
+	compiler := New()
+	if err := compiler.Compile(parse(input)); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+
+	var out bytes.Buffer
+	if err := Disassemble(&out, compiler.Bytecode()); err != nil {
+		t.Fatalf("disassemble error: %s", err)
+	}
+
+	expected := `== main ==
+0000    1 OpClosure 0 0            ; <fn add>
+0004    | OpSetGlobal 0            ; add
+0007    4 OpGetGlobal 0            ; add
+0010    | OpConstant 1             ; 1
+0013    | OpConstant 2             ; 2
+0016    | OpCall 2
+0018    | OpPop
+0019    5 OpClosure 4 0            ; <fn>
+0023    | OpReturnValue
+
+== constants ==
+   0 COMPILED_FUNCTION <fn add>
+   1 INTEGER 1
+   2 INTEGER 2
+   3 INTEGER 3
+   4 COMPILED_FUNCTION <fn>
+
+== <fn add> (constant 0) ==
+parameters 2, locals 2
+0000    2 OpGetLocal 0             ; a
+0002    | OpGetLocal 1             ; b
+0004    | OpAdd
+0005    | OpReturnValue
+
+== <fn> (constant 4) ==
+parameters 0, locals 0
+0000    5 OpConstant 3             ; 3
+0003    | OpReturnValue
+`
+	if out.String() != expected {
+		t.Errorf("wrong disassembly.\nwant=\n%s\ngot=\n%s", expected, out.String())
+	}
+}
@@ -0,0 +1,171 @@
+package compiler
+
+type SymbolScope string
+
+const (
+	GlobalScope   SymbolScope = "GLOBAL"
+	LocalScope    SymbolScope = "LOCAL"
+	FreeScope     SymbolScope = "FREE"
+	FunctionScope SymbolScope = "FUNCTION"
+)
+
+type Symbol struct {
+	Name  string
+	Scope SymbolScope
+	Index int
+	Const bool // declared with const
+}
+
+// SymbolTable keeps track of the names defined in one function, or in the
+// whole program for the outermost table
+type SymbolTable struct {
+	Outer *SymbolTable
+
+	store          map[string]Symbol
+	numDefinitions int
+
+	// the names defined in the blocks being compiled, innermost last. they
+	// hide the names of the function until the block ends
+	blocks []map[string]Symbol
+	// the name of every slot, by index. names in blocks that have ended
+	// are only here
+	slots []string
+
+	// the variables of outer functions this function uses, in the order
+	// they are captured when the closure is made
+	FreeSymbols []Symbol
+}
+
+func NewSymbolTable() *SymbolTable {
+	s := make(map[string]Symbol)
+	free := []Symbol{}
+	return &SymbolTable{store: s, FreeSymbols: free}
+}
+
+func NewEnclosedSymbolTable(outer *SymbolTable) *SymbolTable {
+	s := NewSymbolTable()
+	s.Outer = outer
+	return s
+}
+
+// Define gives name a slot in this table, in the innermost block if one is
+// being compiled. defining a name twice in the same block or function
+// reuses its slot just like let overwrites the binding in the evaluator,
+// in a block it gets a new one that hides the one outside
+func (s *SymbolTable) Define(name string) Symbol {
+	return s.defineIn(s.current(), name, false)
+}
+
+// DefineConst is Define for a const
+func (s *SymbolTable) DefineConst(name string) Symbol {
+	return s.defineIn(s.current(), name, true)
+}
+
+// IsConst reports whether name is a constant of the innermost block being
+// compiled, or of the function outside of blocks. like in the evaluator a
+// constant can't be declared again there, only hidden in a block inside
+func (s *SymbolTable) IsConst(name string) bool {
+	symbol, ok := s.current()[name]
+	return ok && symbol.Const
+}
+
+// current returns the names Define adds to
+func (s *SymbolTable) current() map[string]Symbol {
+	if len(s.blocks) > 0 {
+		return s.blocks[len(s.blocks)-1]
+	}
+	return s.store
+}
+
+// defineLater gives name a slot outside of any block, for globals that
+// are used before they are defined
+func (s *SymbolTable) defineLater(name string) Symbol {
+	return s.defineIn(s.store, name, false)
+}
+
+func (s *SymbolTable) defineIn(store map[string]Symbol, name string, isConst bool) Symbol {
+	scope := GlobalScope
+	if s.Outer != nil {
+		scope = LocalScope
+	}
+	if symbol, ok := store[name]; ok && symbol.Scope == scope {
+		symbol.Const = isConst
+		store[name] = symbol
+		return symbol
+	}
+
+	symbol := Symbol{Name: name, Index: s.numDefinitions, Scope: scope, Const: isConst}
+	store[name] = symbol
+	s.slots = append(s.slots, name)
+	s.numDefinitions++
+	return symbol
+}
+
+// EnterBlock starts a block scope, the names defined until LeaveBlock
+// belong to it
+func (s *SymbolTable) EnterBlock() {
+	s.blocks = append(s.blocks, make(map[string]Symbol))
+}
+
+// LeaveBlock ends the innermost block scope. its slots stay taken
+func (s *SymbolTable) LeaveBlock() {
+	s.blocks = s.blocks[:len(s.blocks)-1]
+}
+
+// DefineFunctionName lets a function refer to itself by the name it is
+// bound to, without capturing itself as a free variable
+func (s *SymbolTable) DefineFunctionName(name string) Symbol {
+	symbol := Symbol{Name: name, Index: 0, Scope: FunctionScope}
+	s.store[name] = symbol
+	return symbol
+}
+
+func (s *SymbolTable) defineFree(original Symbol) Symbol {
+	s.FreeSymbols = append(s.FreeSymbols, original)
+
+	symbol := Symbol{Name: original.Name, Index: len(s.FreeSymbols) - 1}
+	symbol.Scope = FreeScope
+
+	s.store[original.Name] = symbol
+	return symbol
+}
+
+// Resolve looks name up in this table and then the outer ones. Locals of
+// outer functions become free variables of this one
+func (s *SymbolTable) Resolve(name string) (Symbol, bool) {
+	for i := len(s.blocks) - 1; i >= 0; i-- {
+		if symbol, ok := s.blocks[i][name]; ok {
+			return symbol, true
+		}
+	}
+	obj, ok := s.store[name]
+	if !ok && s.Outer != nil {
+		obj, ok = s.Outer.Resolve(name)
+		if !ok {
+			return obj, ok
+		}
+
+		if obj.Scope == GlobalScope {
+			return obj, ok
+		}
+
+		free := s.defineFree(obj)
+		return free, true
+	}
+	return obj, ok
+}
+
+// global returns the outermost table
+func (s *SymbolTable) global() *SymbolTable {
+	for s.Outer != nil {
+		s = s.Outer
+	}
+	return s
+}
+
+// names returns the name of every slot defined in this table, by index
+func (s *SymbolTable) names() []string {
+	names := make([]string, len(s.slots))
+	copy(names, s.slots)
+	return names
+}
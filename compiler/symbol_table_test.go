@@ -0,0 +1,127 @@
+package compiler
+
+import "testing"
+
+func TestDefineAndResolve(t *testing.T) {
+	global := NewSymbolTable()
+	global.Define("a")
+
+	first := NewEnclosedSymbolTable(global)
+	first.Define("b")
+
+	second := NewEnclosedSymbolTable(first)
+	second.Define("c")
+
+	tests := []struct {
+		table    *SymbolTable
+		name     string
+		expected Symbol
+	}{
+		{global, "a", Symbol{Name: "a", Scope: GlobalScope, Index: 0}},
+		{first, "a", Symbol{Name: "a", Scope: GlobalScope, Index: 0}},
+		{first, "b", Symbol{Name: "b", Scope: LocalScope, Index: 0}},
+		{second, "a", Symbol{Name: "a", Scope: GlobalScope, Index: 0}},
+		{second, "b", Symbol{Name: "b", Scope: FreeScope, Index: 0}},
+		{second, "c", Symbol{Name: "c", Scope: LocalScope, Index: 0}},
+	}
+
+	for _, tt := range tests {
+		result, ok := tt.table.Resolve(tt.name)
+		if !ok {
+			t.Errorf("name %s not resolvable", tt.name)
+			continue
+		}
+		if result != tt.expected {
+			t.Errorf("expected %s to resolve to %+v, got=%+v", tt.name, tt.expected, result)
+		}
+	}
+
+	if len(second.FreeSymbols) != 1 || second.FreeSymbols[0].Name != "b" {
+		t.Errorf("wrong free symbols. got=%+v", second.FreeSymbols)
+	}
+	if _, ok := second.Resolve("d"); ok {
+		t.Errorf("name d resolved but was never defined")
+	}
+}
+
+func TestDefineTwiceReusesSlot(t *testing.T) {
+	local := NewEnclosedSymbolTable(NewSymbolTable())
+	first := local.Define("a")
+	local.Define("b")
+	again := local.Define("a")
+
+	if first != again {
+		t.Errorf("redefining a gave a new slot. first=%+v, again=%+v", first, again)
+	}
+	if local.numDefinitions != 2 {
+		t.Errorf("wrong number of definitions. want=2, got=%d", local.numDefinitions)
+	}
+}
+
+func TestDefineFunctionName(t *testing.T) {
+	local := NewEnclosedSymbolTable(NewSymbolTable())
+	local.DefineFunctionName("f")
+
+	expected := Symbol{Name: "f", Scope: FunctionScope, Index: 0}
+	result, ok := local.Resolve("f")
+	if !ok || result != expected {
+		t.Errorf("expected f to resolve to %+v, got=%+v", expected, result)
+	}
+	if len(local.names()) != 0 {
+		t.Errorf("the function name took a local slot: %v", local.names())
+	}
+}
+
+func TestBlockScopes(t *testing.T) {
+	local := NewEnclosedSymbolTable(NewSymbolTable())
+	outer := local.Define("a")
+
+	local.EnterBlock()
+	inner := local.Define("a")
+	if inner == outer {
+		t.Fatalf("a in the block reused the slot outside it: %+v", inner)
+	}
+	if again := local.Define("a"); again != inner {
+		t.Errorf("redefining a in the block gave a new slot. first=%+v, again=%+v", inner, again)
+	}
+	local.Define("b")
+	if result, _ := local.Resolve("a"); result != inner {
+		t.Errorf("a in the block resolved to %+v, want=%+v", result, inner)
+	}
+	local.LeaveBlock()
+
+	if result, _ := local.Resolve("a"); result != outer {
+		t.Errorf("a after the block resolved to %+v, want=%+v", result, outer)
+	}
+	if _, ok := local.Resolve("b"); ok {
+		t.Errorf("b resolved after its block ended")
+	}
+	expected := []string{"a", "a", "b"}
+	if names := local.names(); len(names) != 3 || names[0] != expected[0] || names[1] != expected[1] || names[2] != expected[2] {
+		t.Errorf("wrong slot names. want=%v, got=%v", expected, names)
+	}
+}
+
+func TestConstSymbols(t *testing.T) {
+	global := NewSymbolTable()
+	global.DefineConst("a")
+	global.Define("b")
+
+	if !global.IsConst("a") || global.IsConst("b") {
+		t.Errorf("wrong constness. a=%t, b=%t", global.IsConst("a"), global.IsConst("b"))
+	}
+
+	// a block can hide the constant with a binding of its own
+	global.EnterBlock()
+	if global.IsConst("a") {
+		t.Errorf("a is a constant of the block it isn't declared in")
+	}
+	global.DefineConst("b")
+	if !global.IsConst("b") {
+		t.Errorf("b is not a constant of the block")
+	}
+	global.LeaveBlock()
+	if global.IsConst("b") {
+		t.Errorf("b is still a constant after the block")
+	}
+}
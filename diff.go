@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// lines of unchanged context printed around each change
+const diffContext = 3
+
+// an edit is one line of the diff: ' ' for unchanged, '-' or '+'
+type edit struct {
+	op   byte
+	text string
+}
+
+// unifiedDiff returns the changes from a to b in unified diff format, or
+// "" if they are the same
+func unifiedDiff(name string, a string, b string) string {
+	if a == b {
+		return ""
+	}
+	edits := diffLines(splitLines(a), splitLines(b))
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s.orig\n+++ %s\n", name, name)
+	// line numbers in a and b at the start of edits[i]
+	lineA, lineB := 1, 1
+	for i := 0; i < len(edits); {
+		if edits[i].op == ' ' {
+			lineA++
+			lineB++
+			i++
+			continue
+		}
+		// a hunk starts with the context before the change and runs until
+		// there are more than two contexts worth of unchanged lines
+		start := max(i-diffContext, 0)
+		startA, startB := lineA-(i-start), lineB-(i-start)
+		end := i
+		for unchanged := 0; end < len(edits) && unchanged <= 2*diffContext; end++ {
+			if edits[end].op == ' ' {
+				unchanged++
+			} else {
+				unchanged = 0
+			}
+		}
+		// drop the trailing context we don't need
+		for end > i && edits[end-1].op == ' ' {
+			end--
+		}
+		end = min(end+diffContext, len(edits))
+
+		countA, countB := 0, 0
+		var hunk strings.Builder
+		for _, e := range edits[start:end] {
+			if e.op != '+' {
+				countA++
+			}
+			if e.op != '-' {
+				countB++
+			}
+			hunk.WriteByte(e.op)
+			hunk.WriteString(e.text)
+			hunk.WriteByte('\n')
+		}
+		fmt.Fprintf(&out, "@@ -%d,%d +%d,%d @@\n", startA, countA, startB, countB)
+		out.WriteString(hunk.String())
+
+		for _, e := range edits[i:end] {
+			if e.op != '+' {
+				lineA++
+			}
+			if e.op != '-' {
+				lineB++
+			}
+		}
+		i = end
+	}
+	return out.String()
+}
+
+func splitLines(s string) []string {
+	s = strings.TrimSuffix(s, "\n")
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
+
+// diffLines finds the longest common subsequence of a and b and returns the
+// edits that turn a into b
+func diffLines(a []string, b []string) []edit {
+	// lcs[i][j] is the length of the common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	edits := []edit{}
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			edits = append(edits, edit{' ', a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			edits = append(edits, edit{'-', a[i]})
+			i++
+		default:
+			edits = append(edits, edit{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		edits = append(edits, edit{'-', a[i]})
+	}
+	for ; j < len(b); j++ {
+		edits = append(edits, edit{'+', b[j]})
+	}
+	return edits
+}
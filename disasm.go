@@ -0,0 +1,39 @@
+package main
+
+import (
+	"Go-interpreter/compiler"
+	"flag"
+	"fmt"
+	"os"
+)
+
+// runDisasm prints the bytecode of a file, compiling it first unless it
+// was made by monkey build
+func runDisasm(args []string) int {
+	flags := flag.NewFlagSet("disasm", flag.ContinueOnError)
+	optimize := flags.Bool("optimize", false, "simplify the program before compiling it")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: monkey disasm [--optimize] file")
+		return 2
+	}
+	path := flags.Arg(0)
+
+	src, err := readSource(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "disasm: %s\n", err)
+		return 1
+	}
+	bytecode, err := loadBytecode(path, src, *optimize)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "disasm: %s\n", err)
+		return 1
+	}
+	if err := compiler.Disassemble(os.Stdout, bytecode); err != nil {
+		fmt.Fprintf(os.Stderr, "disasm: %s\n", err)
+		return 1
+	}
+	return 0
+}
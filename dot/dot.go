@@ -0,0 +1,260 @@
+// Package dot exports an AST as a Graphviz DOT graph, which makes it much
+// easier to see how an expression was grouped than reading String().
+//
+//	monkey dot file.mk | dot -Tsvg > ast.svg
+package dot
+
+import (
+	"Go-interpreter/ast"
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// an edge from a node to one of its children, labelled with the field the
+// child is stored in
+type edge struct {
+	label string
+	node  ast.Node
+}
+
+type exporter struct {
+	out    bytes.Buffer
+	nextID int
+}
+
+// Write writes the DOT graph for node and everything below it to w
+func Write(w io.Writer, node ast.Node) error {
+	_, err := io.WriteString(w, String(node))
+	return err
+}
+
+// String returns the DOT graph for node and everything below it
+func String(node ast.Node) string {
+	e := &exporter{}
+	e.out.WriteString("digraph ast {\n")
+	e.out.WriteString("\tnode [shape=box, fontname=\"monospace\"];\n")
+	if node != nil {
+		e.node(node)
+	}
+	e.out.WriteString("}\n")
+	return e.out.String()
+}
+
+// node writes node and its children and returns the id of node
+func (e *exporter) node(node ast.Node) string {
+	id := "n" + strconv.Itoa(e.nextID)
+	e.nextID++
+	fmt.Fprintf(&e.out, "\t%s [label=%s];\n", id, quote(label(node)))
+	for _, child := range children(node) {
+		childID := e.node(child.node)
+		fmt.Fprintf(&e.out, "\t%s -> %s [label=%s];\n", id, childID, quote(child.label))
+	}
+	return id
+}
+
+// label is the node type followed by the operator or literal, if it has one
+func label(node ast.Node) string {
+	name := strings.TrimPrefix(fmt.Sprintf("%T", node), "*ast.")
+	switch n := node.(type) {
+	case *ast.Identifier:
+		return name + "\n" + n.Value
+	case *ast.IntegerLiteral:
+		return name + "\n" + strconv.FormatInt(n.Value, 10)
+	case *ast.Boolean:
+		return name + "\n" + strconv.FormatBool(n.Value)
+	case *ast.StringLiteral:
+		return name + "\n" + strconv.Quote(n.Value)
+	case *ast.PrefixExpression:
+		return name + "\n" + n.Operator
+	case *ast.InfixExpression:
+		return name + "\n" + n.Operator
+	case *ast.AssignExpression:
+		return name + "\n" + n.Operator
+	case *ast.Comment:
+		return name + "\n" + n.Token.Literal
+	}
+	return name
+}
+
+func children(node ast.Node) []edge {
+	edges := []edge{}
+	add := func(label string, child ast.Node) {
+		edges = append(edges, edge{label, child})
+	}
+
+	switch n := node.(type) {
+	case *ast.Program:
+		for i, stmt := range n.Statements {
+			add(fmt.Sprintf("Statements[%d]", i), stmt)
+		}
+	case *ast.ExpressionStatement:
+		if n.Expression != nil {
+			add("Expression", n.Expression)
+		}
+	case *ast.LetStatement:
+		if n.Name != nil {
+			add("Name", n.Name)
+		}
+		if n.Value != nil {
+			add("Value", n.Value)
+		}
+	case *ast.ReturnStatement:
+		if n.ReturnValue != nil {
+			add("ReturnValue", n.ReturnValue)
+		}
+	case *ast.BlockStatement:
+		for i, stmt := range n.Statements {
+			add(fmt.Sprintf("Statements[%d]", i), stmt)
+		}
+	case *ast.PrefixExpression:
+		if n.Right != nil {
+			add("Right", n.Right)
+		}
+	case *ast.InfixExpression:
+		if n.Left != nil {
+			add("Left", n.Left)
+		}
+		if n.Right != nil {
+			add("Right", n.Right)
+		}
+	case *ast.IfExpression:
+		if n.Condition != nil {
+			add("Condition", n.Condition)
+		}
+		if n.Then != nil {
+			add("Then", n.Then)
+		}
+		if n.Else != nil {
+			add("Else", n.Else)
+		}
+	case *ast.FunctionLiteral:
+		for i, param := range n.Parameters {
+			add(fmt.Sprintf("Params[%d]", i), param)
+			if def := n.Default(i); def != nil {
+				add(fmt.Sprintf("Defaults[%d]", i), def)
+			}
+		}
+		if n.Rest != nil {
+			add("Rest", n.Rest)
+		}
+		if n.Body != nil {
+			add("Body", n.Body)
+		}
+	case *ast.CallExpression:
+		if n.Function != nil {
+			add("Function", n.Function)
+		}
+		for i, arg := range n.Arguments {
+			add(fmt.Sprintf("Args[%d]", i), arg)
+		}
+	case *ast.ArrayLiteral:
+		for i, el := range n.Elements {
+			add(fmt.Sprintf("Elements[%d]", i), el)
+		}
+	case *ast.IndexExpression:
+		if n.Left != nil {
+			add("Left", n.Left)
+		}
+		if n.Index != nil {
+			add("Index", n.Index)
+		}
+	case *ast.MemberExpression:
+		if n.Object != nil {
+			add("Object", n.Object)
+		}
+		add("Property", n.Property)
+	case *ast.ConditionalExpression:
+		if n.Condition != nil {
+			add("Condition", n.Condition)
+		}
+		if n.Consequence != nil {
+			add("Consequence", n.Consequence)
+		}
+		if n.Alternative != nil {
+			add("Alternative", n.Alternative)
+		}
+	case *ast.SpreadExpression:
+		if n.Value != nil {
+			add("Value", n.Value)
+		}
+	case *ast.NamedArgument:
+		add("Name", n.Name)
+		if n.Value != nil {
+			add("Value", n.Value)
+		}
+	case *ast.MatchExpression:
+		if n.Value != nil {
+			add("Value", n.Value)
+		}
+		for i, arm := range n.Arms {
+			add(fmt.Sprintf("Patterns[%d]", i), arm.Pattern)
+			if arm.Guard != nil {
+				add(fmt.Sprintf("Guards[%d]", i), arm.Guard)
+			}
+			add(fmt.Sprintf("Bodies[%d]", i), arm.Body)
+		}
+	case *ast.AssignExpression:
+		if n.Target != nil {
+			add("Target", n.Target)
+		}
+		if n.Value != nil {
+			add("Value", n.Value)
+		}
+	case *ast.WhileStatement:
+		if n.Condition != nil {
+			add("Condition", n.Condition)
+		}
+		if n.Body != nil {
+			add("Body", n.Body)
+		}
+	case *ast.ForStatement:
+		if n.Variable != nil {
+			add("Variable", n.Variable)
+		}
+		if n.Iterable != nil {
+			add("Iterable", n.Iterable)
+		}
+		if n.Body != nil {
+			add("Body", n.Body)
+		}
+	case *ast.PropagateExpression:
+		if n.Left != nil {
+			add("Left", n.Left)
+		}
+	case *ast.ThrowStatement:
+		if n.Value != nil {
+			add("Value", n.Value)
+		}
+	case *ast.TryExpression:
+		if n.Block != nil {
+			add("Block", n.Block)
+		}
+		if n.Parameter != nil {
+			add("Parameter", n.Parameter)
+		}
+		if n.Catch != nil {
+			add("Catch", n.Catch)
+		}
+		if n.Finally != nil {
+			add("Finally", n.Finally)
+		}
+	case *ast.HashLiteral:
+		for i, pair := range n.Pairs {
+			add(fmt.Sprintf("Keys[%d]", i), pair.Key)
+			add(fmt.Sprintf("Values[%d]", i), pair.Value)
+		}
+	}
+	return edges
+}
+
+// quote returns s as a DOT string, newlines become \n so labels can have
+// more than one line
+func quote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	return `"` + s + `"`
+}
@@ -0,0 +1,78 @@
+package dot
+
+import (
+	"Go-interpreter/ast"
+	"Go-interpreter/lexer"
+	"Go-interpreter/parser"
+	"strings"
+	"testing"
+)
+
+func parse(t *testing.T, input string) *ast.Program {
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+	return program
+}
+
+func TestString(t *testing.T) {
+	program := parse(t, "-a * b")
+	expected := `digraph ast {
+	node [shape=box, fontname="monospace"];
+	n0 [label="Program"];
+	n1 [label="ExpressionStatement"];
+	n2 [label="InfixExpression\n*"];
+	n3 [label="PrefixExpression\n-"];
+	n4 [label="Identifier\na"];
+	n3 -> n4 [label="Right"];
+	n2 -> n3 [label="Left"];
+	n5 [label="Identifier\nb"];
+	n2 -> n5 [label="Right"];
+	n1 -> n2 [label="Expression"];
+	n0 -> n1 [label="Statements[0]"];
+}
+`
+	actual := String(program)
+	if actual != expected {
+		t.Errorf("wrong graph.\nexpected=%q\ngot=     %q", expected, actual)
+	}
+}
+
+func TestEdgeLabels(t *testing.T) {
+	program := parse(t, `
+let f = fn(x, y) { return x; };
+if (f(1, true)) { 1 } else { 2 }
+`)
+	graph := String(program)
+	labels := []string{
+		`[label="Name"]`, `[label="Value"]`, `[label="Params[0]"]`,
+		`[label="Params[1]"]`, `[label="Body"]`, `[label="ReturnValue"]`,
+		`[label="Condition"]`, `[label="Then"]`, `[label="Else"]`,
+		`[label="Function"]`, `[label="Args[0]"]`, `[label="Args[1]"]`,
+		`[label="Boolean\ntrue"]`, `[label="IntegerLiteral\n2"]`,
+	}
+	for _, label := range labels {
+		if !strings.Contains(graph, label) {
+			t.Errorf("graph has no %s", label)
+		}
+	}
+}
+
+func TestQuote(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"a", `"a"`},
+		{`say "hi"`, `"say \"hi\""`},
+		{"a\nb", `"a\nb"`},
+		{`a\b`, `"a\\b"`},
+	}
+	for _, tt := range tests {
+		if actual := quote(tt.input); actual != tt.expected {
+			t.Errorf("quote(%q) wrong. expected=%s, got=%s", tt.input, tt.expected, actual)
+		}
+	}
+}
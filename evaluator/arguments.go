@@ -0,0 +1,175 @@
+package evaluator
+
+import (
+	"Go-interpreter/ast"
+	"Go-interpreter/object"
+	"fmt"
+)
+
+// Arguments are passed by position, spread out of an array with ...array,
+// or by the name of a parameter with name: value. A function binds them by
+// position first, then by name. Parameters that are still missing get
+// their default, evaluated when the function is called in its own
+// environment, so a default can use the parameters before it. Arguments
+// past the last parameter go into an array for the ...rest parameter,
+// without one they are an error.
+
+// namedArgument carries a name: value argument from the call to
+// applyFunction, programs can't see it
+type namedArgument struct {
+	name  string
+	value object.Object
+}
+
+func (na *namedArgument) Type() object.ObjectType { return "NAMED_ARGUMENT" }
+func (na *namedArgument) Inspect() string         { return na.name + ": " + na.value.Inspect() }
+
+// evalArguments evaluates the arguments of a call from left to right. like
+// evalExpressions, if one of them fails the result is just that error
+func (e *evaluator) evalArguments(exps []ast.Expression, env *object.Environment) []object.Object {
+	var args []object.Object
+
+	for _, exp := range exps {
+		switch exp := exp.(type) {
+		case *ast.SpreadExpression:
+			value := e.eval(exp.Value, env)
+			if isAbrupt(value) {
+				return []object.Object{value}
+			}
+			arr, ok := value.(*object.Array)
+			if !ok {
+				return []object.Object{newError("argument to ... must be ARRAY, got %s", value.Type())}
+			}
+			args = append(args, arr.Elements...)
+		case *ast.NamedArgument:
+			value := e.eval(exp.Value, env)
+			if isAbrupt(value) {
+				return []object.Object{value}
+			}
+			args = append(args, &namedArgument{name: exp.Name.Value, value: value})
+		default:
+			value := e.eval(exp, env)
+			if isAbrupt(value) {
+				return []object.Object{value}
+			}
+			args = append(args, value)
+		}
+	}
+
+	return args
+}
+
+// matchArguments works out which argument each parameter of function
+// gets. parameters left to their default are nil, extra are the arguments
+// for the ...rest parameter
+func matchArguments(function *object.Function, args []object.Object) (values, extra []object.Object, err *object.Error) {
+	params := function.Parameters
+	values = make([]object.Object, len(params))
+	positional := 0
+	for _, arg := range args {
+		if _, ok := arg.(*namedArgument); ok {
+			continue
+		}
+		if positional < len(params) {
+			values[positional] = arg
+		} else {
+			extra = append(extra, arg)
+		}
+		positional++
+	}
+
+	required := 0
+	for i := range params {
+		if defaultOf(function, i) == nil {
+			required++
+		}
+	}
+	if len(args) < required || function.Rest == nil && len(args) > len(params) {
+		return nil, nil, newError("wrong number of arguments to %s: want=%s, got=%d",
+			function.Signature(), arity(required, len(params), function.Rest != nil), len(args))
+	}
+
+	for _, arg := range args {
+		named, ok := arg.(*namedArgument)
+		if !ok {
+			continue
+		}
+		i := parameterIndex(params, named.name)
+		if i < 0 {
+			return nil, nil, newError("%s has no parameter %s", function.Signature(), named.name)
+		}
+		if values[i] != nil {
+			return nil, nil, newError("argument %s given twice to %s", named.name, function.Signature())
+		}
+		values[i] = named.value
+	}
+	for i, param := range params {
+		if values[i] == nil && defaultOf(function, i) == nil {
+			return nil, nil, newError("missing argument %s to %s", param.Value, function.Signature())
+		}
+	}
+	return values, extra, nil
+}
+
+// bindArguments binds the values matchArguments found to the parameters of
+// function in env, evaluating the defaults of the ones that have none
+func (e *evaluator) bindArguments(function *object.Function, values, extra []object.Object, env *object.Environment) object.Object {
+	for i, param := range function.Parameters {
+		value := values[i]
+		if value == nil {
+			value = e.eval(defaultOf(function, i), env)
+			if isAbrupt(value) {
+				return value
+			}
+		}
+		env.Set(param.Value, value)
+	}
+	if function.Rest != nil {
+		rest := e.track(&object.Array{Elements: append([]object.Object{}, extra...)})
+		if isError(rest) {
+			return rest
+		}
+		env.Set(function.Rest.Value, rest)
+	}
+	return nil
+}
+
+func defaultOf(function *object.Function, i int) ast.Expression {
+	if i < len(function.Defaults) {
+		return function.Defaults[i]
+	}
+	return nil
+}
+
+func parameterIndex(params []*ast.Identifier, name string) int {
+	for i, param := range params {
+		if param.Value == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// arity describes how many arguments a function takes, for errors
+func arity(required, params int, rest bool) string {
+	switch {
+	case rest:
+		return fmt.Sprintf("at least %d", required)
+	case required == params:
+		return fmt.Sprint(required)
+	case required+1 == params:
+		return fmt.Sprintf("%d or %d", required, params)
+	}
+	return fmt.Sprintf("%d to %d", required, params)
+}
+
+// namedArgumentError is what a builtin or method called with a named
+// argument gets
+func namedArgumentError(name string, args []object.Object) *object.Error {
+	for _, arg := range args {
+		if named, ok := arg.(*namedArgument); ok {
+			return newError("`%s` takes no named arguments, got %s", name, named.name)
+		}
+	}
+	return nil
+}
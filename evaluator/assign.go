@@ -0,0 +1,128 @@
+package evaluator
+
+import (
+	"Go-interpreter/ast"
+	"Go-interpreter/object"
+	"strings"
+)
+
+// an assignment changes a binding made with let, an element of an array
+// or hash, or a field of a hash, and gives the new value. a compound
+// assignment like x += 1 reads the old value first, then evaluates the
+// right hand side
+func (e *evaluator) evalAssignExpression(ae *ast.AssignExpression, env *object.Environment) object.Object {
+	switch target := ae.Target.(type) {
+	case *ast.Identifier:
+		var current object.Object
+		if ae.Operator != "=" {
+			current = evalIdentifier(target, env)
+			if isAbrupt(current) {
+				return current
+			}
+		}
+		value := e.assignedValue(ae, current, env)
+		if isAbrupt(value) {
+			return value
+		}
+		if fn, ok := value.(*object.Function); ok && fn.Name == "" {
+			fn.Name = target.Value
+		}
+		if !env.Assign(target.Value, value) {
+			if _, ok := env.Get(target.Value); ok {
+				return newError("cannot assign to constant %s", target.Value)
+			}
+			return newError("assignment to undeclared name: %s", target.Value)
+		}
+		return value
+
+	case *ast.IndexExpression:
+		left := e.eval(target.Left, env)
+		if isAbrupt(left) {
+			return left
+		}
+		index := e.eval(target.Index, env)
+		if isAbrupt(index) {
+			return index
+		}
+		var current object.Object
+		if ae.Operator != "=" {
+			current = evalIndexExpression(left, index)
+			if isAbrupt(current) {
+				return current
+			}
+		}
+		value := e.assignedValue(ae, current, env)
+		if isAbrupt(value) {
+			return value
+		}
+		if err := e.setIndex(left, index, value); err != nil {
+			return err
+		}
+		return value
+
+	case *ast.MemberExpression:
+		left := e.eval(target.Object, env)
+		if isAbrupt(left) {
+			return left
+		}
+		if left.Type() != object.HASH_OBJ {
+			return newError("field assignment not supported: %s.%s", left.Type(), target.Property.Value)
+		}
+		var current object.Object
+		if ae.Operator != "=" {
+			current = member(left, target.Property.Value)
+			if isAbrupt(current) {
+				return current
+			}
+		}
+		value := e.assignedValue(ae, current, env)
+		if isAbrupt(value) {
+			return value
+		}
+		if err := e.setIndex(left, &object.String{Value: target.Property.Value}, value); err != nil {
+			return err
+		}
+		return value
+	}
+	return newError("cannot assign to %s", ae.Target)
+}
+
+// assignedValue evaluates the right hand side and, for a compound
+// assignment, applies its operator to the current value and it
+func (e *evaluator) assignedValue(ae *ast.AssignExpression, current object.Object, env *object.Environment) object.Object {
+	value := e.eval(ae.Value, env)
+	if isAbrupt(value) || current == nil {
+		return value
+	}
+	return e.track(evalInfixExpression(strings.TrimSuffix(ae.Operator, "="), current, value))
+}
+
+// setIndex changes an element of an array, which has to exist already, or
+// sets a key of a hash
+func (e *evaluator) setIndex(left, index, value object.Object) *object.Error {
+	switch left := left.(type) {
+	case *object.Array:
+		i, ok := index.(*object.Integer)
+		if !ok {
+			break
+		}
+		if i.Value < 0 || i.Value >= int64(len(left.Elements)) {
+			return newError("index out of range: %d with length %d", i.Value, len(left.Elements))
+		}
+		left.Elements[i.Value] = value
+		return nil
+	case *object.Hash:
+		key, ok := index.(object.Hashable)
+		if !ok {
+			return newError("unusable as hash key: %s", index.Type())
+		}
+		if _, ok := left.Pairs[key.HashKey()]; !ok {
+			if err := e.allocate(hashPairSize); err != nil {
+				return err
+			}
+		}
+		left.Set(key, value)
+		return nil
+	}
+	return newError("index assignment not supported: %s[%s]", left.Type(), index.Type())
+}
@@ -0,0 +1,170 @@
+package evaluator
+
+import (
+	"Go-interpreter/object"
+	"fmt"
+)
+
+// the functions every program can use without defining them. a binding
+// with the same name hides them
+var builtins = map[string]*object.Builtin{
+	"len": {Name: "len", Fn: func(args ...object.Object) object.Object {
+		if len(args) != 1 {
+			return newError("wrong number of arguments: want=1, got=%d", len(args))
+		}
+		switch arg := args[0].(type) {
+		case *object.String:
+			return &object.Integer{Value: int64(len(arg.Value))}
+		case *object.Array:
+			return &object.Integer{Value: int64(len(arg.Elements))}
+		case *object.Hash:
+			return &object.Integer{Value: int64(len(arg.Keys))}
+		case *object.Range:
+			return &object.Integer{Value: arg.Len()}
+		default:
+			return newError("argument to `len` not supported, got %s", args[0].Type())
+		}
+	}},
+	"first": {Name: "first", Fn: func(args ...object.Object) object.Object {
+		arr, err := arrayArgument("first", args)
+		if err != nil {
+			return err
+		}
+		if len(arr.Elements) > 0 {
+			return arr.Elements[0]
+		}
+		return NULL
+	}},
+	"last": {Name: "last", Fn: func(args ...object.Object) object.Object {
+		arr, err := arrayArgument("last", args)
+		if err != nil {
+			return err
+		}
+		if length := len(arr.Elements); length > 0 {
+			return arr.Elements[length-1]
+		}
+		return NULL
+	}},
+	// rest and push make new arrays, the one they are given doesn't change
+	"rest": {Name: "rest", Fn: func(args ...object.Object) object.Object {
+		arr, err := arrayArgument("rest", args)
+		if err != nil {
+			return err
+		}
+		length := len(arr.Elements)
+		if length == 0 {
+			return NULL
+		}
+		elements := make([]object.Object, length-1)
+		copy(elements, arr.Elements[1:])
+		return &object.Array{Elements: elements}
+	}},
+	"push": {Name: "push", Fn: func(args ...object.Object) object.Object {
+		if len(args) != 2 {
+			return newError("wrong number of arguments: want=2, got=%d", len(args))
+		}
+		arr, ok := args[0].(*object.Array)
+		if !ok {
+			return newError("argument to `push` must be ARRAY, got %s", args[0].Type())
+		}
+		length := len(arr.Elements)
+		elements := make([]object.Object, length+1)
+		copy(elements, arr.Elements)
+		elements[length] = args[1]
+		return &object.Array{Elements: elements}
+	}},
+	"ok": {Name: "ok", Fn: func(args ...object.Object) object.Object {
+		if len(args) != 1 {
+			return newError("wrong number of arguments: want=1, got=%d", len(args))
+		}
+		return &object.Result{Value: args[0]}
+	}},
+	"err": {Name: "err", Fn: func(args ...object.Object) object.Object {
+		if len(args) != 1 {
+			return newError("wrong number of arguments: want=1, got=%d", len(args))
+		}
+		return &object.Result{Value: args[0], IsErr: true}
+	}},
+	"is_err": {Name: "is_err", Fn: func(args ...object.Object) object.Object {
+		result, err := resultArgument("is_err", args)
+		if err != nil {
+			return err
+		}
+		return nativeBoolToBoolObject(result.IsErr)
+	}},
+	// unwrap fails for an Err, ? returns it instead
+	"unwrap": {Name: "unwrap", Fn: func(args ...object.Object) object.Object {
+		result, err := resultArgument("unwrap", args)
+		if err != nil {
+			return err
+		}
+		if result.IsErr {
+			return newError("unwrap of %s", result.Inspect())
+		}
+		return result.Value
+	}},
+	// range(end) or range(start, end), the integers from start, or 0, up
+	// to end
+	"range": {Name: "range", Fn: func(args ...object.Object) object.Object {
+		if len(args) != 1 && len(args) != 2 {
+			return newError("wrong number of arguments: want=1 or 2, got=%d", len(args))
+		}
+		bounds := []int64{}
+		for _, arg := range args {
+			integer, ok := arg.(*object.Integer)
+			if !ok {
+				return newError("argument to `range` must be INTEGER, got %s", arg.Type())
+			}
+			bounds = append(bounds, integer.Value)
+		}
+		if len(bounds) == 1 {
+			return &object.Range{Start: 0, End: bounds[0]}
+		}
+		return &object.Range{Start: bounds[0], End: bounds[1]}
+	}},
+	"puts": {Name: "puts", Fn: func(args ...object.Object) object.Object {
+		for _, arg := range args {
+			fmt.Println(arg.Inspect())
+		}
+		return NULL
+	}},
+}
+
+// BuiltinNames lists every builtin in a fixed order. compiled programs
+// refer to a builtin by its index in it
+var BuiltinNames = []string{"len", "first", "last", "rest", "push", "ok", "err", "is_err", "unwrap", "range", "puts"}
+
+// LookupBuiltin returns the builtin called name
+func LookupBuiltin(name string) (*object.Builtin, bool) {
+	builtin, ok := builtins[name]
+	return builtin, ok
+}
+
+// borrows lists the builtins and methods that give back a value they were
+// passed, or a part of one, instead of making a new one. that value was
+// counted as allocated when it was made
+var borrows = map[string]bool{"first": true, "last": true, "unwrap": true, "reduce": true}
+
+// resultArgument checks that a builtin was called with just a result
+func resultArgument(name string, args []object.Object) (*object.Result, *object.Error) {
+	if len(args) != 1 {
+		return nil, newError("wrong number of arguments: want=1, got=%d", len(args))
+	}
+	result, ok := args[0].(*object.Result)
+	if !ok {
+		return nil, newError("argument to `%s` must be RESULT, got %s", name, args[0].Type())
+	}
+	return result, nil
+}
+
+// arrayArgument checks that a builtin was called with just an array
+func arrayArgument(name string, args []object.Object) (*object.Array, *object.Error) {
+	if len(args) != 1 {
+		return nil, newError("wrong number of arguments: want=1, got=%d", len(args))
+	}
+	arr, ok := args[0].(*object.Array)
+	if !ok {
+		return nil, newError("argument to `%s` must be ARRAY, got %s", name, args[0].Type())
+	}
+	return arr, nil
+}
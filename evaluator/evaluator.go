@@ -3,46 +3,350 @@ package evaluator
 import (
 	"Go-interpreter/ast"
 	"Go-interpreter/object"
+	"Go-interpreter/token"
+	"context"
+	"fmt"
+	"time"
 )
 
 var (
 	TRUE  = &object.Boolean{Value: true}
 	FALSE = &object.Boolean{Value: false}
 	NULL  = &object.Null{}
+
+	BREAK    = &object.Break{}
+	CONTINUE = &object.Continue{}
 )
 
-func Eval(node ast.Node) object.Object {
+// DefaultMaxCallDepth is how deeply function calls can nest when Options
+// don't say otherwise
+const DefaultMaxCallDepth = 10000
+
+// errors only keep this many of the calls that were running
+const maxStackFrames = 20
+
+// Options limit what an evaluation may do. the zero value uses the defaults
+// and lets it run for as long as it takes.
+//
+// When the context is cancelled or a step, time or memory limit is
+// reached the evaluation stops with an error of kind object.CancelledError,
+// object.LimitError or object.MemoryError. Nothing in the program can
+// recover from those
+type Options struct {
+	// MaxCallDepth is how many function calls can be running at once
+	// before evaluation fails with "maximum call depth exceeded". tail
+	// calls replace the call they are in, so they don't count
+	MaxCallDepth int
+
+	// Context stops the evaluation when it is done, nil for none
+	Context context.Context
+
+	// MaxSteps is how many nodes may be evaluated, 0 for no limit
+	MaxSteps int64
+
+	// Deadline is when the evaluation has to stop, zero for never
+	Deadline time.Time
+
+	// MaxMemory is how many bytes the program may hold at once, 0 for no
+	// limit. the sizes are estimates, see memory.go
+	MaxMemory int64
+}
+
+// Result is what Run returns
+type Result struct {
+	Value object.Object // the value of the program, or the error it stopped with
+
+	Steps int64 // how many nodes were evaluated
+
+	// Allocated is how many bytes the objects the program created were
+	// counted as, everything that was allocated and not what was still in
+	// use at the end
+	Allocated int64
+
+	// Peak is the most memory the program was found to hold at once, in
+	// the same bytes as Allocated. see memory.go for when it is measured
+	Peak int64
+}
+
+// the context and the clock are only looked at every so many steps
+const checkInterval = 1024
+
+// evaluator holds the state of one evaluation
+type evaluator struct {
+	maxCallDepth int
+	stack        []object.StackFrame // the calls that are running, outermost first
+
+	ctx      context.Context
+	maxSteps int64
+	deadline time.Time
+	steps    int64
+	aborted  *object.Error // set once the evaluation was stopped
+
+	env     *object.Environment   // the environment being evaluated in
+	callers []*object.Environment // the ones of the calls that are running, outermost first
+
+	maxMemory   int64
+	allocated   int64 // bytes allocated so far
+	inUse       int64 // bytes in use at the last measurement plus the ones allocated since
+	nextMeasure int64 // inUse gets measured again when it gets past this
+	peak        int64 // the most a measurement found
+}
+
+func Eval(node ast.Node, env *object.Environment) object.Object {
+	return EvalWithOptions(node, env, Options{})
+}
+
+// EvalWithOptions is Eval with limits, see Options
+func EvalWithOptions(node ast.Node, env *object.Environment, opts Options) object.Object {
+	return Run(node, env, opts).Value
+}
+
+// Run evaluates node like EvalWithOptions and also reports how much work
+// that took
+func Run(node ast.Node, env *object.Environment, opts Options) Result {
+	e := &evaluator{
+		maxCallDepth: opts.MaxCallDepth,
+		ctx:          opts.Context,
+		maxSteps:     opts.MaxSteps,
+		deadline:     opts.Deadline,
+		maxMemory:    opts.MaxMemory,
+		env:          env,
+		nextMeasure:  minMeasure,
+	}
+	if e.maxMemory > 0 {
+		e.nextMeasure = min(e.nextMeasure, e.maxMemory)
+	}
+	if e.maxCallDepth <= 0 {
+		e.maxCallDepth = DefaultMaxCallDepth
+	}
+	value := e.eval(node, env)
+	e.env = env
+	peak := max(e.peak, e.measure(value))
+	return Result{Value: value, Steps: e.steps, Allocated: e.allocated, Peak: peak}
+}
+
+func (e *evaluator) eval(node ast.Node, env *object.Environment) object.Object {
+	if err := e.step(); err != nil {
+		return err
+	}
+	e.env = env
+
 	switch node := node.(type) {
 	case *ast.Program:
-		return evalStatements(node.Statements)
+		return e.evalProgram(node.Statements, env)
 	case *ast.ExpressionStatement:
-		return Eval(node.Expression)
+		return e.eval(node.Expression, env)
 	case *ast.IntegerLiteral:
-		return &object.Integer{Value: node.Value}
+		return e.track(&object.Integer{Value: node.Value})
+	case *ast.StringLiteral:
+		return e.track(&object.String{Value: node.Value})
 	case *ast.Boolean:
 		return nativeBoolToBoolObject(node.Value)
+	case *ast.NullLiteral:
+		return NULL
 	case *ast.PrefixExpression:
-		return evalPrefixExpression(node.Operator, Eval(node.Right))
+		right := e.eval(node.Right, env)
+		if isAbrupt(right) {
+			return right
+		}
+		return e.track(evalPrefixExpression(node.Operator, right))
 	case *ast.InfixExpression:
-		return evalInfixExpression(node.Operator, Eval(node.Left), Eval(node.Right))
+		left := e.eval(node.Left, env)
+		if isAbrupt(left) {
+			return left
+		}
+		// the right hand side of ?? is only evaluated when the left is null
+		if node.Operator == "??" {
+			if left != NULL {
+				return left
+			}
+			return e.eval(node.Right, env)
+		}
+		right := e.eval(node.Right, env)
+		if isAbrupt(right) {
+			return right
+		}
+		return e.track(evalInfixExpression(node.Operator, left, right))
 	case *ast.IfExpression:
-		return evalIfExpression(node)
+		return e.evalIfExpression(node, env)
+	case *ast.ConditionalExpression:
+		condition := e.eval(node.Condition, env)
+		if isAbrupt(condition) {
+			return condition
+		}
+		if isTruthy(condition) {
+			return e.eval(node.Consequence, env)
+		}
+		return e.eval(node.Alternative, env)
 	case *ast.BlockStatement:
-		return evalStatements(node.Statements)
+		return e.evalScopedBlock(node, env)
+	case *ast.ReturnStatement:
+		value := e.eval(node.ReturnValue, env)
+		if isAbrupt(value) {
+			return value
+		}
+		return &object.ReturnValue{Value: value}
+	case *ast.LetStatement:
+		return e.evalLetStatement(node, env)
+	case *ast.Identifier:
+		return evalIdentifier(node, env)
+	case *ast.FunctionLiteral:
+		return e.track(&object.Function{Parameters: node.Parameters, Defaults: node.Defaults, Rest: node.Rest, Body: node.Body, Env: env})
+	case *ast.CallExpression:
+		function := e.eval(node.Function, env)
+		if isAbrupt(function) {
+			return function
+		}
+		args := e.evalArguments(node.Arguments, env)
+		if len(args) == 1 && isAbrupt(args[0]) {
+			return args[0]
+		}
+		return e.applyFunction(function, args, callSite(node))
+	case *ast.ArrayLiteral:
+		elements := e.evalExpressions(node.Elements, env)
+		if len(elements) == 1 && isAbrupt(elements[0]) {
+			return elements[0]
+		}
+		return e.track(&object.Array{Elements: elements})
+	case *ast.IndexExpression:
+		left := e.eval(node.Left, env)
+		if isAbrupt(left) {
+			return left
+		}
+		index := e.eval(node.Index, env)
+		if isAbrupt(index) {
+			return index
+		}
+		return evalIndexExpression(left, index)
+	case *ast.MemberExpression:
+		return e.evalMemberExpression(node, env)
+	case *ast.AssignExpression:
+		return e.evalAssignExpression(node, env)
+	case *ast.HashLiteral:
+		return e.evalHashLiteral(node, env)
+	case *ast.PropagateExpression:
+		left := e.eval(node.Left, env)
+		if isAbrupt(left) {
+			return left
+		}
+		return evalPropagateExpression(left)
+	case *ast.ThrowStatement:
+		value := e.eval(node.Value, env)
+		if isAbrupt(value) {
+			return value
+		}
+		return thrownError(value)
+	case *ast.TryExpression:
+		return e.evalTryExpression(node, env)
+	case *ast.MatchExpression:
+		return e.evalMatchExpression(node, env)
+	case *ast.WhileStatement:
+		return e.evalWhileStatement(node, env)
+	case *ast.ForStatement:
+		return e.evalForStatement(node, env)
+	case *ast.BreakStatement:
+		return BREAK
+	case *ast.ContinueStatement:
+		return CONTINUE
 	}
 	return NULL
 }
 
-func evalStatements(stmts []ast.Statement) object.Object {
+// step counts one evaluated node and returns an error once the evaluation
+// has to stop
+func (e *evaluator) step() *object.Error {
+	if e.aborted != nil {
+		return e.aborted
+	}
+	e.steps++
+	if e.maxSteps > 0 && e.steps > e.maxSteps {
+		e.aborted = &object.Error{
+			Message: fmt.Sprintf("execution limit exceeded: more than %d steps", e.maxSteps),
+			Kind:    object.LimitError,
+		}
+	} else if e.steps%checkInterval == 1 {
+		if e.ctx != nil && e.ctx.Err() != nil {
+			e.aborted = &object.Error{
+				Message: "execution cancelled: " + e.ctx.Err().Error(),
+				Kind:    object.CancelledError,
+			}
+		} else if !e.deadline.IsZero() && time.Now().After(e.deadline) {
+			e.aborted = &object.Error{
+				Message: "execution limit exceeded: deadline passed",
+				Kind:    object.LimitError,
+			}
+		}
+	}
+	return e.aborted
+}
+
+// evaluates the statements of the program, stopping at the first return
+// statement or error
+func (e *evaluator) evalProgram(stmts []ast.Statement, env *object.Environment) object.Object {
+	var result object.Object
+
+	for _, statement := range stmts {
+		result = e.eval(statement, env)
+
+		switch result := result.(type) {
+		case *object.ReturnValue:
+			return result.Value
+		case *object.Error:
+			return result
+		case *object.Break, *object.Continue:
+			return loopControlError(result)
+		}
+	}
+
+	return result
+}
+
+// like evalProgram, but return values, break and continue are passed up so
+// the function call or loop they are in knows to stop. blocks without a
+// value are NULL. the statements run in env, see scope.go for the scope a
+// block gets
+func (e *evaluator) evalBlockStatement(stmts []ast.Statement, env *object.Environment) object.Object {
 	var result object.Object
 
 	for _, statement := range stmts {
-		result = Eval(statement)
+		result = e.eval(statement, env)
+
+		if isAbrupt(result) {
+			return result
+		}
 	}
 
+	if result == nil {
+		return NULL
+	}
 	return result
 }
 
+func newError(format string, a ...interface{}) *object.Error {
+	return &object.Error{Message: fmt.Sprintf(format, a...), Kind: object.RuntimeError}
+}
+
+func isError(obj object.Object) bool {
+	if obj != nil {
+		return obj.Type() == object.ERROR_OBJ
+	}
+	return false
+}
+
+// isAbrupt reports whether obj stops the expression it came out of: an
+// error, a return value on its way to the function it returns from, which
+// may come from a return in an if block or from the ? operator, or a break
+// or continue on its way to its loop
+func isAbrupt(obj object.Object) bool {
+	if obj != nil {
+		switch obj.Type() {
+		case object.ERROR_OBJ, object.RETURN_VALUE_OBJ, object.BREAK_OBJ, object.CONTINUE_OBJ:
+			return true
+		}
+	}
+	return false
+}
+
 func nativeBoolToBoolObject(input bool) *object.Boolean {
 	if input {
 		return TRUE
@@ -57,7 +361,7 @@ func evalPrefixExpression(operator string, right object.Object) object.Object {
 	case "-":
 		return evalNegOperator(right)
 	default:
-		return nil
+		return newError("unknown operator: %s%s", operator, right.Type())
 	}
 }
 
@@ -76,7 +380,7 @@ func evalBangOperator(right object.Object) object.Object {
 
 func evalNegOperator(right object.Object) object.Object {
 	if right.Type() != object.INTEGER_OBJ {
-		return NULL
+		return newError("unknown operator: -%s", right.Type())
 	}
 	value := right.(*object.Integer).Value
 	return &object.Integer{Value: 0 - value}
@@ -86,19 +390,20 @@ func evalInfixExpression(operator string, left object.Object, right object.Objec
 	switch {
 	case left.Type() == object.INTEGER_OBJ && right.Type() == object.INTEGER_OBJ:
 		return evalIntegerInfixExpression(operator, left, right)
+	case left.Type() == object.STRING_OBJ && right.Type() == object.STRING_OBJ:
+		return evalStringInfixExpression(operator, left, right)
 	case operator == "==":
 		return nativeBoolToBoolObject(left == right)
 	case operator == "!=":
 		return nativeBoolToBoolObject(left != right)
+	case left.Type() != right.Type():
+		return newError("type mismatch: %s %s %s", left.Type(), operator, right.Type())
 	default:
-		return NULL
+		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
 	}
 }
 
 func evalIntegerInfixExpression(operator string, left object.Object, right object.Object) object.Object {
-	if left.Type() != object.INTEGER_OBJ || right.Type() != object.INTEGER_OBJ {
-		return NULL
-	}
 	valueLeft := left.(*object.Integer).Value
 	valueRight := right.(*object.Integer).Value
 	switch operator {
@@ -109,8 +414,14 @@ func evalIntegerInfixExpression(operator string, left object.Object, right objec
 	case "*":
 		return &object.Integer{Value: valueLeft * valueRight}
 	case "/":
+		if valueRight == 0 {
+			return newError("division by zero")
+		}
 		return &object.Integer{Value: valueLeft / valueRight}
 	case "%":
+		if valueRight == 0 {
+			return newError("division by zero")
+		}
 		return &object.Integer{Value: valueLeft % valueRight}
 	case "==":
 		return nativeBoolToBoolObject(valueLeft == valueRight)
@@ -121,16 +432,34 @@ func evalIntegerInfixExpression(operator string, left object.Object, right objec
 	case "!=":
 		return nativeBoolToBoolObject(valueLeft != valueRight)
 	default:
-		return NULL
+		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
 	}
 }
 
-func evalIfExpression(ie *ast.IfExpression) object.Object {
-	condition := Eval(ie.Condition)
+func evalStringInfixExpression(operator string, left object.Object, right object.Object) object.Object {
+	valueLeft := left.(*object.String).Value
+	valueRight := right.(*object.String).Value
+	switch operator {
+	case "+":
+		return &object.String{Value: valueLeft + valueRight}
+	case "==":
+		return nativeBoolToBoolObject(valueLeft == valueRight)
+	case "!=":
+		return nativeBoolToBoolObject(valueLeft != valueRight)
+	default:
+		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+	}
+}
+
+func (e *evaluator) evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.Object {
+	condition := e.eval(ie.Condition, env)
+	if isAbrupt(condition) {
+		return condition
+	}
 	if isTruthy(condition) {
-		return Eval(ie.Then)
+		return e.eval(ie.Then, env)
 	} else if ie.Else != nil {
-		return Eval(ie.Else)
+		return e.eval(ie.Else, env)
 	} else {
 		return NULL
 	}
@@ -147,3 +476,213 @@ func isTruthy(obj object.Object) bool {
 	}
 
 }
+
+func evalIdentifier(node *ast.Identifier, env *object.Environment) object.Object {
+	if value, ok := env.Get(node.Value); ok {
+		return value
+	}
+	if builtin, ok := builtins[node.Value]; ok {
+		return builtin
+	}
+	return newError("identifier not found: " + node.Value)
+}
+
+// an Ok gives its value, an Err is returned from the function it is in the
+// same way a return statement would
+func evalPropagateExpression(left object.Object) object.Object {
+	result, ok := left.(*object.Result)
+	if !ok {
+		return newError("unknown operator: %s?", left.Type())
+	}
+	if result.IsErr {
+		return &object.ReturnValue{Value: result}
+	}
+	return result.Value
+}
+
+func evalIndexExpression(left, index object.Object) object.Object {
+	switch {
+	case left.Type() == object.ARRAY_OBJ && index.Type() == object.INTEGER_OBJ:
+		return evalArrayIndexExpression(left, index)
+	case left.Type() == object.HASH_OBJ:
+		return evalHashIndexExpression(left, index)
+	default:
+		return newError("index operator not supported: %s[%s]", left.Type(), index.Type())
+	}
+}
+
+// indexes outside the array give null
+func evalArrayIndexExpression(array, index object.Object) object.Object {
+	elements := array.(*object.Array).Elements
+	i := index.(*object.Integer).Value
+	if i < 0 || i >= int64(len(elements)) {
+		return NULL
+	}
+	return elements[i]
+}
+
+// keys that aren't in the hash give null
+func evalHashIndexExpression(hash, index object.Object) object.Object {
+	key, ok := index.(object.Hashable)
+	if !ok {
+		return newError("unusable as hash key: %s", index.Type())
+	}
+	pair, ok := hash.(*object.Hash).Pairs[key.HashKey()]
+	if !ok {
+		return NULL
+	}
+	return pair.Value
+}
+
+// the pairs are evaluated in order, key first. a key that is already in
+// the hash replaces its value
+func (e *evaluator) evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Object {
+	hash := object.NewHash()
+
+	for _, pair := range node.Pairs {
+		key := e.eval(pair.Key, env)
+		if isAbrupt(key) {
+			return key
+		}
+		hashKey, ok := key.(object.Hashable)
+		if !ok {
+			return newError("unusable as hash key: %s", key.Type())
+		}
+		value := e.eval(pair.Value, env)
+		if isAbrupt(value) {
+			return value
+		}
+		hash.Set(hashKey, value)
+	}
+
+	return e.track(hash)
+}
+
+// evaluates the expressions from left to right, if one of them fails or
+// returns the result is just that error or return value
+func (e *evaluator) evalExpressions(exps []ast.Expression, env *object.Environment) []object.Object {
+	var result []object.Object
+
+	for _, exp := range exps {
+		evaluated := e.eval(exp, env)
+		if isAbrupt(evaluated) {
+			return []object.Object{evaluated}
+		}
+		result = append(result, evaluated)
+	}
+
+	return result
+}
+
+// callSite is where a call starts in the source, the start of the
+// expression for the function being called
+func callSite(node *ast.CallExpression) token.Token {
+	if tok := ast.TokenOf(node.Function); tok.Line != 0 {
+		return tok
+	}
+	return node.Token
+}
+
+// applyFunction calls fn with args. site is where the call is in the
+// source, it is recorded on the call stack and in the stack trace of
+// errors coming out of the call
+func (e *evaluator) applyFunction(fn object.Object, args []object.Object, site token.Token) object.Object {
+	if len(e.stack) >= e.maxCallDepth {
+		return newError("maximum call depth exceeded")
+	}
+	e.stack = append(e.stack, object.StackFrame{})
+	e.callers = append(e.callers, e.env)
+	defer func() {
+		e.stack = e.stack[:len(e.stack)-1]
+		e.env = e.callers[len(e.callers)-1]
+		e.callers = e.callers[:len(e.callers)-1]
+	}()
+
+	for {
+		if builtin, ok := fn.(*object.Builtin); ok {
+			if err := namedArgumentError(builtin.Name, args); err != nil {
+				return err
+			}
+			e.stack[len(e.stack)-1] = object.StackFrame{Function: builtin.Name, Line: site.Line, Column: site.Column}
+			result := builtin.Fn(args...)
+			if !borrows[builtin.Name] {
+				result = e.track(result)
+			}
+			return e.unwind(result)
+		}
+		if bm, ok := fn.(*boundMethod); ok {
+			if err := namedArgumentError(bm.name, args); err != nil {
+				return err
+			}
+			e.stack[len(e.stack)-1] = object.StackFrame{Function: bm.name, Line: site.Line, Column: site.Column}
+			return e.unwind(e.callMethod(bm, args, site))
+		}
+		function, ok := fn.(*object.Function)
+		if !ok {
+			return newError("not a function: %s", fn.Type())
+		}
+		values, extra, err := matchArguments(function, args)
+		if err != nil {
+			return err
+		}
+		e.stack[len(e.stack)-1] = stackFrame(function, site)
+
+		bindings := len(function.Parameters)
+		if function.Rest != nil {
+			bindings++
+		}
+		extendedEnv, err := e.enclose(function.Env, bindings)
+		if err != nil {
+			return e.unwind(err)
+		}
+		if abrupt := e.bindArguments(function, values, extra, extendedEnv); abrupt != nil {
+			return e.unwind(unwrapReturnValue(abrupt))
+		}
+		evaluated := e.evalFunctionBody(function.Body, extendedEnv, true)
+		// a call the body ended with is made here instead of inside it,
+		// taking the place of this one on the stack. see tailcall.go
+		if call, ok := tailCallOf(evaluated); ok {
+			fn, args, site = call.function, call.arguments, call.site
+			continue
+		}
+		result := unwrapReturnValue(evaluated)
+		if result == BREAK || result == CONTINUE {
+			result = loopControlError(result)
+		}
+		return e.unwind(result)
+	}
+}
+
+func stackFrame(fn *object.Function, site token.Token) object.StackFrame {
+	name := fn.Name
+	if name == "" {
+		name = "<anonymous>"
+	}
+	return object.StackFrame{Function: name, Line: site.Line, Column: site.Column}
+}
+
+// unwind adds the call that is returning to the stack trace of obj if it
+// is an error. errors collect the calls they pass through this way, from
+// the one they happened in outwards. mistakes in the call itself, like a
+// wrong number of arguments, are reported from the caller so they don't
+// go through here
+func (e *evaluator) unwind(obj object.Object) object.Object {
+	err, ok := obj.(*object.Error)
+	if !ok {
+		return obj
+	}
+	if len(err.Stack) < maxStackFrames {
+		err.Stack = append(err.Stack, e.stack[len(e.stack)-1])
+	} else {
+		err.Omitted++
+	}
+	return err
+}
+
+// a return stops at the function it is in, not the caller
+func unwrapReturnValue(obj object.Object) object.Object {
+	if returnValue, ok := obj.(*object.ReturnValue); ok {
+		return returnValue.Value
+	}
+	return obj
+}
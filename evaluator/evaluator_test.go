@@ -4,14 +4,25 @@ import (
 	"Go-interpreter/lexer"
 	"Go-interpreter/object"
 	"Go-interpreter/parser"
+	"context"
+	"fmt"
+	"strings"
 	"testing"
+	"time"
 )
 
-func testEval(input string) object.Object {
+// testEval evaluates input in a new environment. input has to parse, a
+// test that means to check a runtime error should not fail earlier
+func testEval(t *testing.T, input string) object.Object {
+	t.Helper()
 	l := lexer.New(input)
 	p := parser.New(l)
 	program := p.ParseProgram()
-	return Eval(program)
+	if errors := p.Errors(); len(errors) != 0 {
+		t.Fatalf("%q has parser errors: %s", input, strings.Join(errors, "; "))
+	}
+	env := object.NewEnvironment()
+	return Eval(program, env)
 }
 
 func testIntegerObject(t *testing.T, obj object.Object, expected int64) bool {
@@ -64,7 +75,7 @@ func TestEvaluateIntegerExpression(t *testing.T) {
 		{"(5 + 10 * 2 + 15 / 3) * 2 + -10", 50},
 	}
 	for _, tt := range tests {
-		evaluated := testEval(tt.input)
+		evaluated := testEval(t, tt.input)
 		testIntegerObject(t, evaluated, tt.expected)
 	}
 }
@@ -95,7 +106,7 @@ func TestEvaluateBooleanExpression(t *testing.T) {
 		{"(1 > 2) == false", true},
 	}
 	for _, tt := range tests {
-		evaluated := testEval(tt.input)
+		evaluated := testEval(t, tt.input)
 		testBooleanObject(t, evaluated, tt.expected)
 	}
 }
@@ -114,7 +125,7 @@ func TestIfElseExpressions(t *testing.T) {
 		{"if (1 < 2) { 10 } else { 20 }", 10},
 	}
 	for _, tt := range tests {
-		evaluated := testEval(tt.input)
+		evaluated := testEval(t, tt.input)
 		integer, ok := tt.expected.(int)
 		if ok {
 			testIntegerObject(t, evaluated, int64(integer))
@@ -131,3 +142,1255 @@ func testNullObject(t *testing.T, obj object.Object) bool {
 	}
 	return true
 }
+
+func TestReturnStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"return 10;", 10},
+		{"return 10; 9;", 10},
+		{"return 2 * 5; 9;", 10},
+		{"9; return 2 * 5; 9;", 10},
+		{
+			`
+if (10 > 1) {
+  if (10 > 1) {
+    return 10;
+  }
+
+  return 1;
+}
+`,
+			10,
+		},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(t, tt.input)
+		testIntegerObject(t, evaluated, tt.expected)
+	}
+}
+
+func TestErrorHandling(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedMessage string
+	}{
+		{"5 + true;", "type mismatch: INTEGER + BOOLEAN"},
+		{"5 + true; 5;", "type mismatch: INTEGER + BOOLEAN"},
+		{"-true", "unknown operator: -BOOLEAN"},
+		{"true + false;", "unknown operator: BOOLEAN + BOOLEAN"},
+		{"5; true + false; 5", "unknown operator: BOOLEAN + BOOLEAN"},
+		{"if (10 > 1) { true + false; }", "unknown operator: BOOLEAN + BOOLEAN"},
+		{
+			`
+if (10 > 1) {
+  if (10 > 1) {
+    return true + false;
+  }
+
+  return 1;
+}
+`,
+			"unknown operator: BOOLEAN + BOOLEAN",
+		},
+		{"foobar", "identifier not found: foobar"},
+		{"5 / 0", "division by zero"},
+		{"5 % (2 - 2)", "division by zero"},
+		{"5(1)", "not a function: INTEGER"},
+		{"fn(x) { x }(1, 2)", "wrong number of arguments to fn(x): want=1, got=2"},
+		{"fn(x, y) { x }(1)", "wrong number of arguments to fn(x, y): want=2, got=1"},
+		{"fn(x) { x }(1 + true)", "type mismatch: INTEGER + BOOLEAN"},
+		{`"Hello" - "World"`, "unknown operator: STRING - STRING"},
+		{`{"name": "Monkey"}[fn(x) { x }];`, "unusable as hash key: FUNCTION"},
+		{`{[1]: 2}`, "unusable as hash key: ARRAY"},
+		{`1[0]`, "index operator not supported: INTEGER[INTEGER]"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(t, tt.input)
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+			continue
+		}
+		if errObj.Message != tt.expectedMessage {
+			t.Errorf("wrong error message. expected=%q, got=%q",
+				tt.expectedMessage, errObj.Message)
+		}
+	}
+}
+
+func TestLetStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"let a = 5; a;", 5},
+		{"let a = 5 * 5; a;", 25},
+		{"let a = 5; let b = a; b;", 5},
+		{"let a = 5; let b = a; let c = a + b + 5; c;", 15},
+	}
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(t, tt.input), tt.expected)
+	}
+
+	if evaluated := testEval(t, "let a = 5;"); evaluated != nil {
+		t.Errorf("let produced a value. got=%T (%+v)", evaluated, evaluated)
+	}
+	testNullObject(t, testEval(t, "if (true) { let a = 5; }"))
+}
+
+func TestFunctionObject(t *testing.T) {
+	input := "fn(x) { x + 2; };"
+
+	evaluated := testEval(t, input)
+	fn, ok := evaluated.(*object.Function)
+	if !ok {
+		t.Fatalf("object is not Function. got=%T (%+v)", evaluated, evaluated)
+	}
+	if len(fn.Parameters) != 1 {
+		t.Fatalf("function has wrong parameters. Parameters=%+v",
+			fn.Parameters)
+	}
+	if fn.Parameters[0].String() != "x" {
+		t.Fatalf("parameter is not 'x'. got=%q", fn.Parameters[0])
+	}
+	expectedBody := "(x + 2)"
+	if fn.Body.String() != expectedBody {
+		t.Fatalf("body is not %q. got=%q", expectedBody, fn.Body.String())
+	}
+	if fn.Inspect() != "fn(x) {\n(x + 2)\n}" {
+		t.Fatalf("wrong Inspect(). got=%q", fn.Inspect())
+	}
+}
+
+func TestFunctionApplication(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"let identity = fn(x) { x; }; identity(5);", 5},
+		{"let identity = fn(x) { return x; }; identity(5);", 5},
+		{"let double = fn(x) { x * 2; }; double(5);", 10},
+		{"let add = fn(x, y) { x + y; }; add(5, 5);", 10},
+		{"let add = fn(x, y) { x + y; }; add(5 + 5, add(5, 5));", 20},
+		{"fn(x) { x; }(5)", 5},
+		{"let f = fn(x) { return x; 10 }; f(5) + 1", 6},
+		{"let fact = fn(n) { if (n < 2) { 1 } else { n * fact(n - 1) } }; fact(5)", 120},
+	}
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(t, tt.input), tt.expected)
+	}
+	testNullObject(t, testEval(t, "fn() {}()"))
+	testNullObject(t, testEval(t, "fn() { let x = 1; }()"))
+}
+
+func TestClosures(t *testing.T) {
+	input := `
+let newAdder = fn(x) {
+  fn(y) { x + y };
+};
+
+let addTwo = newAdder(2);
+addTwo(2);`
+
+	testIntegerObject(t, testEval(t, input), 4)
+}
+
+func TestTailCalls(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`let countdown = fn(n) { if (n == 0) { 0 } else { countdown(n - 1) } };
+		countdown(1000000)`, 0},
+		{`let sum = fn(n, acc) { if (n == 0) { return acc; } return sum(n - 1, acc + n); };
+		sum(1000000, 0)`, 500000500000},
+		{`let even = fn(n) { if (n == 0) { true } else { odd(n - 1) } };
+		let odd = fn(n) { if (n == 0) { false } else { even(n - 1) } };
+		even(1000001)`, false},
+		{`let loop = fn(n) { if (n > 0) { return loop(n - 1); } let done = n; done };
+		loop(1000000)`, 0},
+		{`let f = fn(n) { if (n == 0) { return 5; } if (true) { f(n - 1) } };
+		f(1000000)`, 5},
+		{`let countdown = fn(n) { n == 0 ? 0 : countdown(n - 1) }; countdown(1000000)`, 0},
+		{`let countdown = fn(n) { match (n) { 0 => 0, _ => countdown(n - 1) } }; countdown(1000000)`, 0},
+		{`let f = fn(n) { match (n) { 0 => { 0 } m => { let k = m - 1; f(k) } } }; f(1000000)`, 0},
+		{`let f = fn(n) { if (n > 0) { let m = n - 1; return f(m); } n }; f(1000000)`, 0},
+		// not a tail call, the addition happens after it returns
+		{`let sum = fn(n) { if (n == 0) { 0 } else { n + sum(n - 1) } }; sum(100)`, 5050},
+		{`let f = fn() { 5(); }; f()`, "not a function: INTEGER"},
+		{`let f = fn(n) { f(n, n) }; f(1)`, "wrong number of arguments to f(n): want=1, got=2"},
+		{`let f = fn(n) { return g(n); }; f(1)`, "identifier not found: g"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(t, tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestMaxCallDepth(t *testing.T) {
+	input := `let down = fn(n) { 1 + down(n + 1) };
+let start = fn() { down(0) };
+start()`
+
+	program := parser.New(lexer.New(input)).ParseProgram()
+	evaluated := EvalWithOptions(program, object.NewEnvironment(), Options{MaxCallDepth: 50})
+
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+	}
+	if errObj.Message != "maximum call depth exceeded" {
+		t.Errorf("wrong error message. got=%q", errObj.Message)
+	}
+	if len(errObj.Stack) != maxStackFrames || errObj.Omitted != 50-maxStackFrames {
+		t.Errorf("wrong trace size. frames=%d, omitted=%d", len(errObj.Stack), errObj.Omitted)
+	}
+	innermost := object.StackFrame{Function: "down", Line: 1, Column: 24}
+	if errObj.Stack[0] != innermost {
+		t.Errorf("wrong innermost frame. want=%+v, got=%+v", innermost, errObj.Stack[0])
+	}
+
+	trace := errObj.StackTrace("")
+	if !strings.HasPrefix(trace, "down(...)\n\t1:24\n") || !strings.HasSuffix(trace, "...30 more calls...\n") {
+		t.Errorf("wrong stack trace:\n%s", trace)
+	}
+
+	// the default limit stops runaway recursion before Go's stack overflows
+	evaluated = testEval(t, "let f = fn(n) { 1 + f(n) }; f(0)")
+	errObj, ok = evaluated.(*object.Error)
+	if !ok || errObj.Message != "maximum call depth exceeded" {
+		t.Errorf("runaway recursion wasn't stopped. got=%T(%+v)", evaluated, evaluated)
+	}
+
+	// tail calls don't nest, so they aren't limited
+	evaluated = EvalWithOptions(
+		parser.New(lexer.New("let f = fn(n) { if (n == 0) { 0 } else { f(n - 1) } }; f(1000)")).ParseProgram(),
+		object.NewEnvironment(), Options{MaxCallDepth: 10})
+	testIntegerObject(t, evaluated, 0)
+}
+
+func TestExecutionLimits(t *testing.T) {
+	forever := "let loop = fn(n) { loop(n + 1) }; loop(0)"
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	timeout, cancelTimeout := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancelTimeout()
+
+	tests := []struct {
+		input           string
+		opts            Options
+		expectedMessage string
+		expectedKind    string
+	}{
+		{forever, Options{MaxSteps: 500}, "execution limit exceeded: more than 500 steps", object.LimitError},
+		{forever, Options{Context: cancelled}, "execution cancelled: context canceled", object.CancelledError},
+		{forever, Options{Context: timeout}, "execution cancelled: context deadline exceeded", object.CancelledError},
+		{forever, Options{Deadline: time.Now().Add(20 * time.Millisecond)}, "execution limit exceeded: deadline passed", object.LimitError},
+		{"1 + 2 * 3", Options{MaxSteps: 5}, "execution limit exceeded: more than 5 steps", object.LimitError},
+	}
+
+	for _, tt := range tests {
+		program := parser.New(lexer.New(tt.input)).ParseProgram()
+		evaluated := EvalWithOptions(program, object.NewEnvironment(), tt.opts)
+
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("%q: no error object returned. got=%T(%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if errObj.Message != tt.expectedMessage || errObj.Kind != tt.expectedKind {
+			t.Errorf("%q: wrong error. want=%q (%s), got=%q (%s)",
+				tt.input, tt.expectedMessage, tt.expectedKind, errObj.Message, errObj.Kind)
+		}
+	}
+
+	// the program, the statement and five expressions
+	program := parser.New(lexer.New("1 + 2 * 3")).ParseProgram()
+	evaluated := EvalWithOptions(program, object.NewEnvironment(), Options{MaxSteps: 7})
+	testIntegerObject(t, evaluated, 7)
+}
+
+func TestStringLiterals(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`"Hello World!"`, "Hello World!"},
+		{`"Hello" + " " + "World!"`, "Hello World!"},
+		{`"a\tb"`, "a\tb"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(t, tt.input)
+		str, ok := evaluated.(*object.String)
+		if !ok {
+			t.Errorf("object is not String. got=%T (%+v)", evaluated, evaluated)
+			continue
+		}
+		if str.Value != tt.expected {
+			t.Errorf("String has wrong value. expected=%q, got=%q", tt.expected, str.Value)
+		}
+	}
+
+	testBooleanObject(t, testEval(t, `"a" == "a"`), true)
+	testBooleanObject(t, testEval(t, `"a" != "a"`), false)
+	testBooleanObject(t, testEval(t, `"a" == "b"`), false)
+}
+
+func TestBuiltinFunctions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`len("")`, 0},
+		{`len("four")`, 4},
+		{`len([1, 2, 3])`, 3},
+		{`len({"a": 1})`, 1},
+		{`len(1)`, "argument to `len` not supported, got INTEGER"},
+		{`len("one", "two")`, "wrong number of arguments: want=1, got=2"},
+		{`first([1, 2, 3])`, 1},
+		{`first([])`, nil},
+		{`first(1)`, "argument to `first` must be ARRAY, got INTEGER"},
+		{`last([1, 2, 3])`, 3},
+		{`last([])`, nil},
+		{`rest([1, 2, 3])[0]`, 2},
+		{`len(rest([1, 2, 3]))`, 2},
+		{`rest([])`, nil},
+		{`let a = [1]; let b = push(a, 2); len(a) + len(b) * 10`, 21},
+		{`push(1, 1)`, "argument to `push` must be ARRAY, got INTEGER"},
+		{`let len = fn(x) { 42 }; len([])`, 42},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(t, tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case nil:
+			testNullObject(t, evaluated)
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+// compiled programs refer to builtins by their place in BuiltinNames, so it
+// has to list every one of them exactly once
+func TestBuiltinNames(t *testing.T) {
+	seen := map[string]bool{}
+	for _, name := range BuiltinNames {
+		if _, ok := LookupBuiltin(name); !ok || seen[name] {
+			t.Errorf("BuiltinNames has %q, which is unknown or listed twice", name)
+		}
+		seen[name] = true
+	}
+	if len(seen) != len(builtins) {
+		t.Errorf("BuiltinNames has %d builtins, want=%d", len(seen), len(builtins))
+	}
+}
+
+func TestArrayLiterals(t *testing.T) {
+	evaluated := testEval(t, "[1, 2 * 2, 3 + 3]")
+	result, ok := evaluated.(*object.Array)
+	if !ok {
+		t.Fatalf("object is not Array. got=%T (%+v)", evaluated, evaluated)
+	}
+	if len(result.Elements) != 3 {
+		t.Fatalf("array has wrong num of elements. got=%d", len(result.Elements))
+	}
+	testIntegerObject(t, result.Elements[0], 1)
+	testIntegerObject(t, result.Elements[1], 4)
+	testIntegerObject(t, result.Elements[2], 6)
+}
+
+func TestIndexExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"[1, 2, 3][0]", 1},
+		{"[1, 2, 3][2]", 3},
+		{"let i = 0; [1][i];", 1},
+		{"let myArray = [1, 2, 3]; myArray[0] + myArray[1] + myArray[2];", 6},
+		{"[1, 2, 3][3]", nil},
+		{"[1, 2, 3][-1]", nil},
+		{`{"foo": 5}["foo"]`, 5},
+		{`{"foo": 5}["bar"]`, nil},
+		{`let key = "foo"; {"foo": 5}[key]`, 5},
+		{`{}["foo"]`, nil},
+		{`{5: 5}[5]`, 5},
+		{`{true: 5}[true]`, 5},
+		{`{"a": 1, "a": 2}["a"]`, 2},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(t, tt.input)
+		integer, ok := tt.expected.(int)
+		if ok {
+			testIntegerObject(t, evaluated, int64(integer))
+		} else {
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestHashLiterals(t *testing.T) {
+	input := `let two = "two";
+{
+  "one": 10 - 9,
+  two: 1 + 1,
+  "thr" + "ee": 6 / 2,
+  4: 4,
+  true: 5,
+  false: 6
+}`
+	evaluated := testEval(t, input)
+	result, ok := evaluated.(*object.Hash)
+	if !ok {
+		t.Fatalf("Eval didn't return Hash. got=%T (%+v)", evaluated, evaluated)
+	}
+	expected := "{one: 1, two: 2, three: 3, 4: 4, true: 5, false: 6}"
+	if result.Inspect() != expected {
+		t.Errorf("hash wrong. expected=%q, got=%q", expected, result.Inspect())
+	}
+}
+
+func TestMemoryLimit(t *testing.T) {
+	grow := `
+let grow = fn(s, n) { if (n == 0) { s } else { grow(s + s, n - 1) } };
+grow("ab", 40)`
+	tests := []struct {
+		input     string
+		maxMemory int64
+	}{
+		{grow, 1 << 20},
+		{"let build = fn(arr) { build(push(arr, arr)) }; build([])", 1 << 16},
+		{"let loop = fn(h) { loop({1: h, 2: h}) }; loop({})", 1 << 16},
+		{"let a = []; while (true) { a = push(a, len(a)) }", 1 << 16},
+	}
+	for _, tt := range tests {
+		program := parser.New(lexer.New(tt.input)).ParseProgram()
+		result := Run(program, object.NewEnvironment(), Options{MaxMemory: tt.maxMemory})
+
+		errObj, ok := result.Value.(*object.Error)
+		if !ok {
+			t.Errorf("%q: no error object returned. got=%T(%+v)", tt.input, result.Value, result.Value)
+			continue
+		}
+		expected := fmt.Sprintf("memory limit exceeded: more than %d bytes in use", tt.maxMemory)
+		if errObj.Message != expected || errObj.Kind != object.MemoryError {
+			t.Errorf("%q: wrong error. want=%q, got=%q (%s)", tt.input, expected, errObj.Message, errObj.Kind)
+		}
+		if result.Peak <= tt.maxMemory {
+			t.Errorf("%q: Peak is %d, not above the limit", tt.input, result.Peak)
+		}
+	}
+}
+
+// the limit is on what is in use, not on everything that was allocated
+func TestMemoryLimitAllowsGarbage(t *testing.T) {
+	tests := []string{
+		"let i = 0; while (i < 100000) { i += 1 }; i",
+		`let i = 0; while (i < 10000) { let s = "abc" + "def"; i += 1 }; i`,
+		"let f = fn(n) { [n, n + 1] }; let i = 0; while (i < 10000) { f(i); i += 1 }; i",
+		"let a = []; for (x in range(10000)) { a = push(a, x); a = [] }; len(a)",
+	}
+	for _, input := range tests {
+		program := parser.New(lexer.New(input)).ParseProgram()
+		result := Run(program, object.NewEnvironment(), Options{MaxMemory: 1 << 16})
+
+		if errObj, ok := result.Value.(*object.Error); ok {
+			t.Errorf("%q: stopped with %q", input, errObj.Message)
+			continue
+		}
+		if result.Allocated <= 1<<16 {
+			t.Errorf("%q: Allocated is %d, the test should allocate more than the limit", input, result.Allocated)
+		}
+		if result.Peak > 1<<16 {
+			t.Errorf("%q: Peak is %d, above the limit", input, result.Peak)
+		}
+	}
+}
+
+func TestRunReportsUsage(t *testing.T) {
+	tests := []struct {
+		input             string
+		expectedSteps     int64
+		expectedAllocated int64
+		expectedPeak      int64
+	}{
+		// the environment the program runs in is counted in the peak
+		{"true", 3, 0, environmentSize},
+		{"1 + 2", 5, 3 * integerSize, environmentSize + integerSize},
+		{`"abc"`, 3, stringSize + 3, environmentSize + stringSize + 3},
+		{
+			"[1, 2]", 5,
+			arraySize + 2*arrayElementSize + 2*integerSize,
+			environmentSize + arraySize + 2*arrayElementSize + 2*integerSize,
+		},
+		{
+			`{"a": true}`, 5,
+			hashSize + hashPairSize + stringSize + 1,
+			environmentSize + hashSize + hashPairSize + stringSize + 1,
+		},
+		{
+			"let f = fn(x) { x }; f(true)", 8,
+			functionSize + environmentSize + bindingSize,
+			environmentSize + bindingSize + functionSize,
+		},
+	}
+	for _, tt := range tests {
+		program := parser.New(lexer.New(tt.input)).ParseProgram()
+		result := Run(program, object.NewEnvironment(), Options{})
+		if result.Steps != tt.expectedSteps {
+			t.Errorf("%q: Steps wrong. want=%d, got=%d", tt.input, tt.expectedSteps, result.Steps)
+		}
+		if result.Allocated != tt.expectedAllocated {
+			t.Errorf("%q: Allocated wrong. want=%d, got=%d", tt.input, tt.expectedAllocated, result.Allocated)
+		}
+		if result.Peak != tt.expectedPeak {
+			t.Errorf("%q: Peak wrong. want=%d, got=%d", tt.input, tt.expectedPeak, result.Peak)
+		}
+	}
+}
+
+// the peak counts what was held during the run, not only at the end
+func TestRunReportsPeak(t *testing.T) {
+	input := `
+let f = fn() {
+  let s = "ab";
+  let i = 0;
+  while (i < 20) { s = s + s; i += 1 };
+  len(s)
+};
+f()`
+	program := parser.New(lexer.New(input)).ParseProgram()
+	result := Run(program, object.NewEnvironment(), Options{})
+
+	testIntegerObject(t, result.Value, 2<<20)
+	if result.Peak < 2<<20 || result.Peak > 4<<20 {
+		t.Errorf("Peak is %d, want about the 2MB string", result.Peak)
+	}
+	if result.Allocated < result.Peak {
+		t.Errorf("Allocated is %d, less than Peak %d", result.Allocated, result.Peak)
+	}
+}
+
+// builtins and methods that give back a value they were passed don't
+// count it again
+func TestBorrowedValuesAreNotCounted(t *testing.T) {
+	setup := `let a = ["` + strings.Repeat("x", 10000) + `"]; `
+	usage := func(input string) int64 {
+		program := parser.New(lexer.New(input)).ParseProgram()
+		return Run(program, object.NewEnvironment(), Options{}).Allocated
+	}
+	before := usage(setup)
+	for _, call := range []string{
+		"a[0]", "first(a)", "last(a)", "a.first()", "a.last()",
+		"unwrap(ok(a[0]))", "ok(a[0]).unwrap()", "[1].reduce(fn(r, x) { r }, a[0])",
+	} {
+		if added := usage(setup+call) - before; added > 1000 {
+			t.Errorf("%q: counted %d more bytes", call, added)
+		}
+	}
+}
+
+func TestStackTraces(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []object.StackFrame
+	}{
+		{"1 + true", nil},
+		{"fn(x) { x }(1, 2)", nil},
+		{
+			`let inner = fn(x) { x / 0 };
+let outer = fn() { 1 + inner(1) };
+outer()`,
+			[]object.StackFrame{frame("inner", 2, 24), frame("outer", 3, 1)},
+		},
+		{
+			`let apply = fn(f) { 1 + f() };
+apply(fn() { true + 1 })`,
+			[]object.StackFrame{frame("<anonymous>", 1, 25), frame("apply", 2, 1)},
+		},
+		{
+			`let f = fn() { 1 + len(1) };
+f()`,
+			[]object.StackFrame{frame("len", 1, 20), frame("f", 2, 1)},
+		},
+		// the arguments are wrong where the call is made, not inside it
+		{
+			`let f = fn(x) { x };
+let g = fn() { 1 + f() };
+g()`,
+			[]object.StackFrame{frame("g", 3, 1)},
+		},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(t, tt.input)
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("%q: no error object returned. got=%T(%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if len(errObj.Stack) != len(tt.expected) {
+			t.Errorf("%q: wrong stack. want=%v, got=%v", tt.input, tt.expected, errObj.Stack)
+			continue
+		}
+		for i, frame := range tt.expected {
+			if errObj.Stack[i] != frame {
+				t.Errorf("%q: wrong frame %d. want=%s, got=%s", tt.input, i, frame, errObj.Stack[i])
+			}
+		}
+	}
+}
+
+func frame(function string, line, column int) object.StackFrame {
+	return object.StackFrame{Function: function, Line: line, Column: column}
+}
+
+func TestTryCatch(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"try { 1 } catch (e) { 2 }", 1},
+		{"try { 1 + true } catch (e) { 2 }", 2},
+		{`try { throw "boom" } catch (e) { e["message"] }`, "boom"},
+		{`try { throw "boom" } catch (e) { e["kind"] }`, "Error"},
+		{`try { 1 + true } catch (e) { e["message"] }`, "type mismatch: INTEGER + BOOLEAN"},
+		{`try { len(1) } catch (e) { e["kind"] }`, "RuntimeError"},
+		{`try { [1][5] + 1 } catch (e) { e["kind"] }`, "RuntimeError"},
+		{`try { throw 42 } catch (e) { e["message"] }`, "42"},
+		{`try { throw {"message": "bad", "kind": "ValueError"} } catch (e) { e["kind"] + ": " + e["message"] }`, "ValueError: bad"},
+		// errors can be thrown again
+		{`try { try { 1 + true } catch (e) { throw e } } catch (e) { e["message"] }`, "type mismatch: INTEGER + BOOLEAN"},
+		{
+			`let f = fn() { throw "deep" };
+let g = fn() { f(); 1 };
+try { g() } catch (e) { e["stack"] }`,
+			"[f at 2:16, g at 3:7]",
+		},
+		{
+			`let f = fn() { throw "deep" };
+let g = fn() { try { f() } catch (e) { throw e } };
+let h = fn() { g(); 1 };
+try { h() } catch (e) { e["stack"] }`,
+			"[f at 2:22, g at 3:16, h at 4:7]",
+		},
+		{`try { throw {"message": "m", "stack": ["f at 1:2", 3, "nonsense"]} } catch (e) { e["stack"] }`, "[f at 1:2]"},
+		// finally runs whatever happens, the value is the try or catch block's
+		{"let x = 0; try { 1 } finally { let x = 5; }", 1},
+		{"let r = try { 1 + true } catch (e) { 2 } finally { 3 }; r", 2},
+		{"let f = fn() { try { return 1; } finally { 2 } }; f()", 1},
+		{"let f = fn() { try { return 1; } finally { return 2; } }; f()", 2},
+		{"let f = fn() { try { throw 1 } catch (e) { return 3; } }; f()", 3},
+		{"let f = fn() { try { throw 1 } catch (e) { 3 } 4 }; f()", 4},
+		// the caught error is only known in the catch block
+		{"try { throw 1 } catch (e) { 1 }; e", object.Error{Message: "identifier not found: e"}},
+		{`try { throw "a" } finally { 1 }`, object.Error{Message: "a"}},
+		{`try { throw "a" } catch (e) { throw "b" }`, object.Error{Message: "b"}},
+		{`try { 1 } finally { throw "c" }`, object.Error{Message: "c"}},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(t, tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			if evaluated == nil || evaluated.Inspect() != expected {
+				t.Errorf("%q: wrong result. want=%q, got=%+v", tt.input, expected, evaluated)
+			}
+		case object.Error:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok || errObj.Message != expected.Message {
+				t.Errorf("%q: wrong result. want error %q, got=%+v", tt.input, expected.Message, evaluated)
+			}
+		}
+	}
+}
+
+func TestTryCantCatchLimits(t *testing.T) {
+	input := `let loop = fn(n) { loop(n + 1) };
+try { loop(0) } catch (e) { 1 } finally { 2 }`
+	program := parser.New(lexer.New(input)).ParseProgram()
+	evaluated := EvalWithOptions(program, object.NewEnvironment(), Options{MaxSteps: 1000})
+
+	errObj, ok := evaluated.(*object.Error)
+	if !ok || errObj.Kind != object.LimitError {
+		t.Errorf("limit error was caught. got=%T(%+v)", evaluated, evaluated)
+	}
+}
+
+func TestResults(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"ok(1)", "Ok(1)"},
+		{`err("bad")`, "Err(bad)"},
+		{"is_err(ok(1))", false},
+		{"is_err(err(1))", true},
+		{"unwrap(ok(5))", 5},
+		{`unwrap(err("bad"))`, object.Error{Message: "unwrap of Err(bad)"}},
+		{"is_err(1)", object.Error{Message: "argument to `is_err` must be RESULT, got INTEGER"}},
+		{"1?", object.Error{Message: "unknown operator: INTEGER?"}},
+		{"ok(2)? * 3", 6},
+		{
+			`let half = fn(n) { if (n % 2 == 1) { return err("odd"); } ok(n / 2) };
+let quarter = fn(n) { let h = half(n)?; ok(half(h)? + 0) };
+quarter(8)`,
+			"Ok(2)",
+		},
+		{
+			`let half = fn(n) { if (n % 2 == 1) { return err("odd"); } ok(n / 2) };
+let quarter = fn(n) { let h = half(n)?; ok(half(h)? + 0) };
+quarter(6)`,
+			"Err(odd)",
+		},
+		// the Err stops the expressions it is in, like an error would
+		{"let f = fn() { [1, 2 + err(3)?, 4] }; f()", "Err(3)"},
+		{"let f = fn() { len(err(1)?) }; f()", "Err(1)"},
+		{"let f = fn() { try { err(1)? } finally { 2 } }; f()", "Err(1)"},
+		// and returns from the function it is in, not the caller
+		{"let f = fn() { err(1)?; 2 }; let g = fn() { f(); 3 }; g()", 3},
+		// a return in an if inside an expression works the same way
+		{"let f = fn(x) { 1 + if (x) { return 10; } else { 2 } }; f(true)", 10},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(t, tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case string:
+			if _, ok := evaluated.(*object.Result); !ok || evaluated.Inspect() != expected {
+				t.Errorf("%q: wrong result. want=%s, got=%+v", tt.input, expected, evaluated)
+			}
+		case object.Error:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok || errObj.Message != expected.Message {
+				t.Errorf("%q: wrong result. want error %q, got=%+v", tt.input, expected.Message, evaluated)
+			}
+		}
+	}
+}
+
+func TestLoops(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let i = 0; while (i < 5) { i += 1; }; i", 5},
+		{"let i = 10; while (i < 5) { i += 1; }; i", 10},
+		{"let s = 0; for (x in [1, 2, 3]) { s += x; }; s", 6},
+		{`let s = ""; for (k in {"a": 1, "b": 2, "c": 3}) { s += k; }; s`, "abc"},
+		{`let s = ""; for (c in "héllo") { s = c + s; }; s`, "olléh"},
+		{"let s = 0; for (i in range(5)) { s += i; }; s", 10},
+		{"let s = 0; for (i in range(3, 6)) { s += i; }; s", 12},
+		{"let s = 0; for (i in range(6, 3)) { s += i; }; s", 0},
+		{"len(range(2, 7))", 5},
+		{"let s = 0; for (i in range(10)) { if (i == 4) { break; } s += i; }; s", 6},
+		{"let s = 0; for (i in range(6)) { if (i % 2 == 0) { continue; } s += i; }; s", 9},
+		{"let i = 0; while (true) { i += 1; if (i == 3) { break; } }; i", 3},
+		// break leaves only the innermost loop
+		{"let n = 0; for (i in range(3)) { for (j in range(3)) { if (j == 1) { break; } n += 1; } }; n", 3},
+		// return leaves the loop and the function
+		{"let f = fn() { for (i in range(10)) { if (i == 7) { return i; } } 0 }; f()", 7},
+		{"let f = fn() { while (true) { try { break; } finally { 1 } } 2 }; f()", 2},
+		{"let f = fn() { for (i in [1, 2]) { 1 + if (true) { continue; } } 3 }; f()", 3},
+		// loops are statements, they have no value
+		{"for (i in [1]) { i }", nil},
+		{"for (i in 5) { i }", object.Error{Message: "cannot iterate over INTEGER"}},
+		{"while (x) { 1 }", object.Error{Message: "identifier not found: x"}},
+		{"for (i in [1, 2]) { i + true }", object.Error{Message: "type mismatch: INTEGER + BOOLEAN"}},
+		{`range("a")`, object.Error{Message: "argument to `range` must be INTEGER, got STRING"}},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(t, tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			str, ok := evaluated.(*object.String)
+			if !ok || str.Value != expected {
+				t.Errorf("%q: wrong result. want=%q, got=%+v", tt.input, expected, evaluated)
+			}
+		case object.Error:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok || errObj.Message != expected.Message {
+				t.Errorf("%q: wrong result. want error %q, got=%+v", tt.input, expected.Message, evaluated)
+			}
+		case nil:
+			if evaluated != nil {
+				t.Errorf("%q: loop has a value. got=%+v", tt.input, evaluated)
+			}
+		}
+	}
+}
+
+func TestLoopsStopAtLimits(t *testing.T) {
+	program := parser.New(lexer.New("while (true) { }")).ParseProgram()
+	evaluated := EvalWithOptions(program, object.NewEnvironment(), Options{MaxSteps: 100})
+
+	errObj, ok := evaluated.(*object.Error)
+	if !ok || errObj.Kind != object.LimitError {
+		t.Errorf("endless loop not stopped. got=%T(%+v)", evaluated, evaluated)
+	}
+}
+
+func TestAssignments(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let x = 1; x = 2; x", 2},
+		{"let x = 1; x = x + 1", 2},
+		{"let x = 10; x += 5; x -= 3; x *= 2; x /= 4; x", 6},
+		{"let x = 17; x %= 5", 2},
+		{`let s = "a"; s += "b"; s`, "ab"},
+		{"let a = 1; let b = 2; a = b = 3; a + b", 6},
+		// the nearest binding is changed, an outer one if there is no
+		// inner one
+		{"let x = 1; let f = fn() { x = 5 }; f(); x", 5},
+		{"let x = 1; let f = fn(x) { x = 5 }; f(0); x", 1},
+		{"let counter = fn() { let n = 0; fn() { n += 1 } }; let c = counter(); c(); c(); c()", 3},
+		{"let a = [1, 2, 3]; a[1] = 5; a[1]", 5},
+		{"let a = [1, 2, 3]; a[2] *= 10; a", "[1, 2, 30]"},
+		{"let a = [[1], [2]]; a[1][0] += 1; a", "[[1], [3]]"},
+		{`let h = {"a": 1}; h["a"] = 2; h["b"] = 3; h`, "{a: 2, b: 3}"},
+		{`let h = {"n": 1}; h["n"] += 1; h["n"]`, 2},
+		{"let i = 0; while (i < 5) { i += 1; }; i", 5},
+		{"x = 1", object.Error{Message: "assignment to undeclared name: x"}},
+		{"x += 1", object.Error{Message: "identifier not found: x"}},
+		{"len = 1", object.Error{Message: "assignment to undeclared name: len"}},
+		{"let a = [1]; a[3] = 1", object.Error{Message: "index out of range: 3 with length 1"}},
+		{`let h = {}; h[[1]] = 1`, object.Error{Message: "unusable as hash key: ARRAY"}},
+		{`let s = "ab"; s[0] = "c"`, object.Error{Message: "index assignment not supported: STRING[INTEGER]"}},
+		{`let x = 1; x += "a"`, object.Error{Message: "type mismatch: INTEGER + STRING"}},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(t, tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			if evaluated == nil || evaluated.Inspect() != expected {
+				t.Errorf("%q: wrong result. want=%s, got=%+v", tt.input, expected, evaluated)
+			}
+		case object.Error:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok || errObj.Message != expected.Message {
+				t.Errorf("%q: wrong result. want error %q, got=%+v", tt.input, expected.Message, evaluated)
+			}
+		}
+	}
+}
+
+func TestConstants(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"const x = 5; x * 2", 10},
+		{"const x = 5; let f = fn() { let x = 1; x = 2; x }; f() + x", 7},
+		{"const x = 5; let f = fn(x) { x += 1 }; f(1)", 2},
+		{"const x = 5; x = 6", "cannot assign to constant x"},
+		{"const x = 5; x += 1; x", "cannot assign to constant x"},
+		{"const x = 5; let f = fn() { x = 6 }; f()", "cannot assign to constant x"},
+		{"const x = 5; let x = 6;", "cannot redeclare constant x"},
+		{"const x = 5; const x = 6;", "cannot redeclare constant x"},
+		{"const x = 5; for (y in [1]) { x = y }", "cannot assign to constant x"},
+		// a block can declare the name again, it only hides the constant
+		{"const x = 5; for (x in [1]) { const x = 2; }; if (true) { let x = 3; x = 4 }; x", 5},
+		// the value of a constant can still be changed inside
+		{"const a = [1, 2]; a[0] = 3; a[0]", 3},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(t, tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok || errObj.Message != expected {
+				t.Errorf("%q: wrong result. want error %q, got=%+v", tt.input, expected, evaluated)
+			}
+		}
+	}
+}
+
+func TestBlockScopes(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		// a let in a block hides the outer binding until the block ends
+		{"let x = 1; if (true) { let x = 2; x }", 2},
+		{"let x = 1; if (true) { let x = 2; }; x", 1},
+		{"let x = 1; if (false) { 0 } else { let x = 3; }; x", 1},
+		{"if (true) { let y = 2; }; y", "identifier not found: y"},
+		{"let x = 1; try { let x = 2; } finally { let x = 3; }; x", 1},
+		{`let x = 1; try { throw 1; } catch (e) { let x = len(e["message"]) + 1; x }`, 2},
+		// assignments change the nearest binding, also outside the block
+		{"let x = 1; if (true) { x = 2; }; x", 2},
+		{"let x = 1; if (true) { let x = 2; x = 3; }; x", 1},
+		{"let x = 1; if (true) { if (true) { x += 5; } }; x", 6},
+		// the branches of an if in a function body are scopes too
+		{"let f = fn(x, y) { if (x) { let y = 10; y } else { y } }; f(true, 1) + f(false, 2)", 12},
+		{"let f = fn(x) { if (x) { let x = 10; }; x }; f(1)", 1},
+		{"let f = fn(n) { if (n > 0) { let m = n - 1; return f(m); } n }; f(5)", 0},
+		// lets in a loop body are new on every iteration
+		{"let s = 0; for (i in range(3)) { let d = i * 2; s += d; }; s", 6},
+		{"let i = 0; while (i < 3) { let j = i; i = j + 1; }; i", 3},
+		{"for (i in range(2)) { let k = i; }; k", "identifier not found: k"},
+		{"for (i in range(2)) { }; i", "identifier not found: i"},
+		// closures keep the values of the iteration they were made in
+		{
+			`let fs = []; for (i in range(3)) { fs = push(fs, fn() { i }); };
+fs[0]() * 100 + fs[1]() * 10 + fs[2]()`,
+			12,
+		},
+		{
+			`let fs = []; let n = 0; while (n < 3) { let m = n; fs = push(fs, fn() { m }); n += 1; };
+fs[0]() * 100 + fs[1]() * 10 + fs[2]()`,
+			12,
+		},
+		// but they share the bindings outside the loop
+		{
+			`let fs = []; let n = 0; while (n < 3) { fs = push(fs, fn() { n }); n += 1; };
+fs[0]() + fs[1]() + fs[2]()`,
+			9,
+		},
+		{
+			`let counters = []; for (i in range(2)) { let c = 0; counters = push(counters, fn() { c += 1 }); };
+counters[0](); counters[0](); counters[1]()`,
+			1,
+		},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(t, tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok || errObj.Message != expected {
+				t.Errorf("%q: wrong result. want error %q, got=%+v", tt.input, expected, evaluated)
+			}
+		}
+	}
+}
+
+func TestConditionalAndCoalesce(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"true ? 1 : 2", 1},
+		{"false ? 1 : 2", 2},
+		{"null ? 1 : 2", 2},
+		{"0 ? 1 : 2", 1},
+		{"1 > 2 ? 1 : 2 > 1 ? 3 : 4", 3},
+		{"let f = fn(n) { n < 2 ? n : f(n - 1) + f(n - 2) }; f(10)", 55},
+		// only the branch that is chosen is evaluated
+		{"true ? 1 : missing", 1},
+		{"false ? missing : 2", 2},
+		{"null", nil},
+		{"null ?? 5", 5},
+		{"3 ?? 5", 3},
+		{"false ?? 5", false},
+		{"[1][5] ?? 7", 7},
+		{`{"a": 1}["b"] ?? {"a": 1}["a"]`, 1},
+		{"null ?? null ?? 8", 8},
+		{"1 ?? missing", 1},
+		{"let x = null; x ?? (x = 4); x", 4},
+		{"null == null", true},
+		{"missing ? 1 : 2", object.Error{Message: "identifier not found: missing"}},
+		{"null ?? missing", object.Error{Message: "identifier not found: missing"}},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(t, tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case nil:
+			testNullObject(t, evaluated)
+		case object.Error:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok || errObj.Message != expected.Message {
+				t.Errorf("%q: wrong result. want error %q, got=%+v", tt.input, expected.Message, evaluated)
+			}
+		}
+	}
+}
+
+func TestMatchExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`match (1) { 1 => "one", _ => "other" }`, "one"},
+		{`match (2) { 1 => "one", _ => "other" }`, "other"},
+		{`match (-3) { 3 => 1, -3 => 2 }`, 2},
+		{`match ("b") { "a" => 1, "b" => 2 }`, 2},
+		{`match (true) { false => 1, true => 2 }`, 2},
+		{`match ([1][5]) { 0 => 1, null => 2 }`, 2},
+		{`match ("1") { 1 => 1, _ => 2 }`, 2},
+		{`match (5) { n => n * 2 }`, 10},
+		{`match ([1, 2]) { [a] => a, [a, b] => a + b, _ => 0 }`, 3},
+		{`match ([1, [2, 3]]) { [1, [_, c]] => c }`, 3},
+		{`match ([1, 2]) { [2, b] => b, [1, b] => b * 10 }`, 20},
+		{`match ({"k": 4, "j": 5}) { {"k": v} => v }`, 4},
+		{`match ({"k": 4}) { {"j": v} => v, {"k": 5} => 1, {"k": _} => 2 }`, 2},
+		{`match ({1: [true]}) { {1: [b]} => b }`, true},
+		{`match ([1, 2]) { {"k": v} => v, [_, _] => 6 }`, 6},
+		{`match (7) { n if n < 5 => "small", n if n < 10 => "medium", _ => "large" }`, "medium"},
+		{`match (7) { n if n > 10 => { "large" } _ => { let s = "not large"; s } }`, "not large"},
+		// the first arm that matches wins
+		{`match (1) { _ => 1, 1 => 2 }`, 1},
+		// arms have their own scope
+		{`let n = 1; match (2) { n => n }; n`, 1},
+		{`let a = 1; match ([5]) { [a] if a > 9 => a, _ => a }`, 1},
+		{`let x = 0; match (3) { n => x = n }; x`, 3},
+		{`let f = fn(x) { match (x) { 0 => { return 5; } _ => 1 }; 9 }; f(0)`, 5},
+		{`match (3) { 1 => 1, 2 => 2 }`, "no match arm matches 3"},
+		{`match ([1, 2]) { [a] => a }`, "no match arm matches [1, 2]"},
+		{`match (1) { n if missing => n }`, "identifier not found: missing"},
+		{`match (missing) { _ => 1 }`, "identifier not found: missing"},
+		{`match (1) { n => n }; n`, "identifier not found: n"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(t, tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case string:
+			if errObj, ok := evaluated.(*object.Error); ok {
+				if errObj.Message != expected {
+					t.Errorf("%q: wrong error message. expected=%q, got=%q", tt.input, expected, errObj.Message)
+				}
+				continue
+			}
+			str, ok := evaluated.(*object.String)
+			if !ok || str.Value != expected {
+				t.Errorf("%q: wrong result. want %q, got=%+v", tt.input, expected, evaluated)
+			}
+		}
+	}
+}
+
+func TestDestructuringLet(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let [a, b] = [1, 2]; a * 10 + b", 12},
+		{"let [a, ...rest] = [1, 2, 3]; len(rest) * 10 + rest[1]", 23},
+		{"let [a, ...rest] = [1]; len(rest)", 0},
+		{"let [_, b, _] = [1, 2, 3]; b", 2},
+		{"let [[a, b], c] = [[1, 2], 3]; a + b + c", 6},
+		{"let xs = [1, 2]; let [...copy] = xs; copy[0] = 5; xs[0]", 1},
+		{`let {name, age} = {"name": "x", "age": 3, "extra": true}; age`, 3},
+		{`let {name: n} = {"name": 4}; n`, 4},
+		{`let {1: [a], true: {"k": b}} = {1: [5], true: {"k": 6}}; a + b`, 11},
+		{`let {"a": 1, "b": b} = {"a": 1, "b": 2}; b`, 2},
+		{"let f = fn(pair) { let [a, b] = pair; a - b }; f([5, 3])", 2},
+		{"const [x, y] = [1, 2]; x + y", 3},
+		{"let _ = 5; _", 5},
+		// nothing is bound when the pattern doesn't fit
+		{"let a = 1; try { let [a, b] = [2]; } catch (e) {} a", 1},
+		{"let [a, b] = [1];", "cannot destructure [1]: want 2 elements, got 1"},
+		{"let [a] = [1, 2];", "cannot destructure [1, 2]: want 1 elements, got 2"},
+		{"let [a, b, ...c] = [1];", "cannot destructure [1]: want at least 2 elements, got 1"},
+		{"let [a] = 1;", "cannot destructure 1: want ARRAY, got INTEGER"},
+		{"let [[a]] = [5];", "cannot destructure [5]: want ARRAY, got INTEGER"},
+		{`let {name} = [1];`, "cannot destructure [1]: want HASH, got ARRAY"},
+		{`let {name} = {"age": 1};`, "cannot destructure {age: 1}: missing key name"},
+		{`let [1, a] = [2, 3];`, "cannot destructure [2, 3]: want 1, got 2"},
+		{"const a = 1; let [a, b] = [1, 2];", "cannot redeclare constant a"},
+		{"const [a, b] = [1, 2]; a = 3;", "cannot assign to constant a"},
+		{"let [a] = missing;", "identifier not found: missing"},
+		{"let [a, ...r] = [1, 2]; match ([1, 2, 3]) { [x, ...ys] => len(ys) }", 2},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(t, tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("%q: no error object returned. got=%T(%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("%q: wrong error message. expected=%q, got=%q", tt.input, expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestDefaultRestAndNamedArguments(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let f = fn(a, b = 10) { a + b }; f(1)", 11},
+		{"let f = fn(a, b = 10) { a + b }; f(1, 2)", 3},
+		{"let f = fn(a, b = a * 2) { a + b }; f(3)", 9},
+		// defaults are evaluated at every call, in the scope of the function
+		{"let n = 1; let f = fn(a = n) { a }; n = 5; f()", 5},
+		{"let f = fn(xs = [0]) { xs }; let a = f(); a[0] = 1; f()[0]", 0},
+		{"let f = fn(a, ...rest) { len(rest) * 10 + a }; f(1, 2, 3)", 21},
+		{"let f = fn(...rest) { rest }; len(f())", 0},
+		{"let f = fn(a, b = 2, ...rest) { rest[0] }; f(1, 2, 3)", 3},
+		{"let add = fn(a, b) { a + b }; let xs = [1, 2]; add(...xs)", 3},
+		{"let f = fn(a, b, c) { a * 100 + b * 10 + c }; f(...[1], 2, ...[3])", 123},
+		{"let f = fn(...xs) { len(xs) }; f(...[], ...[1, 2], 3)", 3},
+		{"len(...[[1, 2]])", 2},
+		{"let f = fn(a, b) { a - b }; f(b: 1, a: 5)", 4},
+		{"let f = fn(a, b = 2, c = 3) { a * 100 + b * 10 + c }; f(1, c: 9)", 129},
+		{"let f = fn(a, b = a) { b }; f(a: 7)", 7},
+		{"let f = fn(n, acc = 0) { if (n == 0) { acc } else { f(n - 1, acc: acc + n) } }; f(100000)", 5000050000},
+		{"let f = fn(a, b = 10) { a }; f()", "wrong number of arguments to f(a, b = 10): want=1 or 2, got=0"},
+		{"let f = fn(a, b = 1, c = 2) { a }; f(1, 2, 3, 4)", "wrong number of arguments to f(a, b = 1, c = 2): want=1 to 3, got=4"},
+		{"let f = fn(a, ...rest) { a }; f()", "wrong number of arguments to f(a, ...rest): want=at least 1, got=0"},
+		{"fn(a) { a }(b: 1)", "fn(a) has no parameter b"},
+		{"let f = fn(a, b) { a }; f(1, a: 2)", "argument a given twice to f(a, b)"},
+		{"let f = fn(a, b = 1) { a }; f(b: 2)", "missing argument a to f(a, b = 1)"},
+		{"let f = fn(a = missing) { a }; f()", "identifier not found: missing"},
+		{"let f = fn(a) { a }; f(...1)", "argument to ... must be ARRAY, got INTEGER"},
+		{`len(x: "a")`, "`len` takes no named arguments, got x"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(t, tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("%q: no error object returned. got=%T(%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("%q: wrong error message. expected=%q, got=%q", tt.input, expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestMemberExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`"abc".len()`, 3},
+		{`"a,b,c".split(",").len()`, 3},
+		{`" Hi ".trim().upper()`, "HI"},
+		{`"Hi".lower()`, "hi"},
+		{`"abc".contains("bc")`, true},
+		{"[1, 2, 3].map(fn(x) { x * 2 }).last()", 6},
+		{"[1, 2, 3, 4].filter(fn(x) { x % 2 == 0 }).join(\", \")", "2, 4"},
+		{"[1, 2, 3].reduce(fn(sum, x) { sum + x }, 0)", 6},
+		{"[1, 2].push(3).rest().first()", 2},
+		{"let xs = [1]; xs.push(2); xs.len()", 1},
+		{"[].first()", nil},
+		{"range(5).len()", 5},
+		{"ok(1).unwrap()", 1},
+		{`err("no").is_err()`, true},
+		{`let h = {"a": 1, "b": 2}; h.a + h.b`, 3},
+		{`let h = {"a": {"b": [5]}}; h.a.b[0]`, 5},
+		{`{"a": 1}.missing`, nil},
+		{`{"a": 1, "b": 2}.keys().join("")`, "ab"},
+		{`{"a": 1, "b": 2}.values().last()`, 2},
+		{`{"a": 1}.has("a")`, true},
+		{`{"len": 10}.len`, 10},
+		{`let h = {"greet": fn(name) { "hi " + name }}; h.greet("bob")`, "hi bob"},
+		{`let h = {}; h.x = 1; h.x += 2; h.x`, 3},
+		{`let h = {"n": 1}; h.n = h.n + 1; h["n"]`, 2},
+		{`let len = fn(x) { 0 }; "abc".len()`, 3},
+		{`let f = "abc".len; f()`, 3},
+		{"let count = fn(xs, n) { if (xs.len() == 0) { n } else { count(xs.rest(), n + 1) } }; count([1, 2, 3], 0)", 3},
+		{`"abc".size()`, "STRING has no method size"},
+		{"5.len()", "INTEGER has no method len"},
+		{`"abc".len(1)`, "wrong number of arguments to `len`: want=0, got=1"},
+		{"[1].map(fn(a, b) { a })", "wrong number of arguments to fn(a, b): want=2, got=1"},
+		{"[1].map(1)", "not a function: INTEGER"},
+		{"[1].map(fn(x) { x + true })", "type mismatch: INTEGER + BOOLEAN"},
+		{`"a".split(1)`, "argument to `split` must be STRING, got INTEGER"},
+		{`"a".x = 1`, "field assignment not supported: STRING.x"},
+		{"missing.len()", "identifier not found: missing"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(t, tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case nil:
+			testNullObject(t, evaluated)
+		case string:
+			switch obj := evaluated.(type) {
+			case *object.String:
+				if obj.Value != expected {
+					t.Errorf("%q: wrong string. expected=%q, got=%q", tt.input, expected, obj.Value)
+				}
+			case *object.Error:
+				if obj.Message != expected {
+					t.Errorf("%q: wrong error message. expected=%q, got=%q", tt.input, expected, obj.Message)
+				}
+			default:
+				t.Errorf("%q: expected %q, got=%T(%+v)", tt.input, expected, evaluated, evaluated)
+			}
+		}
+	}
+}
+
+func TestMethodErrorStackTrace(t *testing.T) {
+	input := `let f = fn(x) {
+  x + true
+};
+[1].map(f)`
+	evaluated := testEval(t, input)
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+	}
+	var functions []string
+	for _, frame := range errObj.Stack {
+		functions = append(functions, frame.Function)
+	}
+	if strings.Join(functions, " ") != "f map" {
+		t.Errorf("wrong stack. want=[f map], got=%v", functions)
+	}
+}
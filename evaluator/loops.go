@@ -0,0 +1,122 @@
+package evaluator
+
+import (
+	"Go-interpreter/ast"
+	"Go-interpreter/object"
+	"unicode/utf8"
+)
+
+// Loops are statements, like let they don't have a value. break and
+// continue come back from the body as BREAK and CONTINUE, which blocks
+// pass up like return values until they reach the loop. The parser
+// doesn't allow them outside a loop, if one still gets to a function
+// call or the program it is an error
+
+func (e *evaluator) evalWhileStatement(ws *ast.WhileStatement, env *object.Environment) object.Object {
+	for {
+		condition := e.eval(ws.Condition, env)
+		if isAbrupt(condition) {
+			return condition
+		}
+		if !isTruthy(condition) {
+			return nil
+		}
+		if result, done := loopBodyResult(e.eval(ws.Body, env)); done {
+			return result
+		}
+	}
+}
+
+// for (x in iterable) binds x to every element of an array, every key of
+// a hash, every character of a string or every integer of a range
+func (e *evaluator) evalForStatement(fs *ast.ForStatement, env *object.Environment) object.Object {
+	iterable := e.eval(fs.Iterable, env)
+	if isAbrupt(iterable) {
+		return iterable
+	}
+
+	// each calls body with the elements one at a time until it returns
+	// false
+	var each func(body func(object.Object) bool)
+	switch iterable := iterable.(type) {
+	case *object.Array:
+		each = func(body func(object.Object) bool) {
+			for _, el := range iterable.Elements {
+				if !body(el) {
+					return
+				}
+			}
+		}
+	case *object.Hash:
+		each = func(body func(object.Object) bool) {
+			for _, key := range iterable.Keys {
+				if !body(iterable.Pairs[key].Key) {
+					return
+				}
+			}
+		}
+	case *object.String:
+		each = func(body func(object.Object) bool) {
+			s := iterable.Value
+			for len(s) > 0 {
+				_, size := utf8.DecodeRuneInString(s)
+				if !body(e.track(&object.String{Value: s[:size]})) {
+					return
+				}
+				s = s[size:]
+			}
+		}
+	case *object.Range:
+		each = func(body func(object.Object) bool) {
+			for i := iterable.Start; i < iterable.End; i++ {
+				if !body(e.track(&object.Integer{Value: i})) {
+					return
+				}
+			}
+		}
+	default:
+		return newError("cannot iterate over %s", iterable.Type())
+	}
+
+	var result object.Object
+	each(func(el object.Object) bool {
+		if isError(el) {
+			result = el
+			return false
+		}
+		// the body is run like a block, with the variable bound in its
+		// scope. see scope.go
+		if err := e.step(); err != nil {
+			result = err
+			return false
+		}
+		iterationEnv, err := e.enclose(env, 1)
+		if err != nil {
+			result = err
+			return false
+		}
+		iterationEnv.Set(fs.Variable.Value, el)
+		var done bool
+		result, done = loopBodyResult(e.evalBlockStatement(fs.Body.Statements, iterationEnv))
+		return !done
+	})
+	return result
+}
+
+// loopBodyResult looks at what the body of a loop ended with and returns
+// what the loop should give if it has to stop
+func loopBodyResult(result object.Object) (object.Object, bool) {
+	switch {
+	case result == BREAK:
+		return nil, true
+	case result == CONTINUE:
+		return nil, false
+	case isAbrupt(result):
+		return result, true
+	}
+	return nil, false
+}
+
+func loopControlError(obj object.Object) *object.Error {
+	return newError("%s outside a loop", obj.Inspect())
+}
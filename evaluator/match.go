@@ -0,0 +1,60 @@
+package evaluator
+
+import (
+	"Go-interpreter/ast"
+	"Go-interpreter/object"
+)
+
+// A match tries its arms in order and evaluates the body of the first one
+// whose pattern matches the value and whose guard, if it has one, is true.
+// The names the pattern binds live in an environment of their own inside
+// the one around the match, only the guard and the body of that arm see
+// them. A value that no arm matches is an error.
+
+func (e *evaluator) evalMatchExpression(me *ast.MatchExpression, env *object.Environment) object.Object {
+	arm, armEnv, err := e.matchArm(me, env)
+	if err != nil {
+		return err
+	}
+	return e.eval(arm.Body, armEnv)
+}
+
+// matchArm returns the arm that matches the value of me and the
+// environment its body runs in, or what stopped the match
+func (e *evaluator) matchArm(me *ast.MatchExpression, env *object.Environment) (*ast.MatchArm, *object.Environment, object.Object) {
+	value := e.eval(me.Value, env)
+	if isAbrupt(value) {
+		return nil, nil, value
+	}
+
+	for _, arm := range me.Arms {
+		bindings := []binding{}
+		if matchPattern(arm.Pattern, value, &bindings) != "" {
+			continue
+		}
+		armEnv := env
+		if len(bindings) > 0 {
+			if err := e.trackBindings(bindings); err != nil {
+				return nil, nil, err
+			}
+			var err *object.Error
+			if armEnv, err = e.enclose(env, len(bindings)); err != nil {
+				return nil, nil, err
+			}
+			for _, b := range bindings {
+				armEnv.Set(b.name, b.value)
+			}
+		}
+		if arm.Guard != nil {
+			guard := e.eval(arm.Guard, armEnv)
+			if isAbrupt(guard) {
+				return nil, nil, guard
+			}
+			if !isTruthy(guard) {
+				continue
+			}
+		}
+		return arm, armEnv, nil
+	}
+	return nil, nil, newError("no match arm matches %s", value.Inspect())
+}
@@ -0,0 +1,161 @@
+package evaluator
+
+import (
+	"Go-interpreter/object"
+	"fmt"
+)
+
+// Memory is accounted for roughly: every object the evaluator creates adds
+// about what it takes up in Go to a running total. Objects are never taken
+// off that total one by one, instead every so often the evaluator measures
+// what is still in use, going through everything that can be reached from
+// the environments of the code that is running like the mark phase of a
+// garbage collector, and starts counting again from there. Values that
+// only live while an expression is worked out, like the arguments of a
+// call before they are bound, are left out of a measurement.
+//
+// A measurement is made whenever the count gets past twice what was in use
+// the last time, or 64KB before the first one, when it gets past
+// Options.MaxMemory and at the end of the run. The most that was found in
+// use is reported as Result.Peak. A program is only stopped for holding
+// too much when a measurement says so, so a loop that makes and drops a
+// new integer on every iteration runs in constant space.
+//
+// Containers are counted without what they hold, the elements of an array
+// are objects of their own. Booleans and null are shared and cost nothing.
+const (
+	integerSize      = 16
+	stringSize       = 16 // plus one byte per byte of the string
+	arraySize        = 24 // plus arrayElementSize per element
+	arrayElementSize = 16
+	hashSize         = 48 // plus hashPairSize per pair
+	hashPairSize     = 64
+	functionSize     = 64
+	resultSize       = 24
+	rangeSize        = 24
+	environmentSize  = 48 // plus bindingSize per name bound in it
+	bindingSize      = 32
+)
+
+// the count isn't measured before it gets this far
+const minMeasure = 64 << 10
+
+// sizeOf is how many bytes obj is counted as
+func sizeOf(obj object.Object) int64 {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		return integerSize
+	case *object.String:
+		return stringSize + int64(len(obj.Value))
+	case *object.Array:
+		return arraySize + arrayElementSize*int64(len(obj.Elements))
+	case *object.Hash:
+		return hashSize + hashPairSize*int64(len(obj.Keys))
+	case *object.Function:
+		return functionSize
+	case *object.Result:
+		return resultSize
+	case *object.Range:
+		return rangeSize
+	}
+	return 0
+}
+
+// track counts obj, which was just created, and returns it, or an error if
+// that goes over the memory limit
+func (e *evaluator) track(obj object.Object) object.Object {
+	if err := e.allocate(sizeOf(obj)); err != nil {
+		return err
+	}
+	return obj
+}
+
+// allocate counts size bytes and measures what is in use once the count
+// gets far enough. when that is more than the memory limit it returns an
+// error, like the other limits it stops the whole evaluation
+func (e *evaluator) allocate(size int64) *object.Error {
+	if e.aborted != nil {
+		return e.aborted
+	}
+	e.allocated += size
+	e.inUse += size
+	if e.inUse <= e.nextMeasure {
+		return nil
+	}
+	// what size is for isn't reachable yet
+	e.inUse = e.measure() + size
+	e.peak = max(e.peak, e.inUse)
+	e.nextMeasure = max(2*e.inUse, minMeasure)
+	if e.maxMemory <= 0 {
+		return nil
+	}
+	if e.inUse > e.maxMemory {
+		e.aborted = &object.Error{
+			Message: fmt.Sprintf("memory limit exceeded: more than %d bytes in use", e.maxMemory),
+			Kind:    object.MemoryError,
+		}
+		return e.aborted
+	}
+	e.nextMeasure = min(e.nextMeasure, e.maxMemory)
+	return nil
+}
+
+// measure adds up the size of everything that can be reached from the
+// environment being evaluated in, the ones of the calls below it and
+// values
+func (e *evaluator) measure(values ...object.Object) int64 {
+	m := &marker{seen: map[any]bool{}}
+	m.env(e.env)
+	for _, env := range e.callers {
+		m.env(env)
+	}
+	for _, value := range values {
+		m.object(value)
+	}
+	return m.size
+}
+
+// marker goes through objects and environments, counting each one once
+type marker struct {
+	seen map[any]bool
+	size int64
+}
+
+func (m *marker) env(env *object.Environment) {
+	for ; env != nil && !m.seen[env]; env = env.Outer() {
+		m.seen[env] = true
+		m.size += environmentSize
+		env.Each(func(_ string, value object.Object) {
+			m.size += bindingSize
+			m.object(value)
+		})
+	}
+}
+
+func (m *marker) object(obj object.Object) {
+	if obj == nil || m.seen[obj] {
+		return
+	}
+	m.seen[obj] = true
+	m.size += sizeOf(obj)
+
+	switch obj := obj.(type) {
+	case *object.Array:
+		for _, el := range obj.Elements {
+			m.object(el)
+		}
+	case *object.Hash:
+		for _, pair := range obj.Pairs {
+			m.object(pair.Key)
+			m.object(pair.Value)
+		}
+	case *object.Function:
+		m.env(obj.Env)
+	case *object.Result:
+		m.object(obj.Value)
+	case *object.ReturnValue:
+		m.object(obj.Value)
+	case *boundMethod:
+		m.object(obj.value)
+	}
+}
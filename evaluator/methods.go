@@ -0,0 +1,210 @@
+package evaluator
+
+import (
+	"Go-interpreter/ast"
+	"Go-interpreter/object"
+	"Go-interpreter/token"
+	"strings"
+)
+
+// value.name looks name up in the methods of the type of value and gives
+// the method bound to value, a builtin that can be called right away,
+// value.name(args), or passed around like any other function.
+// "abc".len() is the same call as len("abc"). A hash gives the value of
+// its key "name" instead if it has one, and null for a name that is
+// neither a key nor a method, like hash["name"] would.
+
+// method is a function of the values of one type, it gets the value it
+// was called on and the arguments after it
+type method struct {
+	arity int // the number of arguments, not counting the value
+	fn    func(call caller, value object.Object, args []object.Object) object.Object
+}
+
+// caller calls a function a method was given, like the one map applies to
+// every element
+type caller func(fn object.Object, args ...object.Object) object.Object
+
+var methods = map[object.ObjectType]map[string]method{
+	object.STRING_OBJ: {
+		"len": builtinMethod("len", 0),
+		"upper": {0, func(call caller, value object.Object, args []object.Object) object.Object {
+			return &object.String{Value: strings.ToUpper(value.(*object.String).Value)}
+		}},
+		"lower": {0, func(call caller, value object.Object, args []object.Object) object.Object {
+			return &object.String{Value: strings.ToLower(value.(*object.String).Value)}
+		}},
+		"trim": {0, func(call caller, value object.Object, args []object.Object) object.Object {
+			return &object.String{Value: strings.TrimSpace(value.(*object.String).Value)}
+		}},
+		"split": {1, func(call caller, value object.Object, args []object.Object) object.Object {
+			sep, ok := args[0].(*object.String)
+			if !ok {
+				return newError("argument to `split` must be STRING, got %s", args[0].Type())
+			}
+			parts := strings.Split(value.(*object.String).Value, sep.Value)
+			elements := make([]object.Object, len(parts))
+			for i, part := range parts {
+				elements[i] = &object.String{Value: part}
+			}
+			return &object.Array{Elements: elements}
+		}},
+		"contains": {1, func(call caller, value object.Object, args []object.Object) object.Object {
+			sub, ok := args[0].(*object.String)
+			if !ok {
+				return newError("argument to `contains` must be STRING, got %s", args[0].Type())
+			}
+			return nativeBoolToBoolObject(strings.Contains(value.(*object.String).Value, sub.Value))
+		}},
+	},
+	object.ARRAY_OBJ: {
+		"len":   builtinMethod("len", 0),
+		"first": builtinMethod("first", 0),
+		"last":  builtinMethod("last", 0),
+		"rest":  builtinMethod("rest", 0),
+		"push":  builtinMethod("push", 1),
+		// map, filter and reduce make new arrays like rest and push
+		"map": {1, func(call caller, value object.Object, args []object.Object) object.Object {
+			elements := value.(*object.Array).Elements
+			mapped := make([]object.Object, 0, len(elements))
+			for _, el := range elements {
+				result := call(args[0], el)
+				if isError(result) {
+					return result
+				}
+				mapped = append(mapped, result)
+			}
+			return &object.Array{Elements: mapped}
+		}},
+		"filter": {1, func(call caller, value object.Object, args []object.Object) object.Object {
+			kept := []object.Object{}
+			for _, el := range value.(*object.Array).Elements {
+				result := call(args[0], el)
+				if isError(result) {
+					return result
+				}
+				if isTruthy(result) {
+					kept = append(kept, el)
+				}
+			}
+			return &object.Array{Elements: kept}
+		}},
+		// reduce(f, initial) calls f(result, element) for every element,
+		// starting with initial
+		"reduce": {2, func(call caller, value object.Object, args []object.Object) object.Object {
+			result := args[1]
+			for _, el := range value.(*object.Array).Elements {
+				result = call(args[0], result, el)
+				if isError(result) {
+					return result
+				}
+			}
+			return result
+		}},
+		"join": {1, func(call caller, value object.Object, args []object.Object) object.Object {
+			sep, ok := args[0].(*object.String)
+			if !ok {
+				return newError("argument to `join` must be STRING, got %s", args[0].Type())
+			}
+			parts := []string{}
+			for _, el := range value.(*object.Array).Elements {
+				parts = append(parts, el.Inspect())
+			}
+			return &object.String{Value: strings.Join(parts, sep.Value)}
+		}},
+	},
+	object.HASH_OBJ: {
+		"len": builtinMethod("len", 0),
+		"keys": {0, func(call caller, value object.Object, args []object.Object) object.Object {
+			hash := value.(*object.Hash)
+			keys := make([]object.Object, len(hash.Keys))
+			for i, key := range hash.Keys {
+				keys[i] = hash.Pairs[key].Key
+			}
+			return &object.Array{Elements: keys}
+		}},
+		"values": {0, func(call caller, value object.Object, args []object.Object) object.Object {
+			hash := value.(*object.Hash)
+			values := make([]object.Object, len(hash.Keys))
+			for i, key := range hash.Keys {
+				values[i] = hash.Pairs[key].Value
+			}
+			return &object.Array{Elements: values}
+		}},
+		"has": {1, func(call caller, value object.Object, args []object.Object) object.Object {
+			key, ok := args[0].(object.Hashable)
+			if !ok {
+				return newError("unusable as hash key: %s", args[0].Type())
+			}
+			_, ok = value.(*object.Hash).Pairs[key.HashKey()]
+			return nativeBoolToBoolObject(ok)
+		}},
+	},
+	object.RANGE_OBJ: {
+		"len": builtinMethod("len", 0),
+	},
+	object.RESULT_OBJ: {
+		"is_err": builtinMethod("is_err", 0),
+		"unwrap": builtinMethod("unwrap", 0),
+	},
+}
+
+// builtinMethod makes the builtin called name a method, the value is its
+// first argument
+func builtinMethod(name string, arity int) method {
+	return method{arity, func(call caller, value object.Object, args []object.Object) object.Object {
+		return builtins[name].Fn(append([]object.Object{value}, args...)...)
+	}}
+}
+
+// boundMethod is a method together with the value it was looked up on.
+// programs see it as a builtin
+type boundMethod struct {
+	name   string
+	value  object.Object
+	method method
+}
+
+func (bm *boundMethod) Type() object.ObjectType { return object.BUILTIN_OBJ }
+func (bm *boundMethod) Inspect() string         { return "builtin function " + bm.name }
+
+func (e *evaluator) evalMemberExpression(me *ast.MemberExpression, env *object.Environment) object.Object {
+	value := e.eval(me.Object, env)
+	if isAbrupt(value) {
+		return value
+	}
+	return member(value, me.Property.Value)
+}
+
+// member gives the field or the bound method called name of value
+func member(value object.Object, name string) object.Object {
+	if hash, ok := value.(*object.Hash); ok {
+		if pair, ok := hash.Pairs[(&object.String{Value: name}).HashKey()]; ok {
+			return pair.Value
+		}
+	}
+	m, ok := methods[value.Type()][name]
+	if !ok {
+		if value.Type() == object.HASH_OBJ {
+			return NULL
+		}
+		return newError("%s has no method %s", value.Type(), name)
+	}
+	return &boundMethod{name: name, value: value, method: m}
+}
+
+// callMethod is applyFunction for a bound method. functions the method is
+// given are called from site, the call of the method
+func (e *evaluator) callMethod(bm *boundMethod, args []object.Object, site token.Token) object.Object {
+	if len(args) != bm.method.arity {
+		return newError("wrong number of arguments to `%s`: want=%d, got=%d", bm.name, bm.method.arity, len(args))
+	}
+	call := func(fn object.Object, args ...object.Object) object.Object {
+		return e.applyFunction(fn, args, site)
+	}
+	result := bm.method.fn(call, bm.value, args)
+	if borrows[bm.name] {
+		return result
+	}
+	return e.track(result)
+}
@@ -0,0 +1,185 @@
+package evaluator
+
+import (
+	"Go-interpreter/ast"
+	"Go-interpreter/object"
+	"fmt"
+)
+
+// Patterns are matched by match expressions and destructured by lets, see
+// ast.MatchArm for what they can be. Matching a value gives the names the
+// pattern binds and what they are bound to, the caller decides where they
+// go.
+
+type binding struct {
+	name  string
+	value object.Object
+	rest  bool // value is a new array made for a ...rest
+}
+
+// matchPattern matches value against pattern and adds the names it binds
+// to bindings. it returns why value doesn't match, or "" if it does
+func matchPattern(pattern ast.Expression, value object.Object, bindings *[]binding) string {
+	switch pattern := pattern.(type) {
+	case *ast.Identifier:
+		if pattern.Value != "_" {
+			*bindings = append(*bindings, binding{name: pattern.Value, value: value})
+		}
+		return ""
+	case *ast.ArrayLiteral:
+		arr, ok := value.(*object.Array)
+		if !ok {
+			return fmt.Sprintf("want ARRAY, got %s", value.Type())
+		}
+		elements := pattern.Elements
+		var rest *ast.SpreadExpression
+		if n := len(elements); n > 0 {
+			if rest, ok = elements[n-1].(*ast.SpreadExpression); ok {
+				elements = elements[:n-1]
+			}
+		}
+		if rest == nil && len(arr.Elements) != len(elements) {
+			return fmt.Sprintf("want %d elements, got %d", len(elements), len(arr.Elements))
+		}
+		if rest != nil && len(arr.Elements) < len(elements) {
+			return fmt.Sprintf("want at least %d elements, got %d", len(elements), len(arr.Elements))
+		}
+		for i, element := range elements {
+			if reason := matchPattern(element, arr.Elements[i], bindings); reason != "" {
+				return reason
+			}
+		}
+		if rest != nil {
+			name := rest.Value.(*ast.Identifier).Value
+			if name != "_" {
+				left := make([]object.Object, len(arr.Elements)-len(elements))
+				copy(left, arr.Elements[len(elements):])
+				*bindings = append(*bindings, binding{name: name, value: &object.Array{Elements: left}, rest: true})
+			}
+		}
+		return ""
+	case *ast.HashLiteral:
+		hash, ok := value.(*object.Hash)
+		if !ok {
+			return fmt.Sprintf("want HASH, got %s", value.Type())
+		}
+		for _, pair := range pattern.Pairs {
+			key := literalValue(pair.Key)
+			found, ok := hash.Pairs[key.(object.Hashable).HashKey()]
+			if !ok {
+				return fmt.Sprintf("missing key %s", pair.Key)
+			}
+			if reason := matchPattern(pair.Value, found.Value, bindings); reason != "" {
+				return reason
+			}
+		}
+		return ""
+	}
+	literal := literalValue(pattern)
+	if literal == nil || !sameValue(literal, value) {
+		return fmt.Sprintf("want %s, got %s", pattern, value.Inspect())
+	}
+	return ""
+}
+
+// literalValue returns the value of a literal pattern, nil if pattern isn't
+// one. the parser only lets - in front of integers
+func literalValue(pattern ast.Expression) object.Object {
+	switch pattern := pattern.(type) {
+	case *ast.IntegerLiteral:
+		return &object.Integer{Value: pattern.Value}
+	case *ast.PrefixExpression:
+		if integer, ok := pattern.Right.(*ast.IntegerLiteral); ok && pattern.Operator == "-" {
+			return &object.Integer{Value: -integer.Value}
+		}
+	case *ast.StringLiteral:
+		return &object.String{Value: pattern.Value}
+	case *ast.Boolean:
+		return nativeBoolToBoolObject(pattern.Value)
+	case *ast.NullLiteral:
+		return NULL
+	}
+	return nil
+}
+
+// sameValue compares a literal with a value like == does, except that
+// values of different types are just not the same instead of an error
+func sameValue(literal, value object.Object) bool {
+	switch literal := literal.(type) {
+	case *object.Integer:
+		integer, ok := value.(*object.Integer)
+		return ok && integer.Value == literal.Value
+	case *object.String:
+		str, ok := value.(*object.String)
+		return ok && str.Value == literal.Value
+	}
+	// booleans and null are shared
+	return literal == value
+}
+
+// trackBindings counts the arrays made for ...rest patterns
+func (e *evaluator) trackBindings(bindings []binding) *object.Error {
+	for _, b := range bindings {
+		if b.rest {
+			if err := e.allocate(sizeOf(b.value)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// evalLetStatement binds the names of a let or const. a pattern that
+// doesn't fit the value is an error and binds nothing
+func (e *evaluator) evalLetStatement(node *ast.LetStatement, env *object.Environment) object.Object {
+	bindings := []binding{}
+	// let _ = x binds _, only patterns use it for values they ignore
+	ident, single := node.Name.(*ast.Identifier)
+	if single {
+		bindings = append(bindings, binding{name: ident.Value})
+	}
+	// a constant can only be shadowed in an inner environment
+	check := func() *object.Error {
+		for _, b := range bindings {
+			if env.IsConst(b.name) {
+				return newError("cannot redeclare constant %s", b.name)
+			}
+		}
+		return nil
+	}
+	if err := check(); err != nil {
+		return err
+	}
+
+	value := e.eval(node.Value, env)
+	if isAbrupt(value) {
+		return value
+	}
+	if single {
+		bindings[0].value = value
+		// a function defined with let is known by that name in stack traces
+		if fn, ok := value.(*object.Function); ok && fn.Name == "" {
+			fn.Name = ident.Value
+		}
+	} else {
+		if reason := matchPattern(node.Name, value, &bindings); reason != "" {
+			return newError("cannot destructure %s: %s", value.Inspect(), reason)
+		}
+		if err := check(); err != nil {
+			return err
+		}
+		if err := e.trackBindings(bindings); err != nil {
+			return err
+		}
+	}
+
+	for _, b := range bindings {
+		if node.IsConst() {
+			env.SetConst(b.name, b.value)
+		} else {
+			env.Set(b.name, b.value)
+		}
+	}
+	// let doesn't produce a value
+	return nil
+}
@@ -0,0 +1,40 @@
+package evaluator
+
+import (
+	"Go-interpreter/ast"
+	"Go-interpreter/object"
+)
+
+// Every block is a scope of its own. A let or const in the block of an if,
+// a loop, a try, catch or finally binds the name in a new environment
+// inside the one around the block, so it hides a binding of the same name
+// outside until the block ends and is gone after it. Assignments still
+// change the nearest binding, which can be outside the block.
+//
+// The body of a function shares the environment of its parameters, and
+// the variable of a for loop is bound in the same environment as the lets
+// of the body. Loops get a new one for every iteration, so a closure made
+// in the body keeps the values of that iteration.
+//
+// Blocks that don't declare anything run in the environment around them,
+// it makes no difference to them and saves making one.
+
+// evalScopedBlock evaluates block in a scope of its own
+func (e *evaluator) evalScopedBlock(block *ast.BlockStatement, env *object.Environment) object.Object {
+	if block.Declares() {
+		var err *object.Error
+		if env, err = e.enclose(env, 0); err != nil {
+			return err
+		}
+	}
+	return e.evalBlockStatement(block.Statements, env)
+}
+
+// enclose returns a new environment inside env for a block that starts
+// with bindings names bound
+func (e *evaluator) enclose(env *object.Environment, bindings int) (*object.Environment, *object.Error) {
+	if err := e.allocate(environmentSize + bindingSize*int64(bindings)); err != nil {
+		return nil, err
+	}
+	return object.NewEnclosedEnvironment(env), nil
+}
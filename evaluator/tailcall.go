@@ -0,0 +1,150 @@
+package evaluator
+
+import (
+	"Go-interpreter/ast"
+	"Go-interpreter/object"
+	"Go-interpreter/token"
+)
+
+// Calls in tail position, the last thing a function does before
+// returning, are not made right away. Evaluating them gives a tailCall
+// with the function and its arguments, which applyFunction then runs in
+// place of the call that just finished. That way a recursive loop like
+//
+//	let countdown = fn(n) { if (n == 0) { 0 } else { countdown(n - 1) } };
+//
+// runs in constant Go stack space however deep it goes. The price is that
+// errors only list the last call of such a loop in their stack trace.
+//
+// A call is in tail position when it is the last expression of the
+// function body or of a branch of an if, a c ? a : b or an arm of a match
+// in tail position, or the value of a return statement in the body or in
+// the branches of the ifs in it.
+
+// tailCall never leaves applyFunction, programs can't see it
+type tailCall struct {
+	function  object.Object
+	arguments []object.Object
+	site      token.Token
+}
+
+func (tc *tailCall) Type() object.ObjectType { return "TAIL_CALL" }
+func (tc *tailCall) Inspect() string         { return "tail call" }
+
+// evalFunctionBody evaluates the statements of a function body like
+// evalBlockStatement, but calls in tail position come back as a tailCall,
+// either on its own or wrapped in a ReturnValue. it is also used for the
+// blocks of the ifs in the body, which get their scope from evalTailBlock.
+// tail is false for the blocks of ifs that aren't the last statement,
+// where only the return statements are in tail position
+func (e *evaluator) evalFunctionBody(block *ast.BlockStatement, env *object.Environment, tail bool) object.Object {
+	var result object.Object
+
+	for i, statement := range block.Statements {
+		last := tail && i == len(block.Statements)-1
+		result = e.evalTailStatement(statement, env, last)
+
+		if isAbrupt(result) {
+			return result
+		}
+	}
+
+	if result == nil {
+		return NULL
+	}
+	return result
+}
+
+func (e *evaluator) evalTailStatement(statement ast.Statement, env *object.Environment, tail bool) object.Object {
+	switch statement := statement.(type) {
+	case *ast.ReturnStatement:
+		value := e.evalTailExpression(statement.ReturnValue, env)
+		if isAbrupt(value) {
+			return value
+		}
+		return &object.ReturnValue{Value: value}
+	case *ast.ExpressionStatement:
+		if ie, ok := statement.Expression.(*ast.IfExpression); ok {
+			// the return statements in the branches are in tail position
+			// even if the if isn't
+			return e.evalTailIf(ie, env, tail)
+		}
+		if tail {
+			return e.evalTailExpression(statement.Expression, env)
+		}
+	}
+	return e.eval(statement, env)
+}
+
+// evalTailExpression evaluates an expression in tail position
+func (e *evaluator) evalTailExpression(exp ast.Expression, env *object.Environment) object.Object {
+	switch exp := exp.(type) {
+	case *ast.CallExpression:
+		function := e.eval(exp.Function, env)
+		if isAbrupt(function) {
+			return function
+		}
+		args := e.evalArguments(exp.Arguments, env)
+		if len(args) == 1 && isAbrupt(args[0]) {
+			return args[0]
+		}
+		return &tailCall{function: function, arguments: args, site: callSite(exp)}
+	case *ast.IfExpression:
+		return e.evalTailIf(exp, env, true)
+	case *ast.ConditionalExpression:
+		condition := e.eval(exp.Condition, env)
+		if isAbrupt(condition) {
+			return condition
+		}
+		if isTruthy(condition) {
+			return e.evalTailExpression(exp.Consequence, env)
+		}
+		return e.evalTailExpression(exp.Alternative, env)
+	case *ast.MatchExpression:
+		arm, armEnv, err := e.matchArm(exp, env)
+		if err != nil {
+			return err
+		}
+		if block, ok := arm.Body.(*ast.BlockStatement); ok {
+			return e.evalTailBlock(block, armEnv, true)
+		}
+		return e.evalTailStatement(arm.Body, armEnv, true)
+	}
+	return e.eval(exp, env)
+}
+
+// like evalIfExpression, with the branches evaluated as part of the
+// function body
+func (e *evaluator) evalTailIf(ie *ast.IfExpression, env *object.Environment, tail bool) object.Object {
+	condition := e.eval(ie.Condition, env)
+	if isAbrupt(condition) {
+		return condition
+	}
+	if isTruthy(condition) {
+		return e.evalTailBlock(ie.Then, env, tail)
+	} else if ie.Else != nil {
+		return e.evalTailBlock(ie.Else, env, tail)
+	} else {
+		return NULL
+	}
+}
+
+// like evalScopedBlock, for the branches of an if in the function body
+func (e *evaluator) evalTailBlock(block *ast.BlockStatement, env *object.Environment, tail bool) object.Object {
+	if block.Declares() {
+		var err *object.Error
+		if env, err = e.enclose(env, 0); err != nil {
+			return err
+		}
+	}
+	return e.evalFunctionBody(block, env, tail)
+}
+
+// tailCallOf returns the call a function body ended with, if any
+func tailCallOf(obj object.Object) (*tailCall, bool) {
+	if rv, ok := obj.(*object.ReturnValue); ok {
+		obj = rv.Value
+	}
+	call, ok := obj.(*tailCall)
+	return call, ok
+}
@@ -0,0 +1,146 @@
+package evaluator
+
+import (
+	"Go-interpreter/ast"
+	"Go-interpreter/object"
+	"fmt"
+	"strings"
+)
+
+// Errors raised by throw or by the interpreter can be caught with
+//
+//	try { ... } catch (e) { ... } finally { ... }
+//
+// The caught error is given to the catch block as a hash with its
+// "message", "kind" and "stack", the calls it went through as strings
+// like "f at 1:2". Throwing that hash again keeps the stack, the calls it
+// goes through next are added to it. The finally block runs however the others end, also
+// when they return or break out of a loop, and a return, break or error
+// in it wins over theirs.
+//
+// Errors that stop the whole evaluation, like a step or memory limit,
+// can't be caught and skip finally blocks.
+
+func (e *evaluator) evalTryExpression(te *ast.TryExpression, env *object.Environment) object.Object {
+	result := e.eval(te.Block, env)
+	if uncatchable(result) {
+		return result
+	}
+
+	if err, ok := result.(*object.Error); ok && te.Catch != nil {
+		caught := e.track(errorValue(err))
+		if isError(caught) {
+			return caught
+		}
+		// the error is only known inside the catch block
+		catchEnv, err := e.enclose(env, 1)
+		if err != nil {
+			return err
+		}
+		catchEnv.Set(te.Parameter.Value, caught)
+		result = e.eval(te.Catch, catchEnv)
+		if uncatchable(result) {
+			return result
+		}
+	}
+
+	if te.Finally != nil {
+		finally := e.eval(te.Finally, env)
+		if isAbrupt(finally) {
+			return finally
+		}
+	}
+	return result
+}
+
+// uncatchable reports whether obj is an error that stops the evaluation
+func uncatchable(obj object.Object) bool {
+	err, ok := obj.(*object.Error)
+	if !ok {
+		return false
+	}
+	switch err.Kind {
+	case object.CancelledError, object.LimitError, object.MemoryError:
+		return true
+	}
+	return false
+}
+
+// errorValue turns err into the hash a catch block sees
+func errorValue(err *object.Error) *object.Hash {
+	stack := &object.Array{Elements: []object.Object{}}
+	for _, frame := range err.Stack {
+		stack.Elements = append(stack.Elements, &object.String{Value: frame.String()})
+	}
+	hash := object.NewHash()
+	hash.Set(&object.String{Value: "message"}, &object.String{Value: err.Message})
+	hash.Set(&object.String{Value: "kind"}, &object.String{Value: err.Kind})
+	hash.Set(&object.String{Value: "stack"}, stack)
+	return hash
+}
+
+// thrownError is the error throw raises for value. a hash like the ones
+// catch blocks get is thrown with its message, kind and stack, so errors
+// can be thrown again, anything else becomes the message
+func thrownError(value object.Object) *object.Error {
+	err := &object.Error{Message: value.Inspect(), Kind: object.ThrownError}
+	hash, ok := value.(*object.Hash)
+	if !ok {
+		return err
+	}
+	if message, ok := hashString(hash, "message"); ok {
+		err.Message = message
+	}
+	if kind, ok := hashString(hash, "kind"); ok {
+		err.Kind = kind
+	}
+	err.Stack = stackFrames(hash)
+	if len(err.Stack) > maxStackFrames {
+		err.Omitted = len(err.Stack) - maxStackFrames
+		err.Stack = err.Stack[:maxStackFrames]
+	}
+	return err
+}
+
+// stackFrames reads back the frames errorValue wrote into the "stack" of
+// hash. entries that don't look like one are skipped
+func stackFrames(hash *object.Hash) []object.StackFrame {
+	pair, ok := hash.Pairs[(&object.String{Value: "stack"}).HashKey()]
+	if !ok {
+		return nil
+	}
+	stack, ok := pair.Value.(*object.Array)
+	if !ok {
+		return nil
+	}
+	var frames []object.StackFrame
+	for _, el := range stack.Elements {
+		str, ok := el.(*object.String)
+		if !ok {
+			continue
+		}
+		at := strings.LastIndex(str.Value, " at ")
+		if at < 0 {
+			continue
+		}
+		frame := object.StackFrame{Function: str.Value[:at]}
+		if _, err := fmt.Sscanf(str.Value[at+len(" at "):], "%d:%d", &frame.Line, &frame.Column); err != nil {
+			continue
+		}
+		frames = append(frames, frame)
+	}
+	return frames
+}
+
+// hashString returns the value of key in hash if it is a string
+func hashString(hash *object.Hash, key string) (string, bool) {
+	pair, ok := hash.Pairs[(&object.String{Value: key}).HashKey()]
+	if !ok {
+		return "", false
+	}
+	str, ok := pair.Value.(*object.String)
+	if !ok {
+		return "", false
+	}
+	return str.Value, true
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"Go-interpreter/printer"
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+// runFmt formats the given files, or stdin if there are none. By default the
+// result is printed, -w writes it back to the file and -d prints a diff
+func runFmt(args []string) int {
+	flags := flag.NewFlagSet("fmt", flag.ContinueOnError)
+	write := flags.Bool("w", false, "write the result back to the source file")
+	diff := flags.Bool("d", false, "print a diff instead of the formatted source")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if flags.NArg() == 0 {
+		if *write {
+			fmt.Fprintln(os.Stderr, "fmt: cannot use -w with standard input")
+			return 2
+		}
+		src, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "fmt: %s\n", err)
+			return 1
+		}
+		if err := formatFile("<stdin>", src, false, *diff); err != nil {
+			fmt.Fprintf(os.Stderr, "fmt: %s\n", err)
+			return 1
+		}
+		return 0
+	}
+
+	status := 0
+	for _, path := range flags.Args() {
+		src, err := os.ReadFile(path)
+		if err == nil {
+			err = formatFile(path, src, *write, *diff)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "fmt: %s\n", err)
+			status = 1
+		}
+	}
+	return status
+}
+
+func formatFile(path string, src []byte, write bool, diff bool) error {
+	formatted, err := printer.Format(src)
+	if err != nil {
+		return fmt.Errorf("%s: %s", path, err)
+	}
+	if diff {
+		os.Stdout.WriteString(unifiedDiff(path, string(src), string(formatted)))
+	}
+	if write {
+		if bytes.Equal(src, formatted) {
+			return nil
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(path, formatted, info.Mode().Perm())
+	}
+	if !diff {
+		os.Stdout.Write(formatted)
+	}
+	return nil
+}
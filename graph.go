@@ -0,0 +1,25 @@
+package main
+
+import (
+	"Go-interpreter/dot"
+	"fmt"
+	"os"
+)
+
+// runDot prints the AST of a file as a Graphviz graph
+func runDot(args []string) int {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "usage: monkey dot file")
+		return 2
+	}
+	program, err := parseSource(args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "dot: %s\n", err)
+		return 1
+	}
+	if err := dot.Write(os.Stdout, program); err != nil {
+		fmt.Fprintf(os.Stderr, "dot: %s\n", err)
+		return 1
+	}
+	return 0
+}
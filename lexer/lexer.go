@@ -2,6 +2,7 @@ package lexer
 
 import (
 	"Go-interpreter/token"
+	"strings"
 )
 
 type Lexer struct {
@@ -9,10 +10,14 @@ type Lexer struct {
 	position     int  // current character in terms of index
 	readPosition int  // position of next character in terms of index
 	character    byte // current character in terms of value
+	line         int  // line of the current character
+	column       int  // column of the current character
+
+	comments []token.Token // comments skipped over so far
 }
 
 func New(input string) *Lexer {
-	l := &Lexer{input: input}
+	l := &Lexer{input: input, line: 1}
 	l.readChar()
 	return l
 }
@@ -24,6 +29,11 @@ func newToken(tokenType token.TokenType, character byte) token.Token {
 
 // function for reading the character
 func (l *Lexer) readChar() {
+	if l.character == '\n' {
+		l.line += 1
+		l.column = 0
+	}
+	l.column += 1
 	if l.readPosition >= len(l.input) {
 		l.character = 0
 	} else {
@@ -34,9 +44,26 @@ func (l *Lexer) readChar() {
 }
 
 func (l *Lexer) NextToken() token.Token {
-	var tok token.Token
-
 	l.skipWhitespace()
+	for l.character == '/' && l.peekChar() == '/' {
+		l.readComment()
+		l.skipWhitespace()
+	}
+	// remember where the token starts before reading it
+	line, column := l.line, l.column
+	tok := l.readToken()
+	tok.Line = line
+	tok.Column = column
+	return tok
+}
+
+// Comments returns every comment the lexer has skipped over so far
+func (l *Lexer) Comments() []token.Token {
+	return l.comments
+}
+
+func (l *Lexer) readToken() token.Token {
+	var tok token.Token
 
 	switch l.character {
 	case '=':
@@ -45,6 +72,9 @@ func (l *Lexer) NextToken() token.Token {
 			tok = newToken(token.ASSIGN, l.character)
 			l.readChar()
 			tok = token.Token{Type: token.EQ, Literal: string(ch) + string(l.character)}
+		} else if l.peekChar() == '>' {
+			l.readChar()
+			tok = token.Token{Type: token.ARROW, Literal: "=>"}
 		} else {
 			tok = newToken(token.ASSIGN, l.character)
 		}
@@ -59,15 +89,15 @@ func (l *Lexer) NextToken() token.Token {
 			tok = newToken(token.BANG, l.character)
 		}
 	case '+':
-		tok = newToken(token.PLUS, l.character)
+		tok = l.readOperator(token.PLUS, token.PLUS_ASSIGN)
 	case '-':
-		tok = newToken(token.MINUS, l.character)
+		tok = l.readOperator(token.MINUS, token.MINUS_ASSIGN)
 	case '*':
-		tok = newToken(token.ASTERISK, l.character)
+		tok = l.readOperator(token.ASTERISK, token.ASTERISK_ASSIGN)
 	case '/':
-		tok = newToken(token.SLASH, l.character)
+		tok = l.readOperator(token.SLASH, token.SLASH_ASSIGN)
 	case '%':
-		tok = newToken(token.MODULUS, l.character)
+		tok = l.readOperator(token.MODULUS, token.MODULUS_ASSIGN)
 	case '<':
 		tok = newToken(token.LT, l.character)
 	case '>':
@@ -82,6 +112,34 @@ func (l *Lexer) NextToken() token.Token {
 		tok = newToken(token.RBRACE, l.character)
 	case ';':
 		tok = newToken(token.SEMICOLON, l.character)
+	case ':':
+		tok = newToken(token.COLON, l.character)
+	case '?':
+		if l.peekChar() == '?' {
+			l.readChar()
+			tok = token.Token{Type: token.COALESCE, Literal: "??"}
+		} else {
+			tok = newToken(token.QUESTION, l.character)
+		}
+	case '.':
+		if strings.HasPrefix(l.input[l.position:], "...") {
+			l.readChar()
+			l.readChar()
+			tok = token.Token{Type: token.ELLIPSIS, Literal: "..."}
+		} else {
+			tok = newToken(token.DOT, l.character)
+		}
+	case '[':
+		tok = newToken(token.LBRACKET, l.character)
+	case ']':
+		tok = newToken(token.RBRACKET, l.character)
+	case '"':
+		literal, ok := l.readString()
+		if !ok {
+			// an unterminated string, the lexer is at the end of the input
+			return token.Token{Type: token.ILLEGAL, Literal: literal}
+		}
+		tok = token.Token{Type: token.STRING, Literal: literal}
 	case 0:
 		tok.Literal = ""
 		tok.Type = token.EOF
@@ -102,6 +160,63 @@ func (l *Lexer) NextToken() token.Token {
 	return tok
 }
 
+// readOperator reads an arithmetic operator, which is compound if it is
+// followed by =, like +=
+func (l *Lexer) readOperator(plain, compound token.TokenType) token.Token {
+	if l.peekChar() != '=' {
+		return newToken(plain, l.character)
+	}
+	ch := l.character
+	l.readChar()
+	return token.Token{Type: compound, Literal: string(ch) + string(l.character)}
+}
+
+// reads a comment up to the end of the line and stores it
+func (l *Lexer) readComment() {
+	tok := token.Token{Type: token.COMMENT, Line: l.line, Column: l.column}
+	position := l.position
+	for l.character != '\n' && l.character != 0 {
+		l.readChar()
+	}
+	tok.Literal = strings.TrimRight(l.input[position:l.position], " \t\r")
+	l.comments = append(l.comments, tok)
+}
+
+// reads a string up to the closing quote, which is left as the current
+// character, and returns it with the escapes replaced. ok is false if the
+// input ends first, the literal is then the raw text that was read
+func (l *Lexer) readString() (literal string, ok bool) {
+	position := l.position
+	var out strings.Builder
+	for {
+		l.readChar()
+		switch l.character {
+		case '"':
+			return out.String(), true
+		case 0:
+			return l.input[position:l.position], false
+		case '\\':
+			l.readChar()
+			switch l.character {
+			case 'n':
+				out.WriteByte('\n')
+			case 't':
+				out.WriteByte('\t')
+			case '"', '\\':
+				out.WriteByte(l.character)
+			case 0:
+				return l.input[position:l.position], false
+			default:
+				// unknown escapes are kept as they are
+				out.WriteByte('\\')
+				out.WriteByte(l.character)
+			}
+		default:
+			out.WriteByte(l.character)
+		}
+	}
+}
+
 func (l *Lexer) readIdentifier() string {
 	position := l.position
 	for isLetter(l.character) {
@@ -25,6 +25,17 @@ func TestNextToken(t *testing.T) {
 	
 	10 == 10;
 	10 != 9;
+	"foobar"
+	"foo bar"
+	"a\tb\"c\\"
+	[1, 2];
+	{"foo": "bar"}
+	f()?
+	x = 1; x += 2 -= 3 *= 4 /= 5 %= 6
+	c ? a : null ?? b?
+	match (x) { _ => 1 }
+	let [a, ...b] = c;
+	"a".len().b
 	`
 
 	tests := []struct {
@@ -104,6 +115,73 @@ func TestNextToken(t *testing.T) {
 		{token.NEQ, "!="},
 		{token.INT, "9"},
 		{token.SEMICOLON, ";"},
+		{token.STRING, "foobar"},
+		{token.STRING, "foo bar"},
+		{token.STRING, "a\tb\"c\\"},
+		{token.LBRACKET, "["},
+		{token.INT, "1"},
+		{token.COMMA, ","},
+		{token.INT, "2"},
+		{token.RBRACKET, "]"},
+		{token.SEMICOLON, ";"},
+		{token.LBRACE, "{"},
+		{token.STRING, "foo"},
+		{token.COLON, ":"},
+		{token.STRING, "bar"},
+		{token.RBRACE, "}"},
+		{token.IDENT, "f"},
+		{token.LPAREN, "("},
+		{token.RPAREN, ")"},
+		{token.QUESTION, "?"},
+		{token.IDENT, "x"},
+		{token.ASSIGN, "="},
+		{token.INT, "1"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "x"},
+		{token.PLUS_ASSIGN, "+="},
+		{token.INT, "2"},
+		{token.MINUS_ASSIGN, "-="},
+		{token.INT, "3"},
+		{token.ASTERISK_ASSIGN, "*="},
+		{token.INT, "4"},
+		{token.SLASH_ASSIGN, "/="},
+		{token.INT, "5"},
+		{token.MODULUS_ASSIGN, "%="},
+		{token.INT, "6"},
+		{token.IDENT, "c"},
+		{token.QUESTION, "?"},
+		{token.IDENT, "a"},
+		{token.COLON, ":"},
+		{token.NULL, "null"},
+		{token.COALESCE, "??"},
+		{token.IDENT, "b"},
+		{token.QUESTION, "?"},
+		{token.MATCH, "match"},
+		{token.LPAREN, "("},
+		{token.IDENT, "x"},
+		{token.RPAREN, ")"},
+		{token.LBRACE, "{"},
+		{token.IDENT, "_"},
+		{token.ARROW, "=>"},
+		{token.INT, "1"},
+		{token.RBRACE, "}"},
+		{token.LET, "let"},
+		{token.LBRACKET, "["},
+		{token.IDENT, "a"},
+		{token.COMMA, ","},
+		{token.ELLIPSIS, "..."},
+		{token.IDENT, "b"},
+		{token.RBRACKET, "]"},
+		{token.ASSIGN, "="},
+		{token.IDENT, "c"},
+		{token.SEMICOLON, ";"},
+		{token.STRING, "a"},
+		{token.DOT, "."},
+		{token.IDENT, "len"},
+		{token.LPAREN, "("},
+		{token.RPAREN, ")"},
+		{token.DOT, "."},
+		{token.IDENT, "b"},
 		{token.EOF, ""},
 	}
 	l := New(input)
@@ -120,3 +198,95 @@ func TestNextToken(t *testing.T) {
 		}
 	}
 }
+
+func TestUnterminatedString(t *testing.T) {
+	l := New(`"abc`)
+	tok := l.NextToken()
+	if tok.Type != token.ILLEGAL || tok.Literal != `"abc` {
+		t.Fatalf("token wrong. got=%q %q", tok.Type, tok.Literal)
+	}
+	if tok = l.NextToken(); tok.Type != token.EOF {
+		t.Fatalf("token after the string wrong. got=%q", tok.Type)
+	}
+}
+
+func TestTokenPositions(t *testing.T) {
+	input := `let x = 5;
+  x != 10;`
+
+	tests := []struct {
+		expectedType   token.TokenType
+		expectedLine   int
+		expectedColumn int
+	}{
+		{token.LET, 1, 1},
+		{token.IDENT, 1, 5},
+		{token.ASSIGN, 1, 7},
+		{token.INT, 1, 9},
+		{token.SEMICOLON, 1, 10},
+		{token.IDENT, 2, 3},
+		{token.NEQ, 2, 5},
+		{token.INT, 2, 8},
+		{token.SEMICOLON, 2, 10},
+		{token.EOF, 2, 11},
+	}
+	l := New(input)
+
+	for index, testToken := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != testToken.expectedType {
+			t.Fatalf("tests[%d] - token type wrong. Expected=%q, got=%q", index, testToken.expectedType, tok.Type)
+		}
+
+		if tok.Line != testToken.expectedLine || tok.Column != testToken.expectedColumn {
+			t.Fatalf("tests[%d] - position wrong. Expected=%d:%d, got=%d:%d", index,
+				testToken.expectedLine, testToken.expectedColumn, tok.Line, tok.Column)
+		}
+	}
+}
+
+func TestComments(t *testing.T) {
+	input := `// leading comment
+let x = 5; // trailing comment
+x / 2 //last`
+
+	expectedTypes := []token.TokenType{
+		token.LET, token.IDENT, token.ASSIGN, token.INT, token.SEMICOLON,
+		token.IDENT, token.SLASH, token.INT, token.EOF,
+	}
+	l := New(input)
+	for index, expected := range expectedTypes {
+		tok := l.NextToken()
+		if tok.Type != expected {
+			t.Fatalf("tests[%d] - token type wrong. Expected=%q, got=%q", index, expected, tok.Type)
+		}
+	}
+
+	tests := []struct {
+		expectedLiteral string
+		expectedLine    int
+		expectedColumn  int
+	}{
+		{"// leading comment", 1, 1},
+		{"// trailing comment", 2, 12},
+		{"//last", 3, 7},
+	}
+	comments := l.Comments()
+	if len(comments) != len(tests) {
+		t.Fatalf("wrong number of comments. Expected=%d, got=%d", len(tests), len(comments))
+	}
+	for index, tt := range tests {
+		comment := comments[index]
+		if comment.Type != token.COMMENT {
+			t.Errorf("comments[%d] - type wrong. got=%q", index, comment.Type)
+		}
+		if comment.Literal != tt.expectedLiteral {
+			t.Errorf("comments[%d] - literal wrong. Expected=%q, got=%q", index, tt.expectedLiteral, comment.Literal)
+		}
+		if comment.Line != tt.expectedLine || comment.Column != tt.expectedColumn {
+			t.Errorf("comments[%d] - position wrong. Expected=%d:%d, got=%d:%d", index,
+				tt.expectedLine, tt.expectedColumn, comment.Line, comment.Column)
+		}
+	}
+}
@@ -5,9 +5,36 @@ import (
 	"fmt"
 	"os"
 	"os/user"
+	"sort"
+	"text/tabwriter"
 )
 
+// a subcommand gets the arguments after its name and returns the exit code
+type command struct {
+	run  func(args []string) int
+	args string
+	help string
+}
+
+var commands = map[string]command{
+	"build":  {runBuild, "[--optimize] file [-o output]", "compile a file to bytecode that run loads directly"},
+	"check":  {runCheck, "file ...", "report mistakes in files, like assignments to constants"},
+	"disasm": {runDisasm, "[--optimize] file", "print the bytecode a file compiles to"},
+	"dot":    {runDot, "file", "print the syntax tree of a file as a Graphviz graph"},
+	"fmt":    {runFmt, "[-w] [-d] [file ...]", "format source files"},
+	"parse":  {runParse, "[--json] file", "print the syntax tree of a file"},
+	"run":    {runRun, "[--engine=eval|vm] [--trace] [--optimize] [--max-steps=n] [--timeout=d] [--max-memory=bytes] file", "run a program"},
+}
+
 func main() {
+	if len(os.Args) > 1 {
+		cmd, ok := commands[os.Args[1]]
+		if !ok {
+			printUsage()
+			os.Exit(2)
+		}
+		os.Exit(cmd.run(os.Args[2:]))
+	}
 	user, err := user.Current()
 	if err != nil {
 		panic(err)
@@ -17,3 +44,22 @@ func main() {
 	fmt.Printf("Feel free to type in commands\n")
 	repl.Start(os.Stdin, os.Stdout)
 }
+
+func printUsage() {
+	fmt.Fprintln(os.Stderr, "usage: monkey [command] [arguments]")
+	fmt.Fprintln(os.Stderr, "\nwithout a command monkey starts the REPL. commands:")
+	w := tabwriter.NewWriter(os.Stderr, 0, 0, 2, ' ', 0)
+	for _, name := range commandNames() {
+		fmt.Fprintf(w, "  %s %s\t%s\n", name, commands[name].args, commands[name].help)
+	}
+	w.Flush()
+}
+
+func commandNames() []string {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,79 @@
+package object
+
+// Environment maps names to the values bound to them with let or const.
+// Function calls get a new environment enclosed by the one the function was
+// defined in, so lookups that fail fall back to the outer environment
+type Environment struct {
+	store  map[string]Object
+	consts map[string]bool // the names in store bound with const
+	outer  *Environment
+}
+
+func NewEnvironment() *Environment {
+	s := make(map[string]Object)
+	return &Environment{store: s, outer: nil}
+}
+
+func NewEnclosedEnvironment(outer *Environment) *Environment {
+	env := NewEnvironment()
+	env.outer = outer
+	return env
+}
+
+// Outer is the environment e is enclosed by, nil for the outermost one
+func (e *Environment) Outer() *Environment {
+	return e.outer
+}
+
+// Each calls f with every name bound in e and its value, in no particular
+// order. outer environments aren't looked at
+func (e *Environment) Each(f func(name string, val Object)) {
+	for name, val := range e.store {
+		f(name, val)
+	}
+}
+
+func (e *Environment) Get(name string) (Object, bool) {
+	obj, ok := e.store[name]
+	if !ok && e.outer != nil {
+		obj, ok = e.outer.Get(name)
+	}
+	return obj, ok
+}
+
+func (e *Environment) Set(name string, val Object) Object {
+	e.store[name] = val
+	return val
+}
+
+// SetConst binds name like Set, but the binding can't be changed with
+// Assign afterwards
+func (e *Environment) SetConst(name string, val Object) Object {
+	if e.consts == nil {
+		e.consts = make(map[string]bool)
+	}
+	e.consts[name] = true
+	return e.Set(name, val)
+}
+
+// IsConst reports whether name is bound with const in this environment,
+// outer environments aren't looked at
+func (e *Environment) IsConst(name string) bool {
+	return e.consts[name]
+}
+
+// Assign changes the value of name in the nearest environment that has it
+// bound, which may be an outer one. it reports false if name isn't bound
+// anywhere or the nearest binding is a constant
+func (e *Environment) Assign(name string, val Object) bool {
+	for env := e; env != nil; env = env.outer {
+		if _, ok := env.store[name]; ok {
+			if env.consts[name] {
+				return false
+			}
+			env.store[name] = val
+			return true
+		}
+	}
+	return false
+}
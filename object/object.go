@@ -1,13 +1,33 @@
 package object
 
-import "fmt"
+import (
+	"Go-interpreter/ast"
+	"Go-interpreter/code"
+	"bytes"
+	"fmt"
+	"hash/fnv"
+	"strings"
+)
 
 type ObjectType string
 
 const (
-	INTEGER_OBJ = "INTEGER"
-	BOOLEAN_OBJ = "BOOLEAN"
-	NULL_OBJ    = "NULL"
+	INTEGER_OBJ      = "INTEGER"
+	BOOLEAN_OBJ      = "BOOLEAN"
+	NULL_OBJ         = "NULL"
+	RETURN_VALUE_OBJ = "RETURN_VALUE"
+	ERROR_OBJ        = "ERROR"
+	FUNCTION_OBJ     = "FUNCTION"
+	STRING_OBJ       = "STRING"
+	BUILTIN_OBJ      = "BUILTIN"
+	ARRAY_OBJ        = "ARRAY"
+	HASH_OBJ         = "HASH"
+	RESULT_OBJ       = "RESULT"
+	RANGE_OBJ        = "RANGE"
+	BREAK_OBJ        = "BREAK"
+	CONTINUE_OBJ     = "CONTINUE"
+
+	COMPILED_FUNCTION_OBJ = "COMPILED_FUNCTION"
 )
 
 type Object interface {
@@ -33,3 +53,285 @@ type Null struct{}
 
 func (n *Null) Inspect() string  { return "Null" }
 func (n *Null) Type() ObjectType { return NULL_OBJ }
+
+// wraps the value of a return statement while it's passed up to the
+// function call (or program) it returns from
+type ReturnValue struct {
+	Value Object
+}
+
+func (rv *ReturnValue) Inspect() string  { return rv.Value.Inspect() }
+func (rv *ReturnValue) Type() ObjectType { return RETURN_VALUE_OBJ }
+
+// passed up from a break or continue statement to the loop it is in
+type Break struct{}
+
+func (b *Break) Inspect() string  { return "break" }
+func (b *Break) Type() ObjectType { return BREAK_OBJ }
+
+type Continue struct{}
+
+func (c *Continue) Inspect() string  { return "continue" }
+func (c *Continue) Type() ObjectType { return CONTINUE_OBJ }
+
+// a runtime error, it stops evaluation and is passed all the way up
+type Error struct {
+	Message string
+	Kind    string // one of the kinds below, or what a thrown hash said
+
+	// the calls it went through on its way out, innermost first. only the
+	// innermost ones are kept, Omitted counts the ones left out
+	Stack   []StackFrame
+	Omitted int
+}
+
+func (e *Error) Inspect() string  { return "ERROR: " + e.Message }
+func (e *Error) Type() ObjectType { return ERROR_OBJ }
+
+// kinds of errors. the last three stop the whole evaluation, try can't
+// catch them, see evaluator.Options
+const (
+	RuntimeError = "RuntimeError" // the interpreter found a mistake, like a type mismatch
+	ThrownError  = "Error"        // raised with throw
+
+	CancelledError = "CancelledError" // the context was cancelled
+	LimitError     = "LimitError"     // a step, time or other limit was reached
+	MemoryError    = "MemoryError"    // more memory was in use than allowed
+)
+
+// StackTrace lists the calls in Stack the way Go prints panics, the
+// function on one line and where it was called on the next, indented:
+//
+//	inner(...)
+//		file.mk:2:14
+//	outer(...)
+//		file.mk:5:3
+//
+// the positions are prefixed with file unless it is empty. it returns ""
+// if there are no calls
+func (e *Error) StackTrace(file string) string {
+	var out bytes.Buffer
+	for _, f := range e.Stack {
+		fmt.Fprintf(&out, "%s(...)\n\t", f.Function)
+		if file != "" {
+			out.WriteString(file + ":")
+		}
+		fmt.Fprintf(&out, "%d:%d\n", f.Line, f.Column)
+	}
+	if e.Omitted > 0 {
+		fmt.Fprintf(&out, "...%d more calls...\n", e.Omitted)
+	}
+	return out.String()
+}
+
+// StackFrame is a function call, Line and Column are where it was called
+type StackFrame struct {
+	Function string // its name, or <anonymous>
+	Line     int
+	Column   int
+}
+
+func (f StackFrame) String() string {
+	return fmt.Sprintf("%s at %d:%d", f.Function, f.Line, f.Column)
+}
+
+// a function value, Env is the environment it was defined in so that it
+// can use the bindings around it (a closure)
+type Function struct {
+	Parameters []*ast.Identifier
+	Defaults   []ast.Expression // as in ast.FunctionLiteral
+	Rest       *ast.Identifier
+	Body       *ast.BlockStatement
+	Env        *Environment
+	Name       string // the name it was bound to with let, if any
+}
+
+func (f *Function) Type() ObjectType { return FUNCTION_OBJ }
+func (f *Function) Inspect() string {
+	return FunctionSource(ast.ParameterList(f.Parameters, f.Defaults, f.Rest), f.Body)
+}
+
+// Signature is how the function is shown in errors about how it was
+// called, like f(a, b = 10, ...rest)
+func (f *Function) Signature() string {
+	name := f.Name
+	if name == "" {
+		name = "fn"
+	}
+	return name + "(" + ast.ParameterList(f.Parameters, f.Defaults, f.Rest) + ")"
+}
+
+// FunctionSource is how functions are shown by Inspect
+func FunctionSource(parameters string, body *ast.BlockStatement) string {
+	var out bytes.Buffer
+
+	out.WriteString("fn(")
+	out.WriteString(parameters)
+	out.WriteString(") {\n")
+	out.WriteString(body.String())
+	out.WriteString("\n}")
+
+	return out.String()
+}
+
+// a function compiled to bytecode. it only lives in the constant pool, the
+// vm wraps it in a Closure before it can be called
+type CompiledFunction struct {
+	Instructions  code.Instructions
+	NumLocals     int
+	NumParameters int
+	LocalNames    []string // the name of each local slot, for error messages
+	Name          string   // the name it was bound to with let, if any
+	Source        string   // what Inspect shows, the same as for Function
+	SourceMap     code.SourceMap
+}
+
+func (cf *CompiledFunction) Type() ObjectType { return COMPILED_FUNCTION_OBJ }
+func (cf *CompiledFunction) Inspect() string  { return cf.Source }
+
+// a compiled function together with the free variables it captured. it has
+// the same type as Function so both engines report the same errors
+type Closure struct {
+	Fn   *CompiledFunction
+	Free []Object
+}
+
+func (c *Closure) Type() ObjectType { return FUNCTION_OBJ }
+func (c *Closure) Inspect() string  { return c.Fn.Inspect() }
+
+type String struct {
+	Value string
+}
+
+func (s *String) Type() ObjectType { return STRING_OBJ }
+func (s *String) Inspect() string  { return s.Value }
+
+type BuiltinFunction func(args ...Object) Object
+
+// a function that is part of the interpreter, like len
+type Builtin struct {
+	Name string
+	Fn   BuiltinFunction
+}
+
+func (b *Builtin) Type() ObjectType { return BUILTIN_OBJ }
+func (b *Builtin) Inspect() string  { return "builtin function " + b.Name }
+
+type Array struct {
+	Elements []Object
+}
+
+func (a *Array) Type() ObjectType { return ARRAY_OBJ }
+func (a *Array) Inspect() string {
+	var out bytes.Buffer
+	elements := []string{}
+	for _, e := range a.Elements {
+		elements = append(elements, e.Inspect())
+	}
+	out.WriteString("[")
+	out.WriteString(strings.Join(elements, ", "))
+	out.WriteString("]")
+	return out.String()
+}
+
+// HashKey identifies a value used as a hash key, equal values have equal
+// keys
+type HashKey struct {
+	Type  ObjectType
+	Value uint64
+}
+
+// Hashable is implemented by the values that can be hash keys
+type Hashable interface {
+	HashKey() HashKey
+}
+
+func (b *Boolean) HashKey() HashKey {
+	var value uint64
+	if b.Value {
+		value = 1
+	}
+	return HashKey{Type: b.Type(), Value: value}
+}
+
+func (i *Integer) HashKey() HashKey {
+	return HashKey{Type: i.Type(), Value: uint64(i.Value)}
+}
+
+func (s *String) HashKey() HashKey {
+	h := fnv.New64a()
+	h.Write([]byte(s.Value))
+	return HashKey{Type: s.Type(), Value: h.Sum64()}
+}
+
+// the key is kept next to the value so the hash can be shown and iterated
+type HashPair struct {
+	Key   Object
+	Value Object
+}
+
+// a hash keeps its pairs in the order the keys were first added
+type Hash struct {
+	Pairs map[HashKey]HashPair
+	Keys  []HashKey // in order
+}
+
+func NewHash() *Hash {
+	return &Hash{Pairs: make(map[HashKey]HashPair)}
+}
+
+// Set adds key to the hash or replaces its value
+func (h *Hash) Set(key Hashable, value Object) {
+	hashKey := key.HashKey()
+	if _, ok := h.Pairs[hashKey]; !ok {
+		h.Keys = append(h.Keys, hashKey)
+	}
+	h.Pairs[hashKey] = HashPair{Key: key.(Object), Value: value}
+}
+
+func (h *Hash) Type() ObjectType { return HASH_OBJ }
+func (h *Hash) Inspect() string {
+	var out bytes.Buffer
+	pairs := []string{}
+	for _, key := range h.Keys {
+		pair := h.Pairs[key]
+		pairs = append(pairs, fmt.Sprintf("%s: %s", pair.Key.Inspect(), pair.Value.Inspect()))
+	}
+	out.WriteString("{")
+	out.WriteString(strings.Join(pairs, ", "))
+	out.WriteString("}")
+	return out.String()
+}
+
+// the outcome of something that can fail, made with the ok and err
+// builtins. Value is the result when it worked and the error when not
+type Result struct {
+	Value Object
+	IsErr bool
+}
+
+func (r *Result) Type() ObjectType { return RESULT_OBJ }
+func (r *Result) Inspect() string {
+	if r.IsErr {
+		return "Err(" + r.Value.Inspect() + ")"
+	}
+	return "Ok(" + r.Value.Inspect() + ")"
+}
+
+// the integers from Start up to but not including End, made by the range
+// builtin. they are only created one at a time when a loop needs them
+type Range struct {
+	Start int64
+	End   int64
+}
+
+func (r *Range) Type() ObjectType { return RANGE_OBJ }
+func (r *Range) Inspect() string  { return fmt.Sprintf("range(%d, %d)", r.Start, r.End) }
+
+// Len is how many integers are in the range
+func (r *Range) Len() int64 {
+	if r.End < r.Start {
+		return 0
+	}
+	return r.End - r.Start
+}
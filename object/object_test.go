@@ -0,0 +1,63 @@
+package object
+
+import "testing"
+
+func TestStringHashKey(t *testing.T) {
+	hello1 := &String{Value: "Hello World"}
+	hello2 := &String{Value: "Hello World"}
+	diff1 := &String{Value: "My name is johnny"}
+	diff2 := &String{Value: "My name is johnny"}
+
+	if hello1.HashKey() != hello2.HashKey() {
+		t.Errorf("strings with same content have different hash keys")
+	}
+	if diff1.HashKey() != diff2.HashKey() {
+		t.Errorf("strings with same content have different hash keys")
+	}
+	if hello1.HashKey() == diff1.HashKey() {
+		t.Errorf("strings with different content have same hash keys")
+	}
+}
+
+func TestHashKeepsOrder(t *testing.T) {
+	h := NewHash()
+	h.Set(&String{Value: "b"}, &Integer{Value: 1})
+	h.Set(&Integer{Value: 1}, &Integer{Value: 2})
+	h.Set(&String{Value: "b"}, &Integer{Value: 3})
+	h.Set(&Boolean{Value: true}, &Integer{Value: 4})
+
+	expected := "{b: 3, 1: 2, true: 4}"
+	if h.Inspect() != expected {
+		t.Errorf("Inspect wrong. expected=%q, got=%q", expected, h.Inspect())
+	}
+}
+
+func TestEnvironmentAssign(t *testing.T) {
+	outer := NewEnvironment()
+	outer.Set("x", &Integer{Value: 1})
+	inner := NewEnclosedEnvironment(outer)
+
+	if !inner.Assign("x", &Integer{Value: 2}) {
+		t.Fatalf("assignment to outer binding failed")
+	}
+	if x, _ := outer.Get("x"); x.Inspect() != "2" {
+		t.Errorf("outer binding not changed. got=%s", x.Inspect())
+	}
+	if inner.Assign("y", &Integer{Value: 3}) {
+		t.Errorf("assignment to unbound name succeeded")
+	}
+
+	outer.SetConst("c", &Integer{Value: 1})
+	if inner.Assign("c", &Integer{Value: 2}) {
+		t.Errorf("assignment to constant succeeded")
+	}
+	if c, _ := inner.Get("c"); c.Inspect() != "1" {
+		t.Errorf("constant changed. got=%s", c.Inspect())
+	}
+	if inner.IsConst("c") || !outer.IsConst("c") {
+		t.Errorf("IsConst looks at the wrong environment")
+	}
+	if _, ok := inner.Get("y"); ok {
+		t.Errorf("assignment to unbound name made a binding")
+	}
+}
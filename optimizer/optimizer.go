@@ -0,0 +1,285 @@
+// Package optimizer simplifies a program before it is evaluated or
+// compiled, without changing what it does.
+//
+// It folds prefix and infix expressions whose operands are literals,
+// drops the branch of an if whose condition is a literal and removes
+// double negations where only the truthiness of a value matters.
+// Anything that would fail at runtime, like a division by zero or adding
+// booleans, is left alone so the error still happens when it is run.
+package optimizer
+
+import (
+	"Go-interpreter/ast"
+	"Go-interpreter/token"
+	"strconv"
+)
+
+// Optimize rewrites program in place and returns it. Functions are
+// optimized too, so printing one shows its simplified body
+func Optimize(program *ast.Program) *ast.Program {
+	ast.Modify(program, optimize)
+	return program
+}
+
+// optimize is called bottom up, so the children of node are already as
+// simple as they get
+func optimize(node ast.Node) ast.Node {
+	switch node := node.(type) {
+	case *ast.PrefixExpression:
+		return foldPrefix(node)
+	case *ast.InfixExpression:
+		return foldInfix(node)
+	case *ast.IfExpression:
+		return pruneIf(node)
+	case *ast.ConditionalExpression:
+		node.Condition = stripDoubleBang(node.Condition)
+	case *ast.WhileStatement:
+		node.Condition = stripDoubleBang(node.Condition)
+	case *ast.BlockStatement:
+		node.Statements = inlineIfs(node.Statements)
+	case *ast.Program:
+		node.Statements = inlineIfs(node.Statements)
+	}
+	return node
+}
+
+func foldPrefix(node *ast.PrefixExpression) ast.Expression {
+	switch node.Operator {
+	case "-":
+		if right, ok := node.Right.(*ast.IntegerLiteral); ok {
+			return integer(node, 0-right.Value)
+		}
+	case "!":
+		node.Right = stripDoubleBang(node.Right)
+		if truthy, ok := literalTruthiness(node.Right); ok {
+			return boolean(node, !truthy)
+		}
+		// !!x is x if x is already a boolean
+		if inner, ok := node.Right.(*ast.PrefixExpression); ok && inner.Operator == "!" && isBoolean(inner.Right) {
+			return inner.Right
+		}
+	}
+	return node
+}
+
+func foldInfix(node *ast.InfixExpression) ast.Expression {
+	switch left := node.Left.(type) {
+	case *ast.IntegerLiteral:
+		right, ok := node.Right.(*ast.IntegerLiteral)
+		if !ok {
+			return node
+		}
+		a, b := left.Value, right.Value
+		switch node.Operator {
+		case "+":
+			return integer(node, a+b)
+		case "-":
+			return integer(node, a-b)
+		case "*":
+			return integer(node, a*b)
+		case "/":
+			// dividing by zero has to fail when the program runs
+			if b != 0 {
+				return integer(node, a/b)
+			}
+		case "%":
+			if b != 0 {
+				return integer(node, a%b)
+			}
+		case "<":
+			return boolean(node, a < b)
+		case ">":
+			return boolean(node, a > b)
+		case "==":
+			return boolean(node, a == b)
+		case "!=":
+			return boolean(node, a != b)
+		}
+
+	case *ast.Boolean:
+		right, ok := node.Right.(*ast.Boolean)
+		if !ok {
+			return node
+		}
+		// any other operator on booleans is an error
+		switch node.Operator {
+		case "==":
+			return boolean(node, left.Value == right.Value)
+		case "!=":
+			return boolean(node, left.Value != right.Value)
+		}
+	}
+	return node
+}
+
+// pruneIf drops the branch that can never run. when the branch that
+// always runs is a single expression, the if becomes that expression
+func pruneIf(node *ast.IfExpression) ast.Expression {
+	node.Condition = stripDoubleBang(node.Condition)
+
+	truthy, ok := literalTruthiness(node.Condition)
+	if !ok {
+		return node
+	}
+
+	if truthy {
+		node.Else = nil
+		if exp, ok := singleExpression(node.Then); ok {
+			return exp
+		}
+	} else {
+		if exp, ok := singleExpression(node.Else); ok {
+			return exp
+		}
+		// the then branch is never run, but an if without an else still
+		// needs it
+		node.Then = &ast.BlockStatement{Token: node.Then.Token, Rbrace: node.Then.Rbrace}
+	}
+	return node
+}
+
+// stripDoubleBang turns !!x into x where only the truthiness of the
+// value matters, as in a condition or under another !
+func stripDoubleBang(exp ast.Expression) ast.Expression {
+	for {
+		outer, ok := exp.(*ast.PrefixExpression)
+		if !ok || outer.Operator != "!" {
+			return exp
+		}
+		inner, ok := outer.Right.(*ast.PrefixExpression)
+		if !ok || inner.Operator != "!" {
+			return exp
+		}
+		exp = inner.Right
+	}
+}
+
+// inlineIfs replaces if statements with a literal condition by the
+// statements of the branch that runs. the last statement of a block or
+// program gives it its value, so an if there is only replaced when the
+// branch ends the same way. a branch with a let in it is a scope of its
+// own, so it is left alone as well
+func inlineIfs(stmts []ast.Statement) []ast.Statement {
+	var result []ast.Statement
+	for i, stmt := range stmts {
+		branch, ok := constantBranch(stmt)
+		if !ok {
+			result = append(result, stmt)
+			continue
+		}
+		last := i == len(stmts)-1
+		if last && !endsWithValue(branch) || branch.Declares() {
+			result = append(result, stmt)
+			continue
+		}
+		if branch != nil {
+			result = append(result, branch.Statements...)
+		}
+	}
+	return result
+}
+
+// constantBranch returns the block an if statement with a literal
+// condition always runs, nil if it runs neither
+func constantBranch(stmt ast.Statement) (*ast.BlockStatement, bool) {
+	es, ok := stmt.(*ast.ExpressionStatement)
+	if !ok {
+		return nil, false
+	}
+	ifExp, ok := es.Expression.(*ast.IfExpression)
+	if !ok {
+		return nil, false
+	}
+	truthy, ok := literalTruthiness(ifExp.Condition)
+	if !ok {
+		return nil, false
+	}
+	if truthy {
+		return ifExp.Then, true
+	}
+	return ifExp.Else, true
+}
+
+// endsWithValue reports whether the value of the block is the value of
+// its last statement
+func endsWithValue(block *ast.BlockStatement) bool {
+	if block == nil || len(block.Statements) == 0 {
+		return false
+	}
+	switch block.Statements[len(block.Statements)-1].(type) {
+	case *ast.ExpressionStatement, *ast.ReturnStatement:
+		return true
+	}
+	return false
+}
+
+func singleExpression(block *ast.BlockStatement) (ast.Expression, bool) {
+	if block == nil || len(block.Statements) != 1 {
+		return nil, false
+	}
+	es, ok := block.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		return nil, false
+	}
+	return es.Expression, true
+}
+
+// literalTruthiness says whether exp is a literal and if so whether it
+// counts as true. only false and null are false, every integer is true
+func literalTruthiness(exp ast.Expression) (bool, bool) {
+	switch exp := exp.(type) {
+	case *ast.Boolean:
+		return exp.Value, true
+	case *ast.IntegerLiteral:
+		return true, true
+	case *ast.NullLiteral:
+		return false, true
+	}
+	return false, false
+}
+
+// isBoolean reports whether exp always evaluates to a boolean, if it
+// evaluates at all
+func isBoolean(exp ast.Expression) bool {
+	switch exp := exp.(type) {
+	case *ast.Boolean:
+		return true
+	case *ast.PrefixExpression:
+		return exp.Operator == "!"
+	case *ast.InfixExpression:
+		switch exp.Operator {
+		case "==", "!=", "<", ">":
+			return true
+		}
+	}
+	return false
+}
+
+// the folded literals are placed where the expression they replace starts
+func integer(replaced ast.Expression, value int64) *ast.IntegerLiteral {
+	tok := start(replaced)
+	tok.Type = token.INT
+	tok.Literal = strconv.FormatInt(value, 10)
+	return &ast.IntegerLiteral{Token: tok, Value: value}
+}
+
+func boolean(replaced ast.Expression, value bool) *ast.Boolean {
+	tok := start(replaced)
+	tok.Type = token.FALSE
+	if value {
+		tok.Type = token.TRUE
+	}
+	tok.Literal = strconv.FormatBool(value)
+	return &ast.Boolean{Token: tok, Value: value}
+}
+
+// start returns the first token of an expression
+func start(exp ast.Expression) token.Token {
+	switch exp := exp.(type) {
+	case *ast.InfixExpression:
+		return start(exp.Left)
+	case *ast.CallExpression:
+		return start(exp.Function)
+	}
+	return ast.TokenOf(exp)
+}
@@ -0,0 +1,128 @@
+package optimizer
+
+import (
+	"Go-interpreter/ast"
+	"Go-interpreter/evaluator"
+	"Go-interpreter/lexer"
+	"Go-interpreter/object"
+	"Go-interpreter/parser"
+	"testing"
+)
+
+func parse(t *testing.T, input string) *ast.Program {
+	t.Helper()
+
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+	return program
+}
+
+func TestOptimize(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"60 * 60 * 24", "86400"},
+		{"1 + 2 * 3 - 4", "3"},
+		{"-(2 + 3)", "-5"},
+		{"7 % 4 / 2", "1"},
+		{"1 < 2", "true"},
+		{"2 == 3", "false"},
+		{"true != false", "true"},
+		{"!true", "false"},
+		{"!5", "false"},
+		{"x + 2 * 3", "(x + 6)"},
+		{"fn(a) { a * (4 - 1) }", "fn(a) (a * 3)"},
+
+		// runtime errors stay where they are
+		{"1 / 0", "(1 / 0)"},
+		{"10 % (5 - 5)", "(10 % 0)"},
+		{"true + false", "(true + false)"},
+		{"-true", "(-true)"},
+		{"1 == true", "(1 == true)"},
+
+		// dead branches
+		{"if (true) { 1 } else { 2 }", "1"},
+		{"if (1 > 2) { 1 } else { 2 }", "2"},
+		{"if (0) { x } else { y }", "x"},
+		{"if (false) { 1 }", "iffalse "},
+		{"if (false) { 1 } else { 2; 3 }", "23"},
+		{"if (true) { 1; a }; a", "1aa"},
+		// a branch with a let is a scope of its own, it can't be inlined
+		{"if (false) { let a = 1; a } else { let b = 2; b }", "iffalse else let b = 2;b"},
+		{"if (true) { let a = 1; a }; a", "iftrue let a = 1;aa"},
+		{"if (false) { x }; 5", "5"},
+		{"if (null) { x } else { y }", "y"},
+		{"if (true) { let a = 1; }", "iftrue let a = 1;"},
+		{"fn() { if (true) { let a = 1; return a; } }", "fn() iftrue let a = 1;return a;"},
+		{"fn() { if (true) { a = 1; return a; } }", "fn() (a = 1)return a;"},
+
+		// double negation
+		{"if (!!x) { 1 } else { 2 }", "ifx 1else 2"},
+		{"if (!!!!x) { 1 }", "ifx 1"},
+		{"!!(a < b)", "(a < b)"},
+		{"!!!x", "(!x)"},
+		{"!!x", "(!(!x))"},
+		{"if (!!(2 > 1)) { y }", "y"},
+		{"while (!!x) { 1 }", "whilex 1"},
+		{"!!x ? 1 : 2", "(x ? 1 : 2)"},
+		{"!!!!x ? 1 : 2", "(x ? 1 : 2)"},
+		{"!(!!x)", "(!x)"},
+		{"!!!!!x", "(!x)"},
+		{"!!!!x", "(!(!x))"},
+	}
+
+	for _, tt := range tests {
+		program := Optimize(parse(t, tt.input))
+		if program.String() != tt.expected {
+			t.Errorf("%q: want=%q, got=%q", tt.input, tt.expected, program.String())
+		}
+	}
+}
+
+func eval(program *ast.Program) string {
+	result := evaluator.Eval(program, object.NewEnvironment())
+	if result == nil {
+		return "<none>"
+	}
+	return result.Inspect()
+}
+
+// optimized programs must give exactly the same results and errors
+func TestOptimizePreservesBehaviour(t *testing.T) {
+	inputs := []string{
+		"60 * 60 * 24",
+		"let f = fn(x) { x / (2 - 2) }; f(1)",
+		"1 / 0",
+		"5 % 0; 3",
+		"true * false",
+		"-(true == false)",
+		"if (true) { let a = 1; }",
+		"if (false) { 1 }",
+		"if (true) { 1; return 2; 3 }; 4",
+		"let x = 10; if (!!x) { x } else { 0 }",
+		"let x = 10; !!x",
+		"let x = 10; !!!x",
+		"let x = 0; !!!!x",
+		"let x = 10; !!x ? 1 : 2",
+		"let x = [1]; let i = 0; while (!!x) { i += 1; if (i == 3) { x = null; } }; i",
+		"if (2 > 1) { if (false) { 1 } else { let y = 2; y * 3 } }",
+		"let f = fn() { if (true) { return 1; } 2 }; f()",
+		"if (true) { let z = 5; }; z",
+		"let z = 1; if (true) { let z = 5; }; z",
+		"let z = 1; if (true) { z = 5; }; z",
+		"if (false) { 1 }; missing",
+		"!(1 < 2) == false",
+	}
+
+	for _, input := range inputs {
+		want := eval(parse(t, input))
+		got := eval(Optimize(parse(t, input)))
+		if got != want {
+			t.Errorf("%q: before=%q, after=%q", input, want, got)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+)
+
+// runParse prints the AST of a file, either in the debug form of String()
+// or as JSON with --json
+func runParse(args []string) int {
+	flags := flag.NewFlagSet("parse", flag.ContinueOnError)
+	asJSON := flags.Bool("json", false, "print the AST as JSON")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: monkey parse [--json] file")
+		return 2
+	}
+
+	program, err := parseSource(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "parse: %s\n", err)
+		return 1
+	}
+	if !*asJSON {
+		fmt.Println(program.String())
+		return 0
+	}
+	data, err := json.MarshalIndent(program, "", "  ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "parse: %s\n", err)
+		return 1
+	}
+	os.Stdout.Write(data)
+	os.Stdout.Write([]byte("\n"))
+	return 0
+}
@@ -16,36 +16,63 @@ type (
 const (
 	_ int = iota
 	LOWEST
+	ASSIGN      // = or +=
+	CONDITIONAL // a ? b : c
+	COALESCE    // a ?? b
 	EQUALS      // ==
 	LESSGREATER // < OR >
 	SUM         // +
 	PRODUCT     // *
 	PREFIX      // -X OR !X
 	CALL        // function call
+	INDEX       // array[index]
 )
 
 var precedences = map[token.TokenType]int{
-	token.EQ:       EQUALS,
-	token.NEQ:      EQUALS,
-	token.LT:       LESSGREATER,
-	token.GT:       LESSGREATER,
-	token.MODULUS:  PRODUCT,
-	token.PLUS:     SUM,
-	token.MINUS:    SUM,
-	token.SLASH:    PRODUCT,
-	token.ASTERISK: PRODUCT,
-	token.LPAREN:   CALL,
+	token.ASSIGN:          ASSIGN,
+	token.PLUS_ASSIGN:     ASSIGN,
+	token.MINUS_ASSIGN:    ASSIGN,
+	token.ASTERISK_ASSIGN: ASSIGN,
+	token.SLASH_ASSIGN:    ASSIGN,
+	token.MODULUS_ASSIGN:  ASSIGN,
+	token.CONDITIONAL:     CONDITIONAL,
+	token.COALESCE:        COALESCE,
+	token.EQ:              EQUALS,
+	token.NEQ:             EQUALS,
+	token.LT:              LESSGREATER,
+	token.GT:              LESSGREATER,
+	token.MODULUS:         PRODUCT,
+	token.PLUS:            SUM,
+	token.MINUS:           SUM,
+	token.SLASH:           PRODUCT,
+	token.ASTERISK:        PRODUCT,
+	token.LPAREN:          CALL,
+	token.QUESTION:        CALL,
+	token.DOT:             CALL,
+	token.LBRACKET:        INDEX,
+}
+
+// Precedence returns how tightly an infix operator binds, or LOWEST if the
+// token isn't an infix operator
+func Precedence(t token.TokenType) int {
+	if p, ok := precedences[t]; ok {
+		return p
+	}
+	return LOWEST
 }
 
 type Parser struct {
 	l         *lexer.Lexer
-	curToken  token.Token // the current token that we're looking at
-	peekToken token.Token // the next token that we're looking at
+	curToken  token.Token   // the current token that we're looking at
+	peekToken token.Token   // the next token that we're looking at
+	ahead     []token.Token // tokens after peekToken read to tell what a ? is
 
 	// maps from the type of token to the function used to parse that token
 	prefixParseFns map[token.TokenType]prefixParseFn // used for prefixes
 	infixParseFns  map[token.TokenType]infixParseFn  // used for infixes
 	errors         []string
+
+	loopDepth int // how many loops the current statement is in, within its function
 }
 
 func New(l *lexer.Lexer) *Parser {
@@ -53,10 +80,6 @@ func New(l *lexer.Lexer) *Parser {
 		l:      l,
 		errors: []string{},
 	}
-	// read two tokens so curToken and peekToken are both set
-	p.nextToken()
-	p.nextToken()
-
 	p.prefixParseFns = make(map[token.TokenType]prefixParseFn)
 	p.registerPrefix(token.IDENT, p.parseIdentifier)
 	p.registerPrefix(token.INT, p.parseIntegerLiteral)
@@ -64,9 +87,15 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerPrefix(token.MINUS, p.parsePrefixExpression)
 	p.registerPrefix(token.TRUE, p.parseBoolean)
 	p.registerPrefix(token.FALSE, p.parseBoolean)
+	p.registerPrefix(token.NULL, p.parseNullLiteral)
 	p.registerPrefix(token.LPAREN, p.parseGroupedExpression)
 	p.registerPrefix(token.IF, p.parseIfExpression)
 	p.registerPrefix(token.FUNCTION, p.parseFunctionLiteral)
+	p.registerPrefix(token.STRING, p.parseStringLiteral)
+	p.registerPrefix(token.LBRACKET, p.parseArrayLiteral)
+	p.registerPrefix(token.LBRACE, p.parseHashLiteral)
+	p.registerPrefix(token.TRY, p.parseTryExpression)
+	p.registerPrefix(token.MATCH, p.parseMatchExpression)
 
 	p.infixParseFns = make(map[token.TokenType]infixParseFn)
 	p.registerInfix(token.MODULUS, p.parseInfixExpression)
@@ -78,7 +107,21 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerInfix(token.NEQ, p.parseInfixExpression)
 	p.registerInfix(token.LT, p.parseInfixExpression)
 	p.registerInfix(token.GT, p.parseInfixExpression)
+	p.registerInfix(token.COALESCE, p.parseInfixExpression)
+	p.registerInfix(token.CONDITIONAL, p.parseConditionalExpression)
 	p.registerInfix(token.LPAREN, p.parseCallExpression)
+	p.registerInfix(token.LBRACKET, p.parseIndexExpression)
+	p.registerInfix(token.DOT, p.parseMemberExpression)
+	p.registerInfix(token.QUESTION, p.parsePropagateExpression)
+	for _, op := range []token.TokenType{token.ASSIGN, token.PLUS_ASSIGN, token.MINUS_ASSIGN,
+		token.ASTERISK_ASSIGN, token.SLASH_ASSIGN, token.MODULUS_ASSIGN} {
+		p.registerInfix(op, p.parseAssignExpression)
+	}
+
+	// read two tokens so curToken and peekToken are both set. the parse
+	// functions have to be known first to tell what a ? is
+	p.nextToken()
+	p.nextToken()
 
 	return p
 }
@@ -103,7 +146,64 @@ func (p *Parser) peekError(t token.TokenType) {
 
 func (p *Parser) nextToken() {
 	p.curToken = p.peekToken
-	p.peekToken = p.l.NextToken()
+	if len(p.ahead) > 0 {
+		p.peekToken = p.ahead[0]
+		p.ahead = p.ahead[1:]
+	} else {
+		p.peekToken = p.l.NextToken()
+	}
+	if p.peekTokenIs(token.QUESTION) && p.startsConditional() {
+		p.peekToken.Type = token.CONDITIONAL
+	}
+}
+
+// lookAhead returns the token i places after peekToken, starting at 0
+func (p *Parser) lookAhead(i int) token.Token {
+	for len(p.ahead) <= i {
+		p.ahead = append(p.ahead, p.l.NextToken())
+	}
+	return p.ahead[i]
+}
+
+// startsConditional reports whether the ? in peekToken is the one of a
+// conditional expression. it is if an expression follows and then a :
+// at the same level that no other ? in between takes, like in c ? a : b
+// or c?a:b. otherwise it is the postfix operator, as in f()? or in
+// f()? ? a : b
+func (p *Parser) startsConditional() bool {
+	if p.prefixParseFns[p.lookAhead(0).Type] == nil {
+		return false
+	}
+	depth := 0
+	open := 1 // the ?s still waiting for their :
+	for i := 0; ; i++ {
+		switch tok := p.lookAhead(i); tok.Type {
+		case token.LPAREN, token.LBRACKET, token.LBRACE:
+			depth++
+		case token.RPAREN, token.RBRACKET, token.RBRACE:
+			if depth == 0 {
+				return false
+			}
+			depth--
+		case token.SEMICOLON, token.COMMA:
+			if depth == 0 {
+				return false
+			}
+		case token.EOF:
+			return false
+		case token.QUESTION:
+			if depth == 0 && p.prefixParseFns[p.lookAhead(i+1).Type] != nil {
+				open++
+			}
+		case token.COLON:
+			if depth == 0 {
+				open--
+				if open == 0 {
+					return true
+				}
+			}
+		}
+	}
 }
 
 func (p *Parser) curTokenIs(t token.TokenType) bool {
@@ -127,19 +227,11 @@ func (p *Parser) expectPeek(t token.TokenType) bool {
 }
 
 func (p *Parser) peekPrecedence() int {
-	// if the type exists in precedences, return the precedence
-	if p, ok := precedences[p.peekToken.Type]; ok {
-		return p
-	}
-	// otherwise, we default to the lowest precedene
-	return LOWEST
+	return Precedence(p.peekToken.Type)
 }
 
 func (p *Parser) curPrecedence() int {
-	if p, ok := precedences[p.curToken.Type]; ok {
-		return p
-	}
-	return LOWEST
+	return Precedence(p.curToken.Type)
 }
 
 func (p *Parser) ParseProgram() *ast.Program {
@@ -156,15 +248,28 @@ func (p *Parser) ParseProgram() *ast.Program {
 		}
 		p.nextToken()
 	}
+	for _, comment := range p.l.Comments() {
+		program.Comments = append(program.Comments, &ast.Comment{Token: comment})
+	}
 	return program
 }
 
 func (p *Parser) parseStatement() ast.Statement {
 	switch p.curToken.Type {
-	case token.LET:
+	case token.LET, token.CONST:
 		return p.parseLetStatement()
 	case token.RETURN:
 		return p.parseReturnStatment()
+	case token.THROW:
+		return p.parseThrowStatement()
+	case token.WHILE:
+		return p.parseWhileStatement()
+	case token.FOR:
+		return p.parseForStatement()
+	case token.BREAK:
+		return p.parseBreakStatement()
+	case token.CONTINUE:
+		return p.parseContinueStatement()
 	default:
 		return p.parseExpressionStatement()
 	}
@@ -192,15 +297,32 @@ func (p *Parser) parseBoolean() ast.Expression {
 	return &ast.Boolean{Token: p.curToken, Value: p.curTokenIs(token.TRUE)}
 }
 
+func (p *Parser) parseNullLiteral() ast.Expression {
+	return &ast.NullLiteral{Token: p.curToken}
+}
+
+func (p *Parser) parseStringLiteral() ast.Expression {
+	return &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
+}
+
+// const declarations are parsed the same way, only the token differs. the
+// name can also be an array or hash pattern, let [a, b] = pair;
 func (p *Parser) parseLetStatement() *ast.LetStatement {
 	stmt := &ast.LetStatement{Token: p.curToken}
-	// if the next token is not an identifier, we return nil
-	// if it is, this moves the current token to the identifier token
-	if !p.expectPeek(token.IDENT) {
-		return nil
+	if p.peekTokenIs(token.LBRACKET) || p.peekTokenIs(token.LBRACE) {
+		p.nextToken()
+		if stmt.Name = p.parsePattern(); stmt.Name == nil {
+			return nil
+		}
+	} else {
+		// if the next token is not an identifier, we return nil
+		// if it is, this moves the current token to the identifier token
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		// we then assign the identifier value to the current token's value
+		stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 	}
-	// we then assign the identifier value to the current token's value
-	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 	// if the next token is not an assignment operator, we return nil
 	if !p.expectPeek(token.ASSIGN) {
 		return nil
@@ -228,6 +350,19 @@ func (p *Parser) parseReturnStatment() *ast.ReturnStatement {
 	return stmt
 }
 
+func (p *Parser) parseThrowStatement() *ast.ThrowStatement {
+	stmt := &ast.ThrowStatement{Token: p.curToken}
+
+	p.nextToken()
+
+	stmt.Value = p.parseExpression(LOWEST)
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
 func (p *Parser) parseExpression(precedence int) ast.Expression {
 	// the prefix if there is any
 	prefix := p.prefixParseFns[p.curToken.Type]
@@ -340,6 +475,7 @@ func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 		}
 		p.nextToken()
 	}
+	block.Rbrace = p.curToken
 	return block
 }
 
@@ -359,68 +495,523 @@ func (p *Parser) parseFunctionLiteral() ast.Expression {
 		return nil
 	}
 
-	lit.Parameters = p.parseFunctionParameters()
+	if !p.parseFunctionParameters(lit) {
+		return nil
+	}
 
 	if !p.expectPeek(token.LBRACE) {
 		return nil
 	}
 
+	// loops around the function don't count inside it
+	loopDepth := p.loopDepth
+	p.loopDepth = 0
 	lit.Body = p.parseBlockStatement()
+	p.loopDepth = loopDepth
 
 	return lit
 }
 
-func (p *Parser) parseFunctionParameters() []*ast.Identifier {
-	identifiers := []*ast.Identifier{}
+// parses the parameters of a function literal, names that can have a
+// default value, name = value, and at the end a ...rest. parameters with
+// a default come after those without
+func (p *Parser) parseFunctionParameters(lit *ast.FunctionLiteral) bool {
+	lit.Parameters = []*ast.Identifier{}
+	defaults := []ast.Expression{}
+	hasDefaults := false
+
+	for !p.peekTokenIs(token.RPAREN) {
+		if lit.Rest != nil {
+			p.errors = append(p.errors, "...rest must be the last parameter")
+			return false
+		}
+		if p.peekTokenIs(token.ELLIPSIS) {
+			p.nextToken()
+			if !p.expectPeek(token.IDENT) {
+				return false
+			}
+			lit.Rest = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+		} else {
+			if !p.expectPeek(token.IDENT) {
+				return false
+			}
+			ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+			var def ast.Expression
+			if p.peekTokenIs(token.ASSIGN) {
+				p.nextToken()
+				p.nextToken()
+				def = p.parseExpression(LOWEST)
+				hasDefaults = true
+			} else if hasDefaults {
+				p.errors = append(p.errors, fmt.Sprintf("parameter %s without a default after one with a default", ident))
+				return false
+			}
+			lit.Parameters = append(lit.Parameters, ident)
+			defaults = append(defaults, def)
+		}
+
+		if !p.peekTokenIs(token.RPAREN) && !p.expectPeek(token.COMMA) {
+			return false
+		}
+	}
+	if hasDefaults {
+		lit.Defaults = defaults
+	}
+	return p.expectPeek(token.RPAREN)
+}
+
+func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
+	exp := &ast.CallExpression{Token: p.curToken, Function: function}
+	exp.Arguments = p.parseCallArguments()
+	return exp
+}
+
+// parses the arguments of a call, which can also be ...array to pass the
+// elements of an array and name: value to pass a value by the name of the
+// parameter. named arguments come last
+func (p *Parser) parseCallArguments() []ast.Expression {
+	args := []ast.Expression{}
+	named := false
 
-	if p.peekTokenIs(token.RPAREN) {
+	for !p.peekTokenIs(token.RPAREN) {
 		p.nextToken()
-		return identifiers
+		var arg ast.Expression
+		switch {
+		case p.curTokenIs(token.ELLIPSIS):
+			spread := &ast.SpreadExpression{Token: p.curToken}
+			p.nextToken()
+			spread.Value = p.parseExpression(LOWEST)
+			arg = spread
+		case p.curTokenIs(token.IDENT) && p.peekTokenIs(token.COLON):
+			na := &ast.NamedArgument{Token: p.curToken, Name: &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}}
+			p.nextToken()
+			p.nextToken()
+			na.Value = p.parseExpression(LOWEST)
+			arg = na
+			named = true
+		default:
+			arg = p.parseExpression(LOWEST)
+		}
+		if _, ok := arg.(*ast.NamedArgument); !ok && named && arg != nil {
+			p.errors = append(p.errors, fmt.Sprintf("argument %s after a named argument", arg))
+			return nil
+		}
+		args = append(args, arg)
+
+		if !p.peekTokenIs(token.RPAREN) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+	if !p.expectPeek(token.RPAREN) {
+		return nil
 	}
+	return args
+}
 
+// parses comma separated expressions up to the end token, for array
+// elements
+func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
+	list := []ast.Expression{}
+	// if the list is empty
+	if p.peekTokenIs(end) {
+		p.nextToken()
+		return list
+	}
+	// move to first expression
 	p.nextToken()
-
-	ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
-	identifiers = append(identifiers, ident)
+	list = append(list, p.parseExpression(LOWEST))
 
 	for p.peekTokenIs(token.COMMA) {
 		p.nextToken()
 		p.nextToken()
-		ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
-		identifiers = append(identifiers, ident)
+		list = append(list, p.parseExpression(LOWEST))
+	}
+	if !p.expectPeek(end) {
+		return nil
 	}
+	return list
+}
 
-	if !p.expectPeek(token.RPAREN) {
+func (p *Parser) parseArrayLiteral() ast.Expression {
+	array := &ast.ArrayLiteral{Token: p.curToken}
+	array.Elements = p.parseExpressionList(token.RBRACKET)
+	return array
+}
+
+func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
+	exp := &ast.IndexExpression{Token: p.curToken, Left: left}
+
+	p.nextToken()
+	exp.Index = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.RBRACKET) {
 		return nil
 	}
+	return exp
+}
+
+func (p *Parser) parseMemberExpression(left ast.Expression) ast.Expression {
+	exp := &ast.MemberExpression{Token: p.curToken, Object: left}
 
-	return identifiers
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	exp.Property = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	return exp
 }
 
-func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
-	exp := &ast.CallExpression{Token: p.curToken, Function: function}
-	exp.Arguments = p.parseCallArguments()
+// like assignments, conditional expressions are right associative, so
+// a ? b : c ? d : e is a ? b : (c ? d : e)
+func (p *Parser) parseConditionalExpression(condition ast.Expression) ast.Expression {
+	exp := &ast.ConditionalExpression{Token: p.curToken, Condition: condition}
+
+	p.nextToken()
+	exp.Consequence = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.COLON) {
+		return nil
+	}
+	p.nextToken()
+	exp.Alternative = p.parseExpression(CONDITIONAL - 1)
 	return exp
 }
 
-func (p *Parser) parseCallArguments() []ast.Expression {
-	args := []ast.Expression{}
-	// if empty args list
-	if p.peekTokenIs(token.RPAREN) {
+// assignments are right associative, a = b = 1 sets b first. only names,
+// index expressions and fields can be assigned to
+func (p *Parser) parseAssignExpression(target ast.Expression) ast.Expression {
+	exp := &ast.AssignExpression{Token: p.curToken, Target: target, Operator: p.curToken.Literal}
+
+	switch target.(type) {
+	case *ast.Identifier, *ast.IndexExpression, *ast.MemberExpression:
+	default:
+		p.errors = append(p.errors, fmt.Sprintf("cannot assign to %s", target))
+	}
+
+	p.nextToken()
+	exp.Value = p.parseExpression(ASSIGN - 1)
+	return exp
+}
+
+// the ? operator is postfix, there is nothing after it to parse
+func (p *Parser) parsePropagateExpression(left ast.Expression) ast.Expression {
+	return &ast.PropagateExpression{Token: p.curToken, Left: left}
+}
+
+func (p *Parser) parseHashLiteral() ast.Expression {
+	hash := &ast.HashLiteral{Token: p.curToken, Pairs: []ast.HashPair{}}
+
+	for !p.peekTokenIs(token.RBRACE) {
+		p.nextToken()
+		key := p.parseExpression(LOWEST)
+
+		if !p.expectPeek(token.COLON) {
+			return nil
+		}
 		p.nextToken()
-		return args
+		value := p.parseExpression(LOWEST)
+		hash.Pairs = append(hash.Pairs, ast.HashPair{Key: key, Value: value})
+
+		// the pairs are separated by commas
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+	if !p.expectPeek(token.RBRACE) {
+		return nil
+	}
+	return hash
+}
+
+func (p *Parser) parseTryExpression() ast.Expression {
+	expression := &ast.TryExpression{Token: p.curToken}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	expression.Block = p.parseBlockStatement()
+
+	if p.peekTokenIs(token.CATCH) {
+		p.nextToken()
+		if !p.expectPeek(token.LPAREN) {
+			return nil
+		}
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		expression.Parameter = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+		if !p.expectPeek(token.RPAREN) {
+			return nil
+		}
+		if !p.expectPeek(token.LBRACE) {
+			return nil
+		}
+		expression.Catch = p.parseBlockStatement()
+	}
+
+	if p.peekTokenIs(token.FINALLY) {
+		p.nextToken()
+		if !p.expectPeek(token.LBRACE) {
+			return nil
+		}
+		expression.Finally = p.parseBlockStatement()
+	}
+
+	if expression.Catch == nil && expression.Finally == nil {
+		p.errors = append(p.errors, "expected catch or finally after try block")
+		return nil
+	}
+	return expression
+}
+
+func (p *Parser) parseMatchExpression() ast.Expression {
+	expression := &ast.MatchExpression{Token: p.curToken, Arms: []*ast.MatchArm{}}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
 	}
-	// move to first expression
 	p.nextToken()
-	args = append(args, p.parseExpression(LOWEST))
+	expression.Value = p.parseExpression(LOWEST)
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
 
-	for p.peekTokenIs(token.COMMA) {
+	for !p.peekTokenIs(token.RBRACE) {
+		p.nextToken()
+		arm := p.parseMatchArm()
+		if arm == nil {
+			return nil
+		}
+		expression.Arms = append(expression.Arms, arm)
+
+		// the arms are separated by commas, which can be left out after a
+		// block
+		_, block := arm.Body.(*ast.BlockStatement)
+		if p.peekTokenIs(token.COMMA) {
+			p.nextToken()
+		} else if !block && !p.peekTokenIs(token.RBRACE) {
+			p.peekError(token.COMMA)
+			return nil
+		}
+	}
+	if !p.expectPeek(token.RBRACE) {
+		return nil
+	}
+	expression.Rbrace = p.curToken
+	return expression
+}
+
+// parses pattern if guard => body. a { after the => starts a block, a hash
+// literal as the result needs parentheses
+func (p *Parser) parseMatchArm() *ast.MatchArm {
+	arm := &ast.MatchArm{Pattern: p.parsePattern()}
+	if arm.Pattern == nil {
+		return nil
+	}
+	if p.peekTokenIs(token.IF) {
+		p.nextToken()
+		p.nextToken()
+		arm.Guard = p.parseExpression(LOWEST)
+	}
+	if !p.expectPeek(token.ARROW) {
+		return nil
+	}
+	p.nextToken()
+	if p.curTokenIs(token.LBRACE) {
+		arm.Body = p.parseBlockStatement()
+	} else {
+		stmt := &ast.ExpressionStatement{Token: p.curToken}
+		stmt.Expression = p.parseExpression(LOWEST)
+		arm.Body = stmt
+	}
+	return arm
+}
+
+// parsePattern parses the pattern starting at the current token, see
+// ast.MatchArm for what they can be
+func (p *Parser) parsePattern() ast.Expression {
+	switch p.curToken.Type {
+	case token.IDENT:
+		return p.parseIdentifier()
+	case token.INT, token.STRING, token.TRUE, token.FALSE, token.NULL:
+		return p.prefixParseFns[p.curToken.Type]()
+	case token.MINUS:
+		if !p.peekTokenIs(token.INT) {
+			break
+		}
+		expression := &ast.PrefixExpression{Token: p.curToken, Operator: p.curToken.Literal}
 		p.nextToken()
+		expression.Right = p.parseIntegerLiteral()
+		return expression
+	case token.LBRACKET:
+		return p.parseArrayPattern()
+	case token.LBRACE:
+		return p.parseHashPattern()
+	}
+	p.errors = append(p.errors, fmt.Sprintf("expected a pattern, got %s instead", p.curToken.Type))
+	return nil
+}
+
+func (p *Parser) parseArrayPattern() ast.Expression {
+	array := &ast.ArrayLiteral{Token: p.curToken, Elements: []ast.Expression{}}
+
+	for !p.peekTokenIs(token.RBRACKET) {
 		p.nextToken()
-		args = append(args, p.parseExpression(LOWEST))
+		var element ast.Expression
+		if p.curTokenIs(token.ELLIPSIS) {
+			element = p.parseRestPattern()
+		} else {
+			element = p.parsePattern()
+		}
+		if element == nil {
+			return nil
+		}
+		array.Elements = append(array.Elements, element)
+
+		if !p.peekTokenIs(token.RBRACKET) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
 	}
+	if !p.expectPeek(token.RBRACKET) {
+		return nil
+	}
+	return array
+}
+
+// ...rest, which has to end the array pattern it is in
+func (p *Parser) parseRestPattern() ast.Expression {
+	rest := &ast.SpreadExpression{Token: p.curToken}
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	rest.Value = p.parseIdentifier()
+	if !p.peekTokenIs(token.RBRACKET) {
+		p.errors = append(p.errors, "...rest must be the last element of an array pattern")
+		return nil
+	}
+	return rest
+}
+
+// the keys of hash patterns are literals, the values patterns. a name as
+// the key is a string, {name: n} matches the "name" key and {name} is short
+// for {name: name}
+func (p *Parser) parseHashPattern() ast.Expression {
+	hash := &ast.HashLiteral{Token: p.curToken, Pairs: []ast.HashPair{}}
+
+	for !p.peekTokenIs(token.RBRACE) {
+		p.nextToken()
+		var key ast.Expression
+		switch p.curToken.Type {
+		case token.INT, token.STRING, token.TRUE, token.FALSE:
+			key = p.prefixParseFns[p.curToken.Type]()
+		case token.IDENT:
+			key = &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
+		default:
+			p.errors = append(p.errors, fmt.Sprintf("expected a literal hash key, got %s instead", p.curToken.Type))
+			return nil
+		}
+
+		var value ast.Expression
+		if p.curTokenIs(token.IDENT) && !p.peekTokenIs(token.COLON) {
+			value = p.parseIdentifier()
+		} else {
+			if !p.expectPeek(token.COLON) {
+				return nil
+			}
+			p.nextToken()
+			if value = p.parsePattern(); value == nil {
+				return nil
+			}
+		}
+		hash.Pairs = append(hash.Pairs, ast.HashPair{Key: key, Value: value})
+
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+	if !p.expectPeek(token.RBRACE) {
+		return nil
+	}
+	return hash
+}
+
+func (p *Parser) parseWhileStatement() ast.Statement {
+	stmt := &ast.WhileStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+	p.nextToken()
+	stmt.Condition = p.parseExpression(LOWEST)
 	if !p.expectPeek(token.RPAREN) {
 		return nil
 	}
-	return args
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	stmt.Body = p.parseLoopBody()
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+	return stmt
+}
+
+func (p *Parser) parseForStatement() ast.Statement {
+	stmt := &ast.ForStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	stmt.Variable = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	if !p.expectPeek(token.IN) {
+		return nil
+	}
+	p.nextToken()
+	stmt.Iterable = p.parseExpression(LOWEST)
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	stmt.Body = p.parseLoopBody()
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+	return stmt
+}
+
+// parseLoopBody parses the block of a loop, where break and continue can
+// be used
+func (p *Parser) parseLoopBody() *ast.BlockStatement {
+	p.loopDepth++
+	defer func() { p.loopDepth-- }()
+	return p.parseBlockStatement()
+}
+
+func (p *Parser) parseBreakStatement() ast.Statement {
+	stmt := &ast.BreakStatement{Token: p.curToken}
+	if p.loopDepth == 0 {
+		p.errors = append(p.errors, "break outside a loop")
+	}
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+	return stmt
+}
+
+func (p *Parser) parseContinueStatement() ast.Statement {
+	stmt := &ast.ContinueStatement{Token: p.curToken}
+	if p.loopDepth == 0 {
+		p.errors = append(p.errors, "continue outside a loop")
+	}
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+	return stmt
 }
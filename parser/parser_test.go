@@ -47,8 +47,7 @@ func testLetStatement(t *testing.T, s ast.Statement, name string) bool {
 		t.Errorf("s not *ast.LetStatement. got=%T", s)
 		return false
 	}
-	if letStmt.Name.Value != name {
-		t.Errorf("letStmt.Name.Value not '%s'. got=%s", name, letStmt.Name.Value)
+	if !testIdentifier(t, letStmt.Name, name) {
 		return false
 	}
 	if letStmt.Name.TokenLiteral() != name {
@@ -357,6 +356,99 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 			"3 + 4 * 5 == 3 * 1 + 4 * 5",
 			"((3 + (4 * 5)) == ((3 * 1) + (4 * 5)))",
 		},
+		{
+			"a * [1, 2, 3, 4][b * c] * d",
+			"((a * ([1, 2, 3, 4][(b * c)])) * d)",
+		},
+		{
+			"add(a * b[2], b[1], 2 * [1, 2][1])",
+			"add((a * (b[2])), (b[1]), (2 * ([1, 2][1])))",
+		},
+		{
+			"-a[0]",
+			"(-(a[0]))",
+		},
+		{
+			"-f(x)? + 1",
+			"((-(f(x)?)) + 1)",
+		},
+		{
+			"a[0]?(1)?",
+			"(((a[0])?)(1)?)",
+		},
+		{
+			"a == b ? c : d",
+			"((a == b) ? c : d)",
+		},
+		{
+			"a < b ? c + 1 : d * 2",
+			"((a < b) ? (c + 1) : (d * 2))",
+		},
+		{
+			"a ? b : c ? d : e",
+			"(a ? b : (c ? d : e))",
+		},
+		{
+			"a ? b ? c : d : e",
+			"(a ? (b ? c : d) : e)",
+		},
+		{
+			"x = a ? b : c",
+			"(x = (a ? b : c))",
+		},
+		{
+			"f(a ? b : c, d)",
+			"f((a ? b : c), d)",
+		},
+		{
+			"a ?? b == c",
+			"(a ?? (b == c))",
+		},
+		{
+			"a ?? b ?? c",
+			"((a ?? b) ?? c)",
+		},
+		{
+			"a ?? b ? c : d ?? e",
+			"((a ?? b) ? c : (d ?? e))",
+		},
+		{
+			"f()? ? -1 : [2]",
+			"((f()?) ? (-1) : [2])",
+		},
+		{
+			"a? - 1",
+			"((a?) - 1)",
+		},
+		// whitespace doesn't matter, the : does
+		{
+			"true?1:2",
+			"(true ? 1 : 2)",
+		},
+		{
+			"x == 1? 2 : 3",
+			"((x == 1) ? 2 : 3)",
+		},
+		{
+			"f(x) ? + 1",
+			"((f(x)?) + 1)",
+		},
+		{
+			"a? - 1 ? b : c",
+			"(((a?) - 1) ? b : c)",
+		},
+		{
+			"a ? {b: c} : [d ? e : f]",
+			"(a ? {b: c} : [(d ? e : f)])",
+		},
+		{
+			"[a?, b ? c : d]",
+			"[(a?), (b ? c : d)]",
+		},
+		{
+			"x == null",
+			"(x == null)",
+		},
 	}
 	for _, tt := range tests {
 		l := lexer.New(tt.input)
@@ -532,3 +624,531 @@ func TestCallExpressionParsing(t *testing.T) {
 	testInfixExpression(t, exp.Arguments[1], 2, "*", 3)
 	testInfixExpression(t, exp.Arguments[2], 4, "+", 5)
 }
+
+func TestDefaultAndRestParameters(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"fn(a, b = 10, ...rest) {}", "fn(a, b = 10, ...rest) "},
+		{"fn(a = 1 + 2, b = a) {}", "fn(a = (1 + 2), b = a) "},
+		{"fn(...rest) {}", "fn(...rest) "},
+		{"fn(a, b,) {}", "fn(a, b) "},
+		{"f(...xs, 1, ...ys)", "f(...xs, 1, ...ys)"},
+		{"f(1, b: 2, c: x ? y : z)", "f(1, b: 2, c: (x ? y : z))"},
+	}
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+		if program.String() != tt.expected {
+			t.Errorf("%q: expected=%q, got=%q", tt.input, tt.expected, program.String())
+		}
+	}
+
+	function := parseFunction(t, "fn(a, b = 10, ...rest) {}")
+	if function.Default(0) != nil {
+		t.Errorf("parameter a has a default. got=%s", function.Default(0))
+	}
+	testIntegerLiteral(t, function.Default(1), 10)
+	testIdentifier(t, function.Rest, "rest")
+	if function := parseFunction(t, "fn(a, b) {}"); function.Defaults != nil || function.Rest != nil {
+		t.Errorf("function without defaults or rest has them. got=%s", function)
+	}
+
+	errorTests := []struct {
+		input    string
+		expected string
+	}{
+		{"fn(...a, b) {}", "...rest must be the last parameter"},
+		{"fn(a = 1, b) {}", "parameter b without a default after one with a default"},
+		{"fn(1) {}", "expected next token to be IDENT, got INT instead"},
+		{"f(a: 1, 2)", "argument 2 after a named argument"},
+		{"f(a: 1, ...xs)", "argument ...xs after a named argument"},
+	}
+	for _, tt := range errorTests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 || p.Errors()[0] != tt.expected {
+			t.Errorf("%q: wrong errors. want %q first, got=%v", tt.input, tt.expected, p.Errors())
+		}
+	}
+}
+
+func parseFunction(t *testing.T, input string) *ast.FunctionLiteral {
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	function, ok := stmt.Expression.(*ast.FunctionLiteral)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.FunctionLiteral. got=%T", stmt.Expression)
+	}
+	return function
+}
+
+func TestStringLiteralExpression(t *testing.T) {
+	input := `"hello\tworld";`
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	literal, ok := stmt.Expression.(*ast.StringLiteral)
+	if !ok {
+		t.Fatalf("exp not *ast.StringLiteral. got=%T", stmt.Expression)
+	}
+	if literal.Value != "hello\tworld" {
+		t.Errorf("literal.Value not %q. got=%q", "hello\tworld", literal.Value)
+	}
+}
+
+func TestParsingArrayLiterals(t *testing.T) {
+	input := "[1, 2 * 2, 3 + 3]"
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	array, ok := stmt.Expression.(*ast.ArrayLiteral)
+	if !ok {
+		t.Fatalf("exp not ast.ArrayLiteral. got=%T", stmt.Expression)
+	}
+	if len(array.Elements) != 3 {
+		t.Fatalf("len(array.Elements) not 3. got=%d", len(array.Elements))
+	}
+	testIntegerLiteral(t, array.Elements[0], 1)
+	testInfixExpression(t, array.Elements[1], 2, "*", 2)
+	testInfixExpression(t, array.Elements[2], 3, "+", 3)
+}
+
+func TestParsingIndexExpressions(t *testing.T) {
+	input := "myArray[1 + 1]"
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	indexExp, ok := stmt.Expression.(*ast.IndexExpression)
+	if !ok {
+		t.Fatalf("exp not *ast.IndexExpression. got=%T", stmt.Expression)
+	}
+	if !testIdentifier(t, indexExp.Left, "myArray") {
+		return
+	}
+	testInfixExpression(t, indexExp.Index, 1, "+", 1)
+}
+
+func TestParsingMemberExpressions(t *testing.T) {
+	p := New(lexer.New("obj.field"))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	member, ok := stmt.Expression.(*ast.MemberExpression)
+	if !ok {
+		t.Fatalf("exp not *ast.MemberExpression. got=%T", stmt.Expression)
+	}
+	testIdentifier(t, member.Object, "obj")
+	testIdentifier(t, member.Property, "field")
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`"abc".len()`, "(abc.len)()"},
+		{"arr.map(f).filter(g)", "((arr.map)(f).filter)(g)"},
+		{"a.b.c", "((a.b).c)"},
+		{"a.b[0].c", "(((a.b)[0]).c)"},
+		{"-a.b * c.d", "((-(a.b)) * (c.d))"},
+		{"!f().ok", "(!(f().ok))"},
+		{"a.b = a.b + 1", "((a.b) = ((a.b) + 1))"},
+		{"a.b += 1", "((a.b) += 1)"},
+	}
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+		if program.String() != tt.expected {
+			t.Errorf("%q: expected=%q, got=%q", tt.input, tt.expected, program.String())
+		}
+	}
+
+	errorTests := []struct {
+		input    string
+		expected string
+	}{
+		{"a.1", "expected next token to be IDENT, got INT instead"},
+		{"a.(b)", "expected next token to be IDENT, got ( instead"},
+		{"a.", "expected next token to be IDENT, got EOF instead"},
+	}
+	for _, tt := range errorTests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 || p.Errors()[0] != tt.expected {
+			t.Errorf("%q: wrong errors. want %q first, got=%v", tt.input, tt.expected, p.Errors())
+		}
+	}
+}
+
+func TestParsingHashLiterals(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected map[string]int64
+	}{
+		{`{}`, map[string]int64{}},
+		{`{"one": 1, "two": 2, "three": 3}`, map[string]int64{"one": 1, "two": 2, "three": 3}},
+	}
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		hash, ok := stmt.Expression.(*ast.HashLiteral)
+		if !ok {
+			t.Fatalf("exp is not ast.HashLiteral. got=%T", stmt.Expression)
+		}
+		if len(hash.Pairs) != len(tt.expected) {
+			t.Errorf("hash.Pairs has wrong length. got=%d", len(hash.Pairs))
+		}
+		for _, pair := range hash.Pairs {
+			literal, ok := pair.Key.(*ast.StringLiteral)
+			if !ok {
+				t.Errorf("key is not ast.StringLiteral. got=%T", pair.Key)
+				continue
+			}
+			testIntegerLiteral(t, pair.Value, tt.expected[literal.Value])
+		}
+	}
+}
+
+func TestParsingHashLiteralsWithExpressions(t *testing.T) {
+	input := `{"one": 0 + 1, "two": 10 - 8}`
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	hash, ok := stmt.Expression.(*ast.HashLiteral)
+	if !ok {
+		t.Fatalf("exp is not ast.HashLiteral. got=%T", stmt.Expression)
+	}
+	if len(hash.Pairs) != 2 {
+		t.Fatalf("hash.Pairs has wrong length. got=%d", len(hash.Pairs))
+	}
+	testInfixExpression(t, hash.Pairs[0].Value, 0, "+", 1)
+	testInfixExpression(t, hash.Pairs[1].Value, 10, "-", 8)
+}
+
+func TestThrowStatement(t *testing.T) {
+	l := lexer.New(`throw "boom";`)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+	stmt, ok := program.Statements[0].(*ast.ThrowStatement)
+	if !ok {
+		t.Fatalf("stmt not *ast.ThrowStatement. got=%T", program.Statements[0])
+	}
+	if literal, ok := stmt.Value.(*ast.StringLiteral); !ok || literal.Value != "boom" {
+		t.Errorf("stmt.Value wrong. got=%s", stmt.Value)
+	}
+}
+
+func TestTryExpression(t *testing.T) {
+	tests := []struct {
+		input      string
+		parameter  string
+		hasCatch   bool
+		hasFinally bool
+	}{
+		{"try { x } catch (e) { e }", "e", true, false},
+		{"try { x } finally { y }", "", false, true},
+		{"try { x } catch (err) { err } finally { y }", "err", true, true},
+	}
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		exp, ok := stmt.Expression.(*ast.TryExpression)
+		if !ok {
+			t.Fatalf("exp not *ast.TryExpression. got=%T", stmt.Expression)
+		}
+		if len(exp.Block.Statements) != 1 {
+			t.Errorf("try block wrong. got=%s", exp.Block)
+		}
+		if (exp.Catch != nil) != tt.hasCatch || (exp.Finally != nil) != tt.hasFinally {
+			t.Errorf("%q: wrong blocks. catch=%v, finally=%v", tt.input, exp.Catch, exp.Finally)
+		}
+		if tt.hasCatch && !testIdentifier(t, exp.Parameter, tt.parameter) {
+			return
+		}
+	}
+
+	p := New(lexer.New("try { x }"))
+	p.ParseProgram()
+	if len(p.Errors()) == 0 || p.Errors()[0] != "expected catch or finally after try block" {
+		t.Errorf("wrong errors for try without catch or finally. got=%v", p.Errors())
+	}
+}
+
+func TestWhileStatement(t *testing.T) {
+	// a ; after the body is allowed, like after an expression
+	l := lexer.New("while (x < y) { x; break; }; x")
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+	if len(program.Statements) != 2 {
+		t.Fatalf("program has wrong number of statements. got=%d", len(program.Statements))
+	}
+	stmt, ok := program.Statements[0].(*ast.WhileStatement)
+	if !ok {
+		t.Fatalf("stmt not *ast.WhileStatement. got=%T", program.Statements[0])
+	}
+	if !testInfixExpression(t, stmt.Condition, "x", "<", "y") {
+		return
+	}
+	if len(stmt.Body.Statements) != 2 {
+		t.Fatalf("body has wrong number of statements. got=%d", len(stmt.Body.Statements))
+	}
+	if _, ok := stmt.Body.Statements[1].(*ast.BreakStatement); !ok {
+		t.Errorf("statement not *ast.BreakStatement. got=%T", stmt.Body.Statements[1])
+	}
+}
+
+func TestForStatement(t *testing.T) {
+	l := lexer.New("for (x in [1, 2]) { if (x) { continue; } }; x")
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+	if len(program.Statements) != 2 {
+		t.Fatalf("program has wrong number of statements. got=%d", len(program.Statements))
+	}
+	stmt, ok := program.Statements[0].(*ast.ForStatement)
+	if !ok {
+		t.Fatalf("stmt not *ast.ForStatement. got=%T", program.Statements[0])
+	}
+	if !testIdentifier(t, stmt.Variable, "x") {
+		return
+	}
+	if _, ok := stmt.Iterable.(*ast.ArrayLiteral); !ok {
+		t.Errorf("stmt.Iterable not *ast.ArrayLiteral. got=%T", stmt.Iterable)
+	}
+	if len(stmt.Body.Statements) != 1 {
+		t.Fatalf("body has wrong number of statements. got=%d", len(stmt.Body.Statements))
+	}
+}
+
+func TestLoopControlOutsideLoop(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"break;", "break outside a loop"},
+		{"if (x) { continue; }", "continue outside a loop"},
+		{"while (x) { fn() { break; } }", "break outside a loop"},
+	}
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		if len(p.Errors()) != 1 || p.Errors()[0] != tt.expected {
+			t.Errorf("%q: wrong errors. want=%q, got=%v", tt.input, tt.expected, p.Errors())
+		}
+	}
+}
+
+func TestConditionalExpression(t *testing.T) {
+	p := New(lexer.New("x < y ? x : null"))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	exp, ok := stmt.Expression.(*ast.ConditionalExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.ConditionalExpression. got=%T", stmt.Expression)
+	}
+	if !testInfixExpression(t, exp.Condition, "x", "<", "y") {
+		return
+	}
+	if !testIdentifier(t, exp.Consequence, "x") {
+		return
+	}
+	if _, ok := exp.Alternative.(*ast.NullLiteral); !ok {
+		t.Errorf("exp.Alternative is not ast.NullLiteral. got=%T", exp.Alternative)
+	}
+
+	// without a : the ? is the postfix operator, a? and then b
+	p = New(lexer.New("a ? b"))
+	program = p.ParseProgram()
+	checkParserErrors(t, p)
+	if len(program.Statements) != 2 {
+		t.Fatalf("program has wrong number of statements. got=%d", len(program.Statements))
+	}
+	if program.Statements[0].String() != "(a?)" {
+		t.Errorf("first statement wrong. got=%q", program.Statements[0].String())
+	}
+}
+
+func TestMatchExpression(t *testing.T) {
+	input := `match (x) { 1 => "one", [a, _] if a > 0 => { a }, {"k": -2} => null, n => n, }`
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	exp, ok := stmt.Expression.(*ast.MatchExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.MatchExpression. got=%T", stmt.Expression)
+	}
+	if !testIdentifier(t, exp.Value, "x") {
+		return
+	}
+	expected := []string{`1 => one`, `[a, _] if (a > 0) => {a}`, `{k: (-2)} => null`, `n => n`}
+	if len(exp.Arms) != len(expected) {
+		t.Fatalf("wrong number of arms. want=%d, got=%d", len(expected), len(exp.Arms))
+	}
+	for i, arm := range exp.Arms {
+		if arm.String() != expected[i] {
+			t.Errorf("arms[%d] wrong. want=%q, got=%q", i, expected[i], arm.String())
+		}
+	}
+	if _, ok := exp.Arms[1].Body.(*ast.BlockStatement); !ok {
+		t.Errorf("arms[1].Body is not ast.BlockStatement. got=%T", exp.Arms[1].Body)
+	}
+
+	errorTests := []struct {
+		input    string
+		expected string
+	}{
+		{"match (x) { a + 1 => 1 }", "expected next token to be =>, got + instead"},
+		{"match (x) { f(a) => 1 }", "expected next token to be =>, got ( instead"},
+		{"match (x) { 1 => 1 2 => 2 }", "expected next token to be ,, got INT instead"},
+		{`match (x) { {[1]: v} => 1 }`, "expected a literal hash key, got [ instead"},
+		{"match (x) { -a => 1 }", "expected a pattern, got - instead"},
+		{"match x { _ => 1 }", "expected next token to be (, got IDENT instead"},
+	}
+	for _, tt := range errorTests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 || p.Errors()[0] != tt.expected {
+			t.Errorf("%q: wrong errors. want %q first, got=%v", tt.input, tt.expected, p.Errors())
+		}
+	}
+}
+
+func TestDestructuringLetStatement(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let [a, b] = pair;", "let [a, b] = pair;"},
+		{"let [a, ...rest] = xs;", "let [a, ...rest] = xs;"},
+		{"let [] = xs;", "let [] = xs;"},
+		{"let [[a], _] = xs;", "let [[a], _] = xs;"},
+		{"const {name, age} = person;", "const {name: name, age: age} = person;"},
+		{`let {"first name": n, 1: [x]} = h;`, "let {first name: n, 1: [x]} = h;"},
+		{"let {name: n} = person;", "let {name: n} = person;"},
+	}
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+		if len(program.Statements) != 1 {
+			t.Fatalf("%q: wrong number of statements. got=%d", tt.input, len(program.Statements))
+		}
+		if program.String() != tt.expected {
+			t.Errorf("%q: expected=%q, got=%q", tt.input, tt.expected, program.String())
+		}
+	}
+
+	errorTests := []struct {
+		input    string
+		expected string
+	}{
+		{"let [a, ...b, c] = xs;", "...rest must be the last element of an array pattern"},
+		{"let [...[a]] = xs;", "expected next token to be IDENT, got [ instead"},
+		{"let [a + 1] = xs;", "expected next token to be ,, got + instead"},
+		{"let {a b} = h;", "expected next token to be ,, got IDENT instead"},
+		{"let 5 = x;", "expected next token to be IDENT, got INT instead"},
+	}
+	for _, tt := range errorTests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 || p.Errors()[0] != tt.expected {
+			t.Errorf("%q: wrong errors. want %q first, got=%v", tt.input, tt.expected, p.Errors())
+		}
+	}
+}
+
+func TestConstStatement(t *testing.T) {
+	p := New(lexer.New("const answer = 6 * 7; let x = answer;"))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+	if len(program.Statements) != 2 {
+		t.Fatalf("program.Statements does not contain 2 statements. got=%d", len(program.Statements))
+	}
+	stmt, ok := program.Statements[0].(*ast.LetStatement)
+	if !ok {
+		t.Fatalf("stmt not *ast.LetStatement. got=%T", program.Statements[0])
+	}
+	if !stmt.IsConst() || stmt.Name.String() != "answer" {
+		t.Errorf("wrong const statement. got=%s", stmt)
+	}
+	if !testInfixExpression(t, stmt.Value, 6, "*", 7) {
+		return
+	}
+	if program.Statements[1].(*ast.LetStatement).IsConst() {
+		t.Errorf("let statement is const")
+	}
+}
+
+func TestAssignExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"x = 5", "(x = 5)"},
+		{"x += 1 * 2", "(x += (1 * 2))"},
+		{"a = b = c", "(a = (b = c))"},
+		{"a[i] -= 1", "((a[i]) -= 1)"},
+		{`h["k"][0] %= y == z`, `(((h[k])[0]) %= (y == z))`},
+		{"let x = y *= 2;", "let x = (y *= 2);"},
+		{"f(x /= 2)", "f((x /= 2))"},
+	}
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+		if actual := program.String(); actual != tt.expected {
+			t.Errorf("%q: expected=%q, got=%q", tt.input, tt.expected, actual)
+		}
+	}
+
+	p := New(lexer.New("f() = 1"))
+	p.ParseProgram()
+	if len(p.Errors()) != 1 || p.Errors()[0] != "cannot assign to f()" {
+		t.Errorf("wrong errors for assignment to a call. got=%v", p.Errors())
+	}
+}
+
+func TestProgramComments(t *testing.T) {
+	input := `// first
+let x = 5; // second
+if (x) {
+  x
+}`
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+	if len(program.Comments) != 2 {
+		t.Fatalf("program.Comments does not contain 2 comments. got=%d", len(program.Comments))
+	}
+	if program.Comments[0].String() != "// first" || program.Comments[1].String() != "// second" {
+		t.Errorf("comments wrong. got=%q, %q", program.Comments[0], program.Comments[1])
+	}
+	stmt := program.Statements[1].(*ast.ExpressionStatement)
+	exp := stmt.Expression.(*ast.IfExpression)
+	if exp.Then.Rbrace.Literal != "}" || exp.Then.Rbrace.Line != 5 {
+		t.Errorf("Rbrace wrong. got=%+v", exp.Then.Rbrace)
+	}
+}
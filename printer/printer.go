@@ -0,0 +1,528 @@
+// Package printer turns an AST back into canonical Monkey source.
+//
+// Unlike the String() methods in the ast package, which wrap every
+// operation in parentheses for debugging, the printer only adds the
+// parentheses needed to keep the meaning of the program, puts every
+// statement on its own line, indents blocks with tabs and keeps the
+// comments of the original source.
+package printer
+
+import (
+	"Go-interpreter/ast"
+	"Go-interpreter/lexer"
+	"Go-interpreter/parser"
+	"Go-interpreter/token"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// precedence of expressions that never need parentheses, e.g. literals
+const atom = parser.INDEX + 1
+
+type printer struct {
+	out      bytes.Buffer
+	indent   int
+	comments []*ast.Comment // comments that haven't been printed yet
+	lastLine int            // source line of the last token that was printed
+}
+
+// Fprint writes the canonical source for node to w. Comments are only
+// printed when node is an *ast.Program
+func Fprint(w io.Writer, node ast.Node) error {
+	p := &printer{}
+	switch node := node.(type) {
+	case *ast.Program:
+		p.comments = node.Comments
+		p.statements(node.Statements, 0)
+		if p.out.Len() > 0 {
+			p.write("\n")
+		}
+	case ast.Statement:
+		p.statement(node)
+	case ast.Expression:
+		p.expression(node, parser.LOWEST)
+	default:
+		return fmt.Errorf("printer: unsupported node %T", node)
+	}
+	_, err := w.Write(p.out.Bytes())
+	return err
+}
+
+// Format parses src and returns it in canonical form. It fails if src
+// contains syntax errors
+func Format(src []byte) ([]byte, error) {
+	l := lexer.New(string(src))
+	p := parser.New(l)
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, errors.New(strings.Join(p.Errors(), "\n"))
+	}
+	var out bytes.Buffer
+	if err := Fprint(&out, program); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+func (p *printer) write(s string) {
+	p.out.WriteString(s)
+}
+
+// newline ends the current line and indents the next one
+func (p *printer) newline() {
+	p.write("\n")
+	p.write(strings.Repeat("\t", p.indent))
+}
+
+// mark records that the source up to tok has been printed
+func (p *printer) mark(tok token.Token) {
+	if tok.Line > p.lastLine {
+		p.lastLine = tok.Line
+	}
+}
+
+// statements prints a list of statements, one per line, with the comments
+// in front of them. endLine is the line of the closing } for blocks, 0 for
+// the program which flushes every comment left
+func (p *printer) statements(stmts []ast.Statement, endLine int) {
+	// whether anything has been printed for this list yet
+	started := false
+	for _, stmt := range stmts {
+		line := statementLine(stmt)
+		started = p.flushComments(line, started)
+		if started {
+			// keep one empty line where the source had at least one
+			if line > p.lastLine+1 {
+				p.write("\n")
+			}
+			p.newline()
+		}
+		p.statement(stmt)
+		p.trailingComments()
+		started = true
+	}
+	p.flushComments(endLine, started)
+}
+
+// flushComments prints every pending comment that starts before line on its
+// own line, or all of them if line is 0. when started is false the first
+// comment goes on the current line instead of a new one. it returns whether
+// anything has been printed on the current line
+func (p *printer) flushComments(line int, started bool) bool {
+	for len(p.comments) > 0 {
+		comment := p.comments[0]
+		if line > 0 && comment.Token.Line >= line {
+			break
+		}
+		if started {
+			if comment.Token.Line > p.lastLine+1 {
+				p.write("\n")
+			}
+			p.newline()
+		}
+		p.write(comment.Token.Literal)
+		p.mark(comment.Token)
+		p.comments = p.comments[1:]
+		started = true
+	}
+	return started
+}
+
+// trailingComments prints comments that were on the same line as the end
+// of the statement that was just printed
+func (p *printer) trailingComments() {
+	for len(p.comments) > 0 && p.comments[0].Token.Line == p.lastLine && p.lastLine > 0 {
+		p.write(" " + p.comments[0].Token.Literal)
+		p.comments = p.comments[1:]
+	}
+}
+
+// statementLine returns the line a statement starts on
+func statementLine(stmt ast.Statement) int {
+	switch stmt := stmt.(type) {
+	case *ast.ExpressionStatement:
+		return stmt.Token.Line
+	case *ast.LetStatement:
+		return stmt.Token.Line
+	case *ast.ReturnStatement:
+		return stmt.Token.Line
+	case *ast.ThrowStatement:
+		return stmt.Token.Line
+	case *ast.WhileStatement:
+		return stmt.Token.Line
+	case *ast.ForStatement:
+		return stmt.Token.Line
+	case *ast.BreakStatement:
+		return stmt.Token.Line
+	case *ast.ContinueStatement:
+		return stmt.Token.Line
+	case *ast.BlockStatement:
+		return stmt.Token.Line
+	}
+	return 0
+}
+
+func (p *printer) statement(stmt ast.Statement) {
+	switch stmt := stmt.(type) {
+	case *ast.LetStatement:
+		p.mark(stmt.Token)
+		p.write(stmt.Token.Literal + " ")
+		p.pattern(stmt.Name)
+		p.write(" = ")
+		p.expression(stmt.Value, parser.LOWEST)
+		p.write(";")
+	case *ast.ReturnStatement:
+		p.mark(stmt.Token)
+		p.write("return")
+		if stmt.ReturnValue != nil {
+			p.write(" ")
+			p.expression(stmt.ReturnValue, parser.LOWEST)
+		}
+		p.write(";")
+	case *ast.ThrowStatement:
+		p.mark(stmt.Token)
+		p.write("throw ")
+		p.expression(stmt.Value, parser.LOWEST)
+		p.write(";")
+	case *ast.WhileStatement:
+		p.mark(stmt.Token)
+		p.write("while (")
+		p.expression(stmt.Condition, parser.LOWEST)
+		p.write(") ")
+		p.block(stmt.Body)
+	case *ast.ForStatement:
+		p.mark(stmt.Token)
+		p.write("for (")
+		p.expression(stmt.Variable, parser.LOWEST)
+		p.write(" in ")
+		p.expression(stmt.Iterable, parser.LOWEST)
+		p.write(") ")
+		p.block(stmt.Body)
+	case *ast.BreakStatement:
+		p.mark(stmt.Token)
+		p.write("break;")
+	case *ast.ContinueStatement:
+		p.mark(stmt.Token)
+		p.write("continue;")
+	case *ast.ExpressionStatement:
+		p.mark(stmt.Token)
+		p.expression(stmt.Expression, parser.LOWEST)
+		// if, try and match expressions read like statements, so they
+		// don't get a semicolon
+		switch stmt.Expression.(type) {
+		case *ast.IfExpression, *ast.TryExpression, *ast.MatchExpression:
+		default:
+			p.write(";")
+		}
+	case *ast.BlockStatement:
+		p.block(stmt)
+	}
+}
+
+// block prints { and } around the statements of the block, indented one
+// level deeper than the current line
+func (p *printer) block(block *ast.BlockStatement) {
+	p.mark(block.Token)
+	p.write("{")
+	p.indent++
+	hasComments := len(p.comments) > 0 && block.Rbrace.Line > 0 && p.comments[0].Token.Line < block.Rbrace.Line
+	if len(block.Statements) > 0 || hasComments {
+		p.newline()
+		p.statements(block.Statements, block.Rbrace.Line)
+		p.indent--
+		p.newline()
+	} else {
+		p.indent--
+	}
+	p.write("}")
+	p.mark(block.Rbrace)
+}
+
+// precedence returns how tightly an expression binds, parentheses are
+// needed when it is used somewhere that binds tighter
+func precedence(exp ast.Expression) int {
+	switch exp := exp.(type) {
+	case *ast.InfixExpression:
+		return parser.Precedence(exp.Token.Type)
+	case *ast.PrefixExpression:
+		return parser.PREFIX
+	case *ast.CallExpression:
+		return parser.CALL
+	case *ast.IndexExpression:
+		return parser.INDEX
+	case *ast.MemberExpression:
+		return parser.CALL
+	case *ast.PropagateExpression:
+		return parser.CALL
+	case *ast.AssignExpression:
+		return parser.ASSIGN
+	case *ast.ConditionalExpression:
+		return parser.CONDITIONAL
+	}
+	return atom
+}
+
+// expression prints exp, adding parentheses if it binds looser than prec
+func (p *printer) expression(exp ast.Expression, prec int) {
+	if precedence(exp) < prec {
+		p.write("(")
+		defer p.write(")")
+	}
+	switch exp := exp.(type) {
+	case *ast.Identifier:
+		p.mark(exp.Token)
+		p.write(exp.Value)
+	case *ast.IntegerLiteral:
+		p.mark(exp.Token)
+		p.write(strconv.FormatInt(exp.Value, 10))
+	case *ast.Boolean:
+		p.mark(exp.Token)
+		p.write(strconv.FormatBool(exp.Value))
+	case *ast.NullLiteral:
+		p.mark(exp.Token)
+		p.write("null")
+	case *ast.PrefixExpression:
+		p.mark(exp.Token)
+		p.write(exp.Operator)
+		p.expression(exp.Right, parser.PREFIX)
+	case *ast.InfixExpression:
+		opPrec := precedence(exp)
+		// operators are left associative, so the right hand side needs
+		// parentheses even when it binds exactly as tightly
+		p.expression(exp.Left, opPrec)
+		p.mark(exp.Token)
+		p.write(" " + exp.Operator + " ")
+		p.expression(exp.Right, opPrec+1)
+	case *ast.AssignExpression:
+		// the other way around from infix operators, a = b = c groups to
+		// the right
+		p.expression(exp.Target, parser.ASSIGN+1)
+		p.mark(exp.Token)
+		p.write(" " + exp.Operator + " ")
+		p.expression(exp.Value, parser.ASSIGN)
+	case *ast.ConditionalExpression:
+		p.expression(exp.Condition, parser.CONDITIONAL+1)
+		p.mark(exp.Token)
+		p.write(" ? ")
+		p.expression(exp.Consequence, parser.LOWEST)
+		p.write(" : ")
+		p.expression(exp.Alternative, parser.CONDITIONAL)
+	case *ast.IfExpression:
+		p.mark(exp.Token)
+		p.write("if (")
+		p.expression(exp.Condition, parser.LOWEST)
+		p.write(") ")
+		p.block(exp.Then)
+		if exp.Else != nil {
+			p.write(" else ")
+			p.block(exp.Else)
+		}
+	case *ast.FunctionLiteral:
+		p.mark(exp.Token)
+		p.write("fn(")
+		for i, param := range exp.Parameters {
+			if i > 0 {
+				p.write(", ")
+			}
+			p.expression(param, parser.LOWEST)
+			if def := exp.Default(i); def != nil {
+				p.write(" = ")
+				p.expression(def, parser.LOWEST)
+			}
+		}
+		if exp.Rest != nil {
+			if len(exp.Parameters) > 0 {
+				p.write(", ")
+			}
+			p.write("...")
+			p.expression(exp.Rest, parser.LOWEST)
+		}
+		p.write(") ")
+		p.block(exp.Body)
+	case *ast.CallExpression:
+		p.expression(exp.Function, parser.CALL)
+		p.mark(exp.Token)
+		p.write("(")
+		for i, arg := range exp.Arguments {
+			if i > 0 {
+				p.write(", ")
+			}
+			p.expression(arg, parser.LOWEST)
+		}
+		p.write(")")
+	case *ast.PropagateExpression:
+		p.expression(exp.Left, parser.CALL)
+		p.mark(exp.Token)
+		p.write("?")
+	case *ast.TryExpression:
+		p.mark(exp.Token)
+		p.write("try ")
+		p.block(exp.Block)
+		if exp.Catch != nil {
+			p.write(" catch (")
+			p.expression(exp.Parameter, parser.LOWEST)
+			p.write(") ")
+			p.block(exp.Catch)
+		}
+		if exp.Finally != nil {
+			p.write(" finally ")
+			p.block(exp.Finally)
+		}
+	case *ast.MatchExpression:
+		p.match(exp)
+	case *ast.NamedArgument:
+		p.expression(exp.Name, parser.LOWEST)
+		p.write(": ")
+		p.expression(exp.Value, parser.LOWEST)
+	case *ast.SpreadExpression:
+		p.mark(exp.Token)
+		p.write("...")
+		p.expression(exp.Value, parser.LOWEST)
+	case *ast.StringLiteral:
+		p.mark(exp.Token)
+		p.write(quote(exp.Value))
+	case *ast.ArrayLiteral:
+		p.mark(exp.Token)
+		p.write("[")
+		for i, el := range exp.Elements {
+			if i > 0 {
+				p.write(", ")
+			}
+			p.expression(el, parser.LOWEST)
+		}
+		p.write("]")
+	case *ast.IndexExpression:
+		// calls and indexes both read left to right, so f(x)[0] needs no
+		// parentheses
+		p.expression(exp.Left, parser.CALL)
+		p.mark(exp.Token)
+		p.write("[")
+		p.expression(exp.Index, parser.LOWEST)
+		p.write("]")
+	case *ast.MemberExpression:
+		p.expression(exp.Object, parser.CALL)
+		p.mark(exp.Token)
+		p.write(".")
+		p.expression(exp.Property, parser.LOWEST)
+	case *ast.HashLiteral:
+		p.mark(exp.Token)
+		p.write("{")
+		for i, pair := range exp.Pairs {
+			if i > 0 {
+				p.write(", ")
+			}
+			p.expression(pair.Key, parser.LOWEST)
+			p.write(": ")
+			p.expression(pair.Value, parser.LOWEST)
+		}
+		p.write("}")
+	}
+}
+
+// pattern prints the pattern of a let or a match arm, where {"name": name}
+// is written {name}
+func (p *printer) pattern(exp ast.Expression) {
+	switch exp := exp.(type) {
+	case *ast.ArrayLiteral:
+		p.mark(exp.Token)
+		p.write("[")
+		for i, el := range exp.Elements {
+			if i > 0 {
+				p.write(", ")
+			}
+			p.pattern(el)
+		}
+		p.write("]")
+	case *ast.HashLiteral:
+		p.mark(exp.Token)
+		p.write("{")
+		for i, pair := range exp.Pairs {
+			if i > 0 {
+				p.write(", ")
+			}
+			key, isString := pair.Key.(*ast.StringLiteral)
+			name, isName := pair.Value.(*ast.Identifier)
+			if isString && isName && key.Value == name.Value {
+				p.pattern(name)
+				continue
+			}
+			p.expression(pair.Key, parser.LOWEST)
+			p.write(": ")
+			p.pattern(pair.Value)
+		}
+		p.write("}")
+	default:
+		p.expression(exp, parser.LOWEST)
+	}
+}
+
+// match prints a match expression with every arm on a line of its own,
+// each ending in a comma
+func (p *printer) match(exp *ast.MatchExpression) {
+	p.mark(exp.Token)
+	p.write("match (")
+	p.expression(exp.Value, parser.LOWEST)
+	p.write(") {")
+	p.indent++
+	for _, arm := range exp.Arms {
+		if line := ast.TokenOf(arm.Pattern).Line; line > 0 {
+			p.flushComments(line, true)
+		}
+		p.newline()
+		p.pattern(arm.Pattern)
+		if arm.Guard != nil {
+			p.write(" if ")
+			p.expression(arm.Guard, parser.LOWEST)
+		}
+		p.write(" => ")
+		switch body := arm.Body.(type) {
+		case *ast.BlockStatement:
+			p.block(body)
+		case *ast.ExpressionStatement:
+			p.mark(body.Token)
+			// a hash would read as a block
+			if _, ok := body.Expression.(*ast.HashLiteral); ok {
+				p.expression(body.Expression, atom+1)
+			} else {
+				p.expression(body.Expression, parser.LOWEST)
+			}
+		}
+		p.write(",")
+		p.trailingComments()
+	}
+	pending := len(p.comments)
+	if exp.Rbrace.Line > 0 {
+		p.flushComments(exp.Rbrace.Line, true)
+	}
+	p.indent--
+	if len(exp.Arms) > 0 || len(p.comments) < pending {
+		p.newline()
+	}
+	p.write("}")
+	p.mark(exp.Rbrace)
+}
+
+// quote returns s as a string literal, escaping what the lexer unescapes
+func quote(s string) string {
+	var out strings.Builder
+	out.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '"', '\\':
+			out.WriteByte('\\')
+			out.WriteByte(c)
+		case '\n':
+			out.WriteString(`\n`)
+		case '\t':
+			out.WriteString(`\t`)
+		default:
+			out.WriteByte(c)
+		}
+	}
+	out.WriteByte('"')
+	return out.String()
+}
@@ -0,0 +1,190 @@
+package printer
+
+import (
+	"Go-interpreter/lexer"
+	"Go-interpreter/parser"
+	"testing"
+)
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"", ""},
+		{"let   x=5", "let x = 5;\n"},
+		{"const   x=5", "const x = 5;\n"},
+		{"return 1+2*3", "return 1 + 2 * 3;\n"},
+		{"(1 + 2) * 3", "(1 + 2) * 3;\n"},
+		{"1 + (2 * 3)", "1 + 2 * 3;\n"},
+		{"(a - b) - c", "a - b - c;\n"},
+		{"a - (b - c)", "a - (b - c);\n"},
+		{"a * (b / c)", "a * (b / c);\n"},
+		{"-(1 + 2)", "-(1 + 2);\n"},
+		{"!(-a)", "!-a;\n"},
+		{"(-a) * b", "-a * b;\n"},
+		{"(a < b) == (c > d)", "a < b == c > d;\n"},
+		{"a < (b == c)", "a < (b == c);\n"},
+		{"add(1, (2 + 3))", "add(1, 2 + 3);\n"},
+		{"add(1)(2)", "add(1)(2);\n"},
+		{"(-f)(2)", "(-f)(2);\n"},
+		{"let f = fn(x, y) { x + y; }", "let f = fn(x, y) {\n\tx + y;\n};\n"},
+		{"fn() {}", "fn() {};\n"},
+		{
+			"if (x < y) { x } else { if (y) { y } }",
+			"if (x < y) {\n\tx;\n} else {\n\tif (y) {\n\t\ty;\n\t}\n}\n",
+		},
+		{"let a = 1; let b = 2;", "let a = 1;\nlet b = 2;\n"},
+		{`"a\tb\"c\\"`, "\"a\\tb\\\"c\\\\\";\n"},
+		{"[1,(2+3)][ 0 ]", "[1, 2 + 3][0];\n"},
+		{"f(1)[0]", "f(1)[0];\n"},
+		{"(-a)[0]", "(-a)[0];\n"},
+		{`{"a":1,"b":[]}`, `{"a": 1, "b": []};` + "\n"},
+		{"throw   1+2", "throw 1 + 2;\n"},
+		{"while(x<3){x;break}", "while (x < 3) {\n\tx;\n\tbreak;\n}\n"},
+		{"for(c in \"ab\"){continue}", "for (c in \"ab\") {\n\tcontinue;\n}\n"},
+		{"(f(x) ?) + (-g)?", "f(x)? + (-g)?;\n"},
+		{"x=a[0]+=1*2", "x = a[0] += 1 * 2;\n"},
+		{"(x = 1) + 2", "(x = 1) + 2;\n"},
+		{"f(x=1)", "f(x = 1);\n"},
+		{"a==b ?c+1:d ?e:null", "a == b ? c + 1 : d ? e : null;\n"},
+		{"(a ? b : c) ? d : e", "(a ? b : c) ? d : e;\n"},
+		{"(a ? b : c) + 1", "(a ? b : c) + 1;\n"},
+		{"x = a ?? (b ?? c)", "x = a ?? (b ?? c);\n"},
+		{"(a ?? b) == c", "(a ?? b) == c;\n"},
+		{"f(x)? ? 1 : 2", "f(x)? ? 1 : 2;\n"},
+		{
+			"try { f() } catch(e) { g(e) } finally { h() }",
+			"try {\n\tf();\n} catch (e) {\n\tg(e);\n} finally {\n\th();\n}\n",
+		},
+		{"let x = try { 1 } finally {}", "let x = try {\n\t1;\n} finally {};\n"},
+		{"let a = 1;\n\n\n\nlet b = 2;", "let a = 1;\n\nlet b = 2;\n"},
+		{
+			`match(x){-1=>"neg",[a,_] if a>0=>{a},{"k":v}=>({"v":v})}`,
+			"match (x) {\n\t-1 => \"neg\",\n\t[a, _] if a > 0 => {\n\t\ta;\n\t},\n\t{\"k\": v} => ({\"v\": v}),\n}\n",
+		},
+		{"let y = match (x) {}", "let y = match (x) {};\n"},
+		{"let [a,[b],...c]=x", "let [a, [b], ...c] = x;\n"},
+		{`const {name,"age":a,1:[_,...r]}=x`, `const {name, "age": a, 1: [_, ...r]} = x;` + "\n"},
+		{`let {"k": k, "j": v} = x`, `let {k, "j": v} = x;` + "\n"},
+		{"let f = fn(a,b=1+2,...c){}", "let f = fn(a, b = 1 + 2, ...c) {};\n"},
+		{"fn(...c){}", "fn(...c) {};\n"},
+		{"f(...a,b:x=1,c:2)", "f(...a, b: x = 1, c: 2);\n"},
+		{`"abc" . len ( ).x[0] . y`, `"abc".len().x[0].y;` + "\n"},
+		{"(-a).b+(a+b).c.d(e)", "(-a).b + (a + b).c.d(e);\n"},
+		{"x.y=x.y+1", "x.y = x.y + 1;\n"},
+		{`match(x){{name}=>name}`, "match (x) {\n\t{name} => name,\n}\n"},
+	}
+	for _, tt := range tests {
+		actual, err := Format([]byte(tt.input))
+		if err != nil {
+			t.Errorf("Format(%q) returned error: %s", tt.input, err)
+			continue
+		}
+		if string(actual) != tt.expected {
+			t.Errorf("Format(%q) wrong.\nexpected=%q\ngot=     %q", tt.input, tt.expected, actual)
+		}
+	}
+}
+
+func TestFormatComments(t *testing.T) {
+	input := `// header
+
+let x = 5;   // five
+// about f
+let f = fn(a) {
+  // inside
+  a * 2 // double
+
+
+  // at the end
+};
+if (x) {
+  // only a comment
+}
+// footer`
+	expected := `// header
+
+let x = 5; // five
+// about f
+let f = fn(a) {
+	// inside
+	a * 2; // double
+
+	// at the end
+};
+if (x) {
+	// only a comment
+}
+// footer
+`
+	actual, err := Format([]byte(input))
+	if err != nil {
+		t.Fatalf("Format returned error: %s", err)
+	}
+	if string(actual) != expected {
+		t.Errorf("Format wrong.\nexpected=%q\ngot=     %q", expected, actual)
+	}
+}
+
+func TestFormatSyntaxError(t *testing.T) {
+	_, err := Format([]byte("let = 5;"))
+	if err == nil {
+		t.Fatalf("expected an error for invalid source")
+	}
+}
+
+// formatting must not change what the program means, and formatting the
+// result again must not change it any further
+var idempotencyInputs = []string{
+	"-a * b",
+	"!-a",
+	"a + b * c + d / e - f",
+	"5 > 4 == 3 < 4",
+	"3 + 4 * 5 == 3 * 1 + 4 * 5",
+	"(5 + 5) * 2",
+	"2 / (5 + 5)",
+	"-(5 + 5)",
+	"!(true == true)",
+	"a % (b % c)",
+	"a + add(b * c) + d",
+	"add(a, b, 1, 2 * 3, 4 + 5, add(6, 7 * 8))",
+	"let add = fn(x, y) { return x + y; }; add(1, 2)",
+	"fn(x) { x }(5)",
+	"let max = fn(a, b) { if (a > b) { a } else { b } }; // pick one\nmax(1, 2)",
+	"if (if (a) { b } else { c }) { d }",
+	"// a\n// b\nlet a = 1; // c\n\n\n// d\nlet b = fn() {\n// e\n};",
+	"match (x) {\n// one\n1 => 2, // two\n_ if y => { 3 }\n// end\n}",
+}
+
+func TestFormatIdempotent(t *testing.T) {
+	for _, input := range idempotencyInputs {
+		first, err := Format([]byte(input))
+		if err != nil {
+			t.Errorf("Format(%q) returned error: %s", input, err)
+			continue
+		}
+		second, err := Format(first)
+		if err != nil {
+			t.Errorf("Format(%q) returned error: %s", first, err)
+			continue
+		}
+		if string(first) != string(second) {
+			t.Errorf("Format not idempotent for %q.\nfirst= %q\nsecond=%q", input, first, second)
+		}
+		if parse(t, input) != parse(t, string(first)) {
+			t.Errorf("Format changed the program %q.\nexpected=%q\ngot=     %q",
+				input, parse(t, input), parse(t, string(first)))
+		}
+	}
+}
+
+// parse returns the fully parenthesized form of input
+func parse(t *testing.T, input string) string {
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors for %q: %v", input, p.Errors())
+	}
+	return program.String()
+}
@@ -0,0 +1,119 @@
+package repl
+
+import (
+	"Go-interpreter/lexer"
+	"Go-interpreter/token"
+	"io"
+	"os"
+	"strings"
+)
+
+// ANSI escape codes used for colouring input and output
+const (
+	colorReset   = "\x1b[0m"
+	colorRed     = "\x1b[31m"
+	colorGreen   = "\x1b[32m"
+	colorYellow  = "\x1b[33m"
+	colorBlue    = "\x1b[34m"
+	colorMagenta = "\x1b[35m"
+	colorCyan    = "\x1b[36m"
+	colorGray    = "\x1b[90m"
+)
+
+// colorEnabled reports whether we should write escape codes to out.
+// colours are turned off when NO_COLOR is set (https://no-color.org)
+// or when out is not a terminal, e.g. when piping into a file
+func colorEnabled(out io.Writer) bool {
+	if _, ok := os.LookupEnv("NO_COLOR"); ok {
+		return false
+	}
+	f, ok := out.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// tokenColor returns the colour used for a token type, or "" if the token
+// should be printed as is
+func tokenColor(t token.TokenType) string {
+	switch t {
+	case token.FUNCTION, token.LET, token.CONST, token.IF, token.ELSE,
+		token.RETURN, token.THROW, token.TRY, token.CATCH, token.FINALLY,
+		token.WHILE, token.FOR, token.IN, token.BREAK, token.CONTINUE,
+		token.MATCH:
+		return colorMagenta
+	case token.INT:
+		return colorYellow
+	case token.STRING:
+		return colorGreen
+	case token.TRUE, token.FALSE, token.NULL:
+		return colorBlue
+	case token.ASSIGN, token.PLUS, token.MINUS, token.BANG, token.ASTERISK,
+		token.SLASH, token.MODULUS, token.LT, token.GT, token.EQ, token.NEQ,
+		token.QUESTION, token.CONDITIONAL, token.COALESCE, token.PLUS_ASSIGN,
+		token.MINUS_ASSIGN, token.ASTERISK_ASSIGN, token.SLASH_ASSIGN,
+		token.MODULUS_ASSIGN, token.ARROW, token.ELLIPSIS:
+		return colorCyan
+	case token.ILLEGAL:
+		return colorRed
+	default:
+		return ""
+	}
+}
+
+// Highlight runs the lexer over a line of input and wraps every token in the
+// escape code for its type. Whitespace between tokens is kept as is, so the
+// printed width of the result matches the input
+func Highlight(line string) string {
+	var out strings.Builder
+	l := lexer.New(line)
+	position := 0
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		// the lexer drops whitespace, so we find the token again in the input.
+		// the column is where it starts, the literal of a string doesn't
+		// have its quotes and escapes so that is the only way to find it
+		start := tok.Column - 1
+		if start < position || start >= len(line) {
+			break
+		}
+		end := start + len(tok.Literal)
+		if tok.Type == token.STRING || tok.Type == token.ILLEGAL {
+			end = stringEnd(line, start)
+		}
+		out.WriteString(line[position:start])
+		position = end
+		text := line[start:end]
+
+		color := tokenColor(tok.Type)
+		if color == "" {
+			out.WriteString(text)
+			continue
+		}
+		out.WriteString(color + text + colorReset)
+	}
+	out.WriteString(line[position:])
+	return out.String()
+}
+
+// stringEnd returns where the string literal starting at line[start] ends,
+// just after the closing quote or at the end of the line if there is none.
+// for other tokens it returns the position after the first character
+func stringEnd(line string, start int) int {
+	if line[start] != '"' {
+		return start + 1
+	}
+	for i := start + 1; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1
+		}
+	}
+	return len(line)
+}
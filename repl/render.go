@@ -0,0 +1,106 @@
+package repl
+
+import (
+	"Go-interpreter/object"
+	"strings"
+)
+
+// valueColor returns the colour used when printing an object of type t
+func valueColor(t object.ObjectType) string {
+	switch t {
+	case object.INTEGER_OBJ:
+		return colorYellow
+	case object.STRING_OBJ:
+		return colorGreen
+	case object.BOOLEAN_OBJ:
+		return colorBlue
+	case object.NULL_OBJ:
+		return colorGray
+	case object.ERROR_OBJ:
+		return colorRed
+	default:
+		return ""
+	}
+}
+
+// Render formats an evaluated object for printing in the REPL.
+// when color is false the result is what Inspect() returns, except that
+// arrays and hashes holding other arrays or hashes get one element per
+// line, indented by how deeply they are nested, and errors that have a
+// stack trace are followed by an empty line and the trace
+func Render(obj object.Object, color bool) string {
+	r := &renderer{color: color}
+	r.render(obj, 0)
+	return r.out.String()
+}
+
+type renderer struct {
+	out   strings.Builder
+	color bool
+}
+
+func (r *renderer) render(obj object.Object, depth int) {
+	switch obj := obj.(type) {
+	case *object.Array:
+		r.container("[", "]", len(obj.Elements), nested(obj.Elements...), depth, func(i int) {
+			r.render(obj.Elements[i], depth+1)
+		})
+	case *object.Hash:
+		values := []object.Object{}
+		for _, key := range obj.Keys {
+			values = append(values, obj.Pairs[key].Key, obj.Pairs[key].Value)
+		}
+		r.container("{", "}", len(obj.Keys), nested(values...), depth, func(i int) {
+			pair := obj.Pairs[obj.Keys[i]]
+			r.render(pair.Key, depth+1)
+			r.out.WriteString(": ")
+			r.render(pair.Value, depth+1)
+		})
+	default:
+		text := obj.Inspect()
+		if errObj, ok := obj.(*object.Error); ok && len(errObj.Stack) > 0 {
+			text += "\n\n" + strings.TrimSuffix(errObj.StackTrace(""), "\n")
+		}
+		r.colored(text, valueColor(obj.Type()))
+	}
+}
+
+// container writes the n elements of an array or hash between open and
+// close, on one line or, if multiline, one per line
+func (r *renderer) container(open, close string, n int, multiline bool, depth int, element func(i int)) {
+	r.out.WriteString(open)
+	for i := 0; i < n; i++ {
+		if multiline {
+			r.out.WriteString("\n" + strings.Repeat("  ", depth+1))
+		} else if i > 0 {
+			r.out.WriteString(", ")
+		}
+		element(i)
+		if multiline {
+			r.out.WriteString(",")
+		}
+	}
+	if multiline {
+		r.out.WriteString("\n" + strings.Repeat("  ", depth))
+	}
+	r.out.WriteString(close)
+}
+
+func (r *renderer) colored(text, color string) {
+	if !r.color || color == "" {
+		r.out.WriteString(text)
+		return
+	}
+	r.out.WriteString(color + text + colorReset)
+}
+
+// nested reports whether any of objs is an array or a hash
+func nested(objs ...object.Object) bool {
+	for _, obj := range objs {
+		switch obj.(type) {
+		case *object.Array, *object.Hash:
+			return true
+		}
+	}
+	return false
+}
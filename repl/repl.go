@@ -1,28 +1,63 @@
 package repl
 
 import (
+	"Go-interpreter/dot"
 	"Go-interpreter/evaluator"
 	"Go-interpreter/lexer"
+	"Go-interpreter/object"
 	"Go-interpreter/parser"
 	"bufio"
-	"fmt"
 	"io"
+	"strings"
 )
 
 const PROMPT = ">> "
 
+// lineReader reads one line of input after printing the prompt.
+// ok is false once the input is exhausted
+type lineReader interface {
+	readLine(prompt string) (line string, ok bool)
+}
+
+// scannerReader is used when the input isn't an interactive terminal
+type scannerReader struct {
+	scanner *bufio.Scanner
+	out     io.Writer
+}
+
+func (s *scannerReader) readLine(prompt string) (string, bool) {
+	io.WriteString(s.out, prompt)
+	if !s.scanner.Scan() {
+		return "", false
+	}
+	return s.scanner.Text(), true
+}
+
 func Start(in io.Reader, out io.Writer) {
-	scanner := bufio.NewScanner(in)
+	color := colorEnabled(out)
+	var reader lineReader
+	// input is only highlighted while typing if we're allowed to use colours
+	if color {
+		reader = newTerminal(in, out)
+	}
+	if reader == nil {
+		reader = &scannerReader{scanner: bufio.NewScanner(in), out: out}
+	}
+	// bindings are kept from one line to the next
+	env := object.NewEnvironment()
 	for {
-		fmt.Printf(PROMPT)
-		scanned := scanner.Scan()
-		if !scanned {
+		line, ok := reader.readLine(PROMPT)
+		if !ok {
 			return
 		}
-		line := scanner.Text()
 		if line == ":q" {
 			break
 		}
+		// :dot <code> prints the syntax tree of the code instead of running it
+		showGraph := strings.HasPrefix(line, ":dot ")
+		if showGraph {
+			line = strings.TrimPrefix(line, ":dot ")
+		}
 		l := lexer.New(line)
 		p := parser.New(l)
 		program := p.ParseProgram()
@@ -30,9 +65,13 @@ func Start(in io.Reader, out io.Writer) {
 			printParserErrors(out, p.Errors())
 			continue
 		}
-		evaluated := evaluator.Eval(program)
+		if showGraph {
+			io.WriteString(out, dot.String(program))
+			continue
+		}
+		evaluated := evaluator.Eval(program, env)
 		if evaluated != nil {
-			io.WriteString(out, evaluated.Inspect())
+			io.WriteString(out, Render(evaluated, color))
 			io.WriteString(out, "\n")
 		}
 	}
@@ -0,0 +1,110 @@
+package repl
+
+import (
+	"Go-interpreter/object"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"", ""},
+		{"x", "x"},
+		{"5", colorYellow + "5" + colorReset},
+		{
+			"let x = true;",
+			colorMagenta + "let" + colorReset + " x " +
+				colorCyan + "=" + colorReset + " " +
+				colorBlue + "true" + colorReset + ";",
+		},
+		{
+			"  1 != 2  ",
+			"  " + colorYellow + "1" + colorReset + " " +
+				colorCyan + "!=" + colorReset + " " +
+				colorYellow + "2" + colorReset + "  ",
+		},
+		{"@", colorRed + "@" + colorReset},
+		{
+			`len("a\"b") ["x"]`,
+			"len(" + colorGreen + `"a\"b"` + colorReset + ") [" + colorGreen + `"x"` + colorReset + "]",
+		},
+		{`"open`, colorRed + `"open` + colorReset},
+	}
+	for _, tt := range tests {
+		actual := Highlight(tt.input)
+		if actual != tt.expected {
+			t.Errorf("Highlight(%q) wrong. expected=%q, got=%q", tt.input, tt.expected, actual)
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		obj      object.Object
+		color    bool
+		expected string
+	}{
+		{&object.Integer{Value: 5}, false, "5"},
+		{&object.Integer{Value: 5}, true, colorYellow + "5" + colorReset},
+		{&object.Boolean{Value: true}, false, "true"},
+		{&object.Boolean{Value: false}, true, colorBlue + "false" + colorReset},
+		{&object.Null{}, true, colorGray + "Null" + colorReset},
+		{&object.Error{Message: "boom"}, false, "ERROR: boom"},
+		{&object.Error{
+			Message: "boom",
+			Stack:   []object.StackFrame{{Function: "f", Line: 1, Column: 2}},
+			Omitted: 3,
+		}, true, colorRed + "ERROR: boom\n\nf(...)\n\t1:2\n...3 more calls..." + colorReset},
+		{&object.String{Value: "hi"}, true, colorGreen + "hi" + colorReset},
+		{array(integer(1), integer(2)), false, "[1, 2]"},
+		{array(integer(1), array()), true, "[\n  " + colorYellow + "1" + colorReset + ",\n  [],\n]"},
+		{
+			hash(&object.String{Value: "a"}, array(integer(1), array(integer(2)))),
+			false,
+			"{\n  a: [\n    1,\n    [2],\n  ],\n}",
+		},
+	}
+	for _, tt := range tests {
+		actual := Render(tt.obj, tt.color)
+		if actual != tt.expected {
+			t.Errorf("Render(%s, %t) wrong. expected=%q, got=%q", tt.obj.Inspect(), tt.color, tt.expected, actual)
+		}
+	}
+}
+
+func integer(value int64) *object.Integer {
+	return &object.Integer{Value: value}
+}
+
+func array(elements ...object.Object) *object.Array {
+	return &object.Array{Elements: elements}
+}
+
+func hash(key object.Hashable, value object.Object) *object.Hash {
+	h := object.NewHash()
+	h.Set(key, value)
+	return h
+}
+
+func TestStartWithoutTerminal(t *testing.T) {
+	in := strings.NewReader("1 + 2\ntrue\n:q\n")
+	var out bytes.Buffer
+	Start(in, &out)
+	expected := ">> 3\n>> true\n>> "
+	if out.String() != expected {
+		t.Errorf("output wrong. expected=%q, got=%q", expected, out.String())
+	}
+}
+
+func TestStartDot(t *testing.T) {
+	in := strings.NewReader(":dot x\n")
+	var out bytes.Buffer
+	Start(in, &out)
+	if !strings.Contains(out.String(), `n2 [label="Identifier\nx"];`) {
+		t.Errorf("output has no graph. got=%q", out.String())
+	}
+}
@@ -0,0 +1,213 @@
+package repl
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"syscall"
+	"unicode/utf8"
+	"unsafe"
+)
+
+// terminal is a small line editor that puts the tty into raw mode so that
+// the line can be highlighted again after every key press
+type terminal struct {
+	fd      uintptr
+	in      *bufio.Reader
+	out     io.Writer
+	history []string
+}
+
+// newTerminal returns nil if in is not a terminal we can switch to raw mode
+func newTerminal(in io.Reader, out io.Writer) lineReader {
+	f, ok := in.(*os.File)
+	if !ok {
+		return nil
+	}
+	if _, err := getTermios(f.Fd()); err != nil {
+		return nil
+	}
+	return &terminal{fd: f.Fd(), in: bufio.NewReader(f), out: out}
+}
+
+func getTermios(fd uintptr) (*syscall.Termios, error) {
+	termios := &syscall.Termios{}
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, syscall.TCGETS, uintptr(unsafe.Pointer(termios)))
+	if errno != 0 {
+		return nil, errno
+	}
+	return termios, nil
+}
+
+func setTermios(fd uintptr, termios *syscall.Termios) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, syscall.TCSETS, uintptr(unsafe.Pointer(termios)))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// control characters we handle while editing
+const (
+	keyCtrlA     = 1
+	keyCtrlC     = 3
+	keyCtrlD     = 4
+	keyCtrlE     = 5
+	keyBackspace = 8
+	keyEnter     = '\r'
+	keyNewline   = '\n'
+	keyEscape    = 27
+	keyDelete    = 127
+)
+
+func (t *terminal) readLine(prompt string) (string, bool) {
+	old, err := getTermios(t.fd)
+	if err != nil {
+		return "", false
+	}
+	raw := *old
+	// no echo, no line buffering and no signals, we handle ctrl-c ourselves.
+	// output processing stays on so "\n" still moves to the start of the line
+	raw.Iflag &^= syscall.ICRNL | syscall.IXON
+	raw.Lflag &^= syscall.ECHO | syscall.ICANON | syscall.ISIG | syscall.IEXTEN
+	raw.Cc[syscall.VMIN] = 1
+	raw.Cc[syscall.VTIME] = 0
+	if err := setTermios(t.fd, &raw); err != nil {
+		return "", false
+	}
+	defer setTermios(t.fd, old)
+	return t.edit(prompt)
+}
+
+// edit reads keys until the line is entered. the line is kept as runes so
+// the cursor moves over a character typed as several bytes at once
+func (t *terminal) edit(prompt string) (string, bool) {
+	var line []rune
+	cursor := 0
+	// index into the history, len(history) is the line being typed
+	entry := len(t.history)
+	draft := ""
+
+	redraw := func() {
+		fmt.Fprintf(t.out, "\r%s%s\x1b[K", prompt, Highlight(string(line)))
+		// the terminal leaves the cursor after the line, move it back to
+		// its column. wide characters take two columns, combining marks none
+		if back := displayWidth(line) - displayWidth(line[:cursor]); back > 0 {
+			fmt.Fprintf(t.out, "\x1b[%dD", back)
+		}
+	}
+	recall := func(index int) {
+		if index < 0 || index > len(t.history) {
+			return
+		}
+		if entry == len(t.history) {
+			draft = string(line)
+		}
+		entry = index
+		if entry == len(t.history) {
+			line = []rune(draft)
+		} else {
+			line = []rune(t.history[entry])
+		}
+		cursor = len(line)
+	}
+
+	redraw()
+	for {
+		ch, err := t.in.ReadByte()
+		if err != nil {
+			return "", false
+		}
+		switch ch {
+		case keyEnter, keyNewline:
+			io.WriteString(t.out, "\n")
+			if len(line) > 0 {
+				t.history = append(t.history, string(line))
+			}
+			return string(line), true
+		case keyCtrlC:
+			io.WriteString(t.out, "^C\n")
+			line, cursor = nil, 0
+		case keyCtrlD:
+			if len(line) == 0 {
+				io.WriteString(t.out, "\n")
+				return "", false
+			}
+			if cursor < len(line) {
+				line = append(line[:cursor], line[cursor+1:]...)
+			}
+		case keyCtrlA:
+			cursor = 0
+		case keyCtrlE:
+			cursor = len(line)
+		case keyBackspace, keyDelete:
+			if cursor > 0 {
+				line = append(line[:cursor-1], line[cursor:]...)
+				cursor--
+			}
+		case keyEscape:
+			switch t.readEscape() {
+			case 'A':
+				recall(entry - 1)
+			case 'B':
+				recall(entry + 1)
+			case 'C':
+				if cursor < len(line) {
+					cursor++
+				}
+			case 'D':
+				if cursor > 0 {
+					cursor--
+				}
+			case 'H':
+				cursor = 0
+			case 'F':
+				cursor = len(line)
+			case '~':
+				if cursor < len(line) {
+					line = append(line[:cursor], line[cursor+1:]...)
+				}
+			}
+		default:
+			// anything else that isn't printable is ignored
+			if ch < ' ' {
+				continue
+			}
+			r := rune(ch)
+			if ch >= utf8.RuneSelf {
+				t.in.UnreadByte()
+				if r, _, err = t.in.ReadRune(); err != nil {
+					return "", false
+				}
+				if r == utf8.RuneError {
+					continue
+				}
+			}
+			line = append(line[:cursor], append([]rune{r}, line[cursor:]...)...)
+			cursor++
+		}
+		redraw()
+	}
+}
+
+// readEscape reads the rest of an escape sequence and returns its final
+// byte: A/B/C/D for the arrow keys, H/F for home and end and ~ for delete.
+// unknown sequences return 0
+func (t *terminal) readEscape() byte {
+	if b, err := t.in.ReadByte(); err != nil || b != '[' {
+		return 0
+	}
+	b, err := t.in.ReadByte()
+	if err != nil {
+		return 0
+	}
+	if b == '3' {
+		// delete is sent as ESC [ 3 ~
+		if next, err := t.in.ReadByte(); err != nil || next != '~' {
+			return 0
+		}
+		return '~'
+	}
+	return b
+}
@@ -0,0 +1,61 @@
+package repl
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestTerminalEdit(t *testing.T) {
+	tests := []struct {
+		keys     string
+		expected string
+	}{
+		{"\"héllo\"\r", "\"héllo\""},
+		// backspace and the arrow keys go over a whole character
+		{"abé\x7f\x7fc\r", "ac"},
+		{"éb\x1b[D\x1b[Dx\r", "xéb"},
+		{"日本\x1b[D\x1b[3~語\r", "日語"},
+		// control characters and invalid bytes are dropped
+		{"a\x02\xffb\r", "ab"},
+	}
+	for _, tt := range tests {
+		term := &terminal{in: bufio.NewReader(strings.NewReader(tt.keys)), out: io.Discard}
+		line, ok := term.edit(">> ")
+		if !ok || line != tt.expected {
+			t.Errorf("%q: expected=%q, got=%q (ok=%t)", tt.keys, tt.expected, line, ok)
+		}
+	}
+}
+
+func TestTerminalCursorColumn(t *testing.T) {
+	tests := []struct {
+		keys     string
+		expected string // how the last redraw moves the cursor back
+	}{
+		{"ab\x1b[D", "\x1b[1D"},
+		{"日本", ""},
+		// a wide character is two columns, wherever it is
+		{"日本\x1b[D", "\x1b[2D"},
+		{"日本\x1b[D\x1b[D", "\x1b[4D"},
+		{"日a\x1b[D", "\x1b[1D"},
+		{"a日b\x1b[D\x1b[D\x1b[D", "\x1b[4D"},
+		// a combining mark takes no column of its own
+		{"e\u0301x\x1b[H", "\x1b[2D"},
+	}
+	for _, tt := range tests {
+		var out bytes.Buffer
+		term := &terminal{in: bufio.NewReader(strings.NewReader(tt.keys)), out: &out}
+		term.edit(">> ")
+
+		// the input ends without enter, so the last redraw is the last
+		// thing written
+		last := out.String()[strings.LastIndex(out.String(), "\r"):]
+		move := last[strings.LastIndex(last, "\x1b[K")+len("\x1b[K"):]
+		if move != tt.expected {
+			t.Errorf("%q: expected=%q, got=%q", tt.keys, tt.expected, move)
+		}
+	}
+}
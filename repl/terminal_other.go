@@ -0,0 +1,11 @@
+//go:build !linux
+
+package repl
+
+import "io"
+
+// raw mode is only implemented for linux, other platforms always fall back
+// to reading plain lines
+func newTerminal(in io.Reader, out io.Writer) lineReader {
+	return nil
+}
@@ -0,0 +1,60 @@
+package repl
+
+import "unicode"
+
+// wide is the characters a terminal draws two columns wide: the CJK
+// blocks, hangul, fullwidth forms and the common emoji. it is a small
+// part of Unicode's East Asian Width table, enough for the text people
+// type into a REPL
+var wide = &unicode.RangeTable{
+	R16: []unicode.Range16{
+		{Lo: 0x1100, Hi: 0x115f, Stride: 1}, // hangul jamo
+		{Lo: 0x231a, Hi: 0x231b, Stride: 1}, // watch, hourglass
+		{Lo: 0x2614, Hi: 0x2615, Stride: 1}, // umbrella, hot beverage
+		{Lo: 0x26a1, Hi: 0x26a1, Stride: 1}, // high voltage
+		{Lo: 0x2705, Hi: 0x2705, Stride: 1}, // check mark
+		{Lo: 0x2728, Hi: 0x2728, Stride: 1}, // sparkles
+		{Lo: 0x274c, Hi: 0x274c, Stride: 1}, // cross mark
+		{Lo: 0x2b50, Hi: 0x2b50, Stride: 1}, // star
+		{Lo: 0x2e80, Hi: 0x303e, Stride: 1}, // CJK radicals and punctuation
+		{Lo: 0x3041, Hi: 0x33ff, Stride: 1}, // kana, bopomofo, CJK compatibility
+		{Lo: 0x3400, Hi: 0x4dbf, Stride: 1}, // CJK extension A
+		{Lo: 0x4e00, Hi: 0x9fff, Stride: 1}, // CJK unified ideographs
+		{Lo: 0xa000, Hi: 0xa4cf, Stride: 1}, // yi
+		{Lo: 0xa960, Hi: 0xa97f, Stride: 1}, // hangul jamo extended A
+		{Lo: 0xac00, Hi: 0xd7a3, Stride: 1}, // hangul syllables
+		{Lo: 0xf900, Hi: 0xfaff, Stride: 1}, // CJK compatibility ideographs
+		{Lo: 0xfe10, Hi: 0xfe19, Stride: 1}, // vertical forms
+		{Lo: 0xfe30, Hi: 0xfe6f, Stride: 1}, // CJK compatibility forms, small forms
+		{Lo: 0xff00, Hi: 0xff60, Stride: 1}, // fullwidth forms
+		{Lo: 0xffe0, Hi: 0xffe6, Stride: 1}, // fullwidth signs
+	},
+	R32: []unicode.Range32{
+		{Lo: 0x1f300, Hi: 0x1f64f, Stride: 1}, // pictographs and emoticons
+		{Lo: 0x1f680, Hi: 0x1f6ff, Stride: 1}, // transport and map symbols
+		{Lo: 0x1f900, Hi: 0x1f9ff, Stride: 1}, // supplemental pictographs
+		{Lo: 0x20000, Hi: 0x3fffd, Stride: 1}, // CJK extensions B and up
+	},
+}
+
+// runeWidth is the number of columns r takes up on a terminal. combining
+// marks go on top of the character before them and take none
+func runeWidth(r rune) int {
+	switch {
+	case unicode.In(r, unicode.Mn, unicode.Me):
+		return 0
+	case unicode.Is(wide, r):
+		return 2
+	default:
+		return 1
+	}
+}
+
+// displayWidth is the number of columns text takes up on a terminal
+func displayWidth(text []rune) int {
+	width := 0
+	for _, r := range text {
+		width += runeWidth(r)
+	}
+	return width
+}
@@ -0,0 +1,24 @@
+package repl
+
+import "testing"
+
+func TestDisplayWidth(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected int
+	}{
+		{"", 0},
+		{"let x = 1;", 10},
+		{"héllo", 5},
+		{"é", 1},
+		{"日本語", 6},
+		{"한국어", 6},
+		{"ｆｕｌｌ", 8},
+		{"a🙂b", 4},
+	}
+	for _, tt := range tests {
+		if width := displayWidth([]rune(tt.text)); width != tt.expected {
+			t.Errorf("%q: expected=%d, got=%d", tt.text, tt.expected, width)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"Go-interpreter/ast"
+	"Go-interpreter/compiler"
+	"Go-interpreter/evaluator"
+	"Go-interpreter/object"
+	"Go-interpreter/optimizer"
+	"Go-interpreter/vm"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+)
+
+// runRun runs a program with the tree-walking evaluator or, with
+// --engine=vm, compiles it to bytecode and runs that. files made by
+// monkey build are run on the vm directly. the value of the program is
+// printed if it has one. --trace runs it on the vm and logs every
+// instruction to stderr, --optimize runs the optimizer on it first.
+// --max-steps, --timeout and --max-memory limit the evaluator, which also
+// stops when interrupted
+func runRun(args []string) int {
+	flags := flag.NewFlagSet("run", flag.ContinueOnError)
+	engine := flags.String("engine", "", "how to run the program: eval or vm (default eval, vm for compiled files)")
+	trace := flags.Bool("trace", false, "log every instruction the vm executes")
+	optimize := flags.Bool("optimize", false, "simplify the program before running it")
+	maxSteps := flags.Int64("max-steps", 0, "stop the evaluator after this many steps, 0 for no limit")
+	timeout := flags.Duration("timeout", 0, "stop the evaluator after this long, 0 for no limit")
+	maxMemory := flags.Int64("max-memory", 0, "stop the evaluator once it holds more than this many bytes, 0 for no limit")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 || (*engine != "" && *engine != "eval" && *engine != "vm") {
+		fmt.Fprintln(os.Stderr, "usage: monkey run [--engine=eval|vm] [--trace] [--optimize] [--max-steps=n] [--timeout=d] [--max-memory=bytes] file")
+		return 2
+	}
+	path := flags.Arg(0)
+
+	src, err := readSource(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "run: %s\n", err)
+		return 1
+	}
+	compiled := compiler.IsBinary(src)
+	if *engine == "" {
+		*engine = "eval"
+		if compiled || *trace {
+			*engine = "vm"
+		}
+	}
+	if *engine == "eval" && compiled {
+		fmt.Fprintf(os.Stderr, "run: %s is compiled, it can only run on the vm\n", path)
+		return 2
+	}
+	if *engine == "eval" && *trace {
+		fmt.Fprintln(os.Stderr, "run: --trace only works on the vm")
+		return 2
+	}
+	if *engine == "vm" && (*maxSteps != 0 || *timeout != 0 || *maxMemory != 0) {
+		fmt.Fprintln(os.Stderr, "run: --max-steps, --timeout and --max-memory only work with the evaluator")
+		return 2
+	}
+
+	var result object.Object
+	if *engine == "vm" {
+		bytecode, loadErr := loadBytecode(path, src, *optimize)
+		if loadErr != nil {
+			fmt.Fprintf(os.Stderr, "run: %s\n", loadErr)
+			return 1
+		}
+		var traceOut io.Writer
+		if *trace {
+			traceOut = os.Stderr
+		}
+		result, err = runVM(bytecode, traceOut)
+	} else {
+		program, parseErr := parseBytes(path, src)
+		if parseErr != nil {
+			fmt.Fprintf(os.Stderr, "run: %s\n", parseErr)
+			return 1
+		}
+		if *optimize {
+			optimizer.Optimize(program)
+		}
+		opts := evaluator.Options{MaxSteps: *maxSteps, MaxMemory: *maxMemory}
+		if *timeout > 0 {
+			opts.Deadline = time.Now().Add(*timeout)
+		}
+		result, err = runEval(program, path, opts)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %s\n", err)
+		return 1
+	}
+	if result != nil {
+		fmt.Println(result.Inspect())
+	}
+	return 0
+}
+
+// runEval evaluates program, path is the file it came from for stack traces
+func runEval(program *ast.Program, path string, opts evaluator.Options) (object.Object, error) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	opts.Context = ctx
+
+	result := evaluator.EvalWithOptions(program, object.NewEnvironment(), opts)
+	if errObj, ok := result.(*object.Error); ok {
+		return nil, runtimeError{errObj, path}
+	}
+	return result, nil
+}
+
+// runtimeError is an error object from the evaluator, printed with its
+// stack trace after an empty line like a Go panic
+type runtimeError struct {
+	obj  *object.Error
+	file string
+}
+
+func (e runtimeError) Error() string {
+	if len(e.obj.Stack) == 0 {
+		return e.obj.Message
+	}
+	return e.obj.Message + "\n\n" + strings.TrimSuffix(e.obj.StackTrace(e.file), "\n")
+}
+
+// runVM runs compiled code, logging the instructions to trace if it isn't
+// nil
+func runVM(bytecode *compiler.Bytecode, trace io.Writer) (object.Object, error) {
+	machine := vm.New(bytecode)
+	if trace != nil {
+		machine.Trace(trace)
+	}
+	if err := machine.Run(); err != nil {
+		return nil, err
+	}
+	return machine.Result(), nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"Go-interpreter/ast"
+	"Go-interpreter/compiler"
+	"Go-interpreter/lexer"
+	"Go-interpreter/optimizer"
+	"Go-interpreter/parser"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// readSource reads a source file, or stdin if path is "-"
+func readSource(path string) ([]byte, error) {
+	if path == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(path)
+}
+
+// parseSource parses a whole program and returns the parser errors as one
+// error, one per line
+func parseSource(path string) (*ast.Program, error) {
+	src, err := readSource(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseBytes(path, src)
+}
+
+// parseBytes parses src, which was read from path
+func parseBytes(path string, src []byte) (*ast.Program, error) {
+	p := parser.New(lexer.New(string(src)))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, errors.New(path + ": " + strings.Join(p.Errors(), "\n"+path+": "))
+	}
+	return program, nil
+}
+
+// loadBytecode returns the bytecode of src, which was read from path. it
+// is either decoded if src was made by monkey build or compiled from
+// source, after running the optimizer on it if optimize is set
+func loadBytecode(path string, src []byte, optimize bool) (*compiler.Bytecode, error) {
+	if compiler.IsBinary(src) {
+		var bytecode compiler.Bytecode
+		if err := bytecode.UnmarshalBinary(src); err != nil {
+			return nil, fmt.Errorf("%s: %s", path, err)
+		}
+		return &bytecode, nil
+	}
+
+	program, err := parseBytes(path, src)
+	if err != nil {
+		return nil, err
+	}
+	if optimize {
+		optimizer.Optimize(program)
+	}
+	comp := compiler.New()
+	if err := comp.Compile(program); err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+	return comp.Bytecode(), nil
+}
@@ -3,16 +3,20 @@ package token
 type TokenType string
 
 type Token struct {
-	Type    TokenType
-	Literal string
+	Type    TokenType `json:"type"`
+	Literal string    `json:"literal"`
+	Line    int       `json:"line"`   // 1-based line the token starts on, 0 if unknown
+	Column  int       `json:"column"` // 1-based byte offset into the line
 }
 
 const (
 	ILLEGAL = "ILLEGAL"
 	EOF     = "EOF"
 
-	IDENT = "IDENT"
-	INT   = "INT"
+	IDENT   = "IDENT"
+	INT     = "INT"
+	STRING  = "STRING"
+	COMMENT = "COMMENT"
 
 	ASSIGN   = "="
 	PLUS     = "+"
@@ -22,37 +26,79 @@ const (
 	SLASH    = "/"
 	MODULUS  = "%"
 
+	PLUS_ASSIGN     = "+="
+	MINUS_ASSIGN    = "-="
+	ASTERISK_ASSIGN = "*="
+	SLASH_ASSIGN    = "/="
+	MODULUS_ASSIGN  = "%="
+
 	LT = "<"
 	GT = ">"
 
 	COMMA     = ","
 	SEMICOLON = ";"
+	COLON     = ":"
+	QUESTION  = "?"
+
+	// the ? of a conditional expression. the lexer gives every ? as
+	// QUESTION, the parser tells this one apart from the postfix operator
+	CONDITIONAL = "CONDITIONAL"
+	COALESCE    = "??"
+	ARROW       = "=>"
+	ELLIPSIS    = "..."
+	DOT         = "."
 
-	LPAREN = "("
-	RPAREN = ")"
-	LBRACE = "{"
-	RBRACE = "}"
+	LPAREN   = "("
+	RPAREN   = ")"
+	LBRACE   = "{"
+	RBRACE   = "}"
+	LBRACKET = "["
+	RBRACKET = "]"
 
 	FUNCTION = "FUNCTION"
 	LET      = "LET"
+	CONST    = "CONST"
 	TRUE     = "TRUE"
 	FALSE    = "FALSE"
+	NULL     = "NULL"
 	IF       = "IF"
 	ELSE     = "ELSE"
 	RETURN   = "RETURN"
+	THROW    = "THROW"
+	TRY      = "TRY"
+	CATCH    = "CATCH"
+	FINALLY  = "FINALLY"
+	WHILE    = "WHILE"
+	FOR      = "FOR"
+	IN       = "IN"
+	BREAK    = "BREAK"
+	CONTINUE = "CONTINUE"
+	MATCH    = "MATCH"
 
 	EQ  = "=="
 	NEQ = "!="
 )
 
 var keywords = map[string]TokenType{
-	"fn":     FUNCTION,
-	"let":    LET,
-	"true":   TRUE,
-	"false":  FALSE,
-	"if":     IF,
-	"else":   ELSE,
-	"return": RETURN,
+	"fn":       FUNCTION,
+	"let":      LET,
+	"const":    CONST,
+	"true":     TRUE,
+	"false":    FALSE,
+	"null":     NULL,
+	"if":       IF,
+	"else":     ELSE,
+	"return":   RETURN,
+	"throw":    THROW,
+	"try":      TRY,
+	"catch":    CATCH,
+	"finally":  FINALLY,
+	"while":    WHILE,
+	"for":      FOR,
+	"in":       IN,
+	"break":    BREAK,
+	"continue": CONTINUE,
+	"match":    MATCH,
 }
 
 func LookupIdent(ident string) TokenType {
@@ -0,0 +1,21 @@
+package vm
+
+import (
+	"Go-interpreter/code"
+	"Go-interpreter/object"
+)
+
+// Frame is one function call being run
+type Frame struct {
+	cl          *object.Closure
+	ip          int
+	basePointer int // where the locals of the call start on the stack
+}
+
+func NewFrame(cl *object.Closure, basePointer int) *Frame {
+	return &Frame{cl: cl, ip: -1, basePointer: basePointer}
+}
+
+func (f *Frame) Instructions() code.Instructions {
+	return f.cl.Fn.Instructions
+}
@@ -0,0 +1,621 @@
+// Package vm runs the bytecode produced by the compiler package on a
+// value stack, with a slot for every global, a call frame per function
+// call and closures for functions that use variables around them.
+//
+// Runtime errors are returned from Run with the same messages the
+// evaluator puts in its error objects.
+package vm
+
+import (
+	"Go-interpreter/code"
+	"Go-interpreter/compiler"
+	"Go-interpreter/evaluator"
+	"Go-interpreter/object"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// the stack and the frames start this big and grow when they run out.
+// like in the evaluator, at most evaluator.DefaultMaxCallDepth calls can be
+// running at once
+const (
+	initialStackSize  = 2048
+	initialFrameCount = 256
+)
+
+var (
+	True  = &object.Boolean{Value: true}
+	False = &object.Boolean{Value: false}
+	Null  = &object.Null{}
+)
+
+// small integers are shared instead of allocating one per result
+const (
+	minCachedInteger = -128
+	maxCachedInteger = 1024
+)
+
+var cachedIntegers = func() []*object.Integer {
+	integers := make([]*object.Integer, maxCachedInteger-minCachedInteger+1)
+	for i := range integers {
+		integers[i] = &object.Integer{Value: int64(i + minCachedInteger)}
+	}
+	return integers
+}()
+
+func newInteger(value int64) *object.Integer {
+	if value >= minCachedInteger && value <= maxCachedInteger {
+		return cachedIntegers[value-minCachedInteger]
+	}
+	return &object.Integer{Value: value}
+}
+
+type VM struct {
+	constants   []object.Object
+	globals     []object.Object
+	globalNames []string
+
+	stack []object.Object
+	sp    int // always points to the next free slot, the top is stack[sp-1]
+
+	frames      []*Frame
+	framesIndex int
+
+	result object.Object
+
+	trace io.Writer
+}
+
+func New(bytecode *compiler.Bytecode) *VM {
+	mainFn := &object.CompiledFunction{
+		Instructions: bytecode.Instructions,
+		SourceMap:    bytecode.SourceMap,
+	}
+	mainClosure := &object.Closure{Fn: mainFn}
+	mainFrame := NewFrame(mainClosure, 0)
+
+	frames := make([]*Frame, initialFrameCount)
+	frames[0] = mainFrame
+
+	return &VM{
+		constants:   bytecode.Constants,
+		globals:     make([]object.Object, len(bytecode.GlobalNames)),
+		globalNames: bytecode.GlobalNames,
+
+		stack: make([]object.Object, initialStackSize),
+		sp:    0,
+
+		frames:      frames,
+		framesIndex: 1,
+	}
+}
+
+// Result returns the value the program finished with, nil if its last
+// statement didn't produce one
+func (vm *VM) Result() object.Object {
+	return vm.result
+}
+
+// Trace makes Run log every instruction it executes to w, with the source
+// line it came from and the top of the stack afterwards
+func (vm *VM) Trace(w io.Writer) {
+	vm.trace = w
+}
+
+func (vm *VM) currentFrame() *Frame {
+	return vm.frames[vm.framesIndex-1]
+}
+
+func (vm *VM) pushFrame(f *Frame) {
+	if vm.framesIndex == len(vm.frames) {
+		vm.frames = append(vm.frames, make([]*Frame, len(vm.frames))...)
+	}
+	vm.frames[vm.framesIndex] = f
+	vm.framesIndex++
+}
+
+func (vm *VM) popFrame() *Frame {
+	vm.framesIndex--
+	return vm.frames[vm.framesIndex]
+}
+
+func (vm *VM) Run() error {
+	var ip int
+	var ins code.Instructions
+	var op code.Opcode
+
+	for {
+		frame := vm.currentFrame()
+		frame.ip++
+		ip = frame.ip
+		ins = frame.Instructions()
+		if ip >= len(ins) {
+			return fmt.Errorf("instruction pointer out of range: %d", ip)
+		}
+		op = code.Opcode(ins[ip])
+		depth := vm.framesIndex
+		halted := false
+
+		switch op {
+		case code.OpConstant:
+			constIndex := code.ReadUint16(ins[ip+1:])
+			frame.ip += 2
+			if err := vm.push(vm.constants[constIndex]); err != nil {
+				return err
+			}
+
+		case code.OpAdd, code.OpSub, code.OpMul, code.OpDiv, code.OpMod,
+			code.OpEqual, code.OpNotEqual, code.OpGreaterThan, code.OpLessThan:
+			if err := vm.executeBinaryOperation(op); err != nil {
+				return err
+			}
+
+		case code.OpMinus:
+			operand := vm.pop()
+			integer, ok := operand.(*object.Integer)
+			if !ok {
+				return fmt.Errorf("unknown operator: -%s", operand.Type())
+			}
+			if err := vm.push(newInteger(-integer.Value)); err != nil {
+				return err
+			}
+
+		case code.OpBang:
+			operand := vm.pop()
+			if err := vm.push(nativeBoolToBooleanObject(!isTruthy(operand))); err != nil {
+				return err
+			}
+
+		case code.OpPop:
+			vm.pop()
+
+		case code.OpTrue:
+			if err := vm.push(True); err != nil {
+				return err
+			}
+
+		case code.OpFalse:
+			if err := vm.push(False); err != nil {
+				return err
+			}
+
+		case code.OpNull:
+			if err := vm.push(Null); err != nil {
+				return err
+			}
+
+		case code.OpJump:
+			pos := int(code.ReadUint16(ins[ip+1:]))
+			// the loop increments ip before reading the next opcode
+			frame.ip = pos - 1
+
+		case code.OpJumpNotTruthy:
+			pos := int(code.ReadUint16(ins[ip+1:]))
+			frame.ip += 2
+			condition := vm.pop()
+			if !isTruthy(condition) {
+				frame.ip = pos - 1
+			}
+
+		case code.OpJumpNotNull:
+			pos := int(code.ReadUint16(ins[ip+1:]))
+			frame.ip += 2
+			if _, isNull := vm.stack[vm.sp-1].(*object.Null); isNull {
+				vm.pop()
+			} else {
+				frame.ip = pos - 1
+			}
+
+		case code.OpSetGlobal:
+			globalIndex := code.ReadUint16(ins[ip+1:])
+			frame.ip += 2
+			vm.globals[globalIndex] = vm.pop()
+
+		case code.OpGetGlobal:
+			globalIndex := code.ReadUint16(ins[ip+1:])
+			frame.ip += 2
+			value := vm.globals[globalIndex]
+			if value == nil {
+				return fmt.Errorf("identifier not found: %s", vm.globalNames[globalIndex])
+			}
+			if err := vm.push(value); err != nil {
+				return err
+			}
+
+		case code.OpSetLocal:
+			localIndex := code.ReadUint8(ins[ip+1:])
+			frame.ip += 1
+			vm.stack[frame.basePointer+int(localIndex)] = vm.pop()
+
+		case code.OpGetLocal:
+			localIndex := code.ReadUint8(ins[ip+1:])
+			frame.ip += 1
+			value := vm.stack[frame.basePointer+int(localIndex)]
+			if value == nil {
+				// a let that was never run, e.g. in an if branch not taken
+				return fmt.Errorf("identifier not found: %s", frame.cl.Fn.LocalNames[localIndex])
+			}
+			if err := vm.push(value); err != nil {
+				return err
+			}
+
+		case code.OpGetFree:
+			freeIndex := code.ReadUint8(ins[ip+1:])
+			frame.ip += 1
+			if err := vm.push(frame.cl.Free[freeIndex]); err != nil {
+				return err
+			}
+
+		case code.OpCurrentClosure:
+			if err := vm.push(frame.cl); err != nil {
+				return err
+			}
+
+		case code.OpClosure:
+			constIndex := code.ReadUint16(ins[ip+1:])
+			numFree := code.ReadUint8(ins[ip+3:])
+			frame.ip += 3
+			if err := vm.pushClosure(int(constIndex), int(numFree)); err != nil {
+				return err
+			}
+
+		case code.OpArray:
+			numElements := int(code.ReadUint16(ins[ip+1:]))
+			frame.ip += 2
+			elements := make([]object.Object, numElements)
+			copy(elements, vm.stack[vm.sp-numElements:vm.sp])
+			vm.sp = vm.sp - numElements
+			if err := vm.push(&object.Array{Elements: elements}); err != nil {
+				return err
+			}
+
+		case code.OpHash:
+			numValues := int(code.ReadUint16(ins[ip+1:]))
+			frame.ip += 2
+			hash, err := vm.buildHash(vm.sp-numValues, vm.sp)
+			if err != nil {
+				return err
+			}
+			vm.sp = vm.sp - numValues
+			if err := vm.push(hash); err != nil {
+				return err
+			}
+
+		case code.OpIndex:
+			index := vm.pop()
+			left := vm.pop()
+			if err := vm.executeIndexExpression(left, index); err != nil {
+				return err
+			}
+
+		case code.OpGetBuiltin:
+			builtinIndex := code.ReadUint8(ins[ip+1:])
+			frame.ip += 1
+			builtin, _ := evaluator.LookupBuiltin(evaluator.BuiltinNames[builtinIndex])
+			if err := vm.push(builtin); err != nil {
+				return err
+			}
+
+		case code.OpCall:
+			numArgs := code.ReadUint8(ins[ip+1:])
+			frame.ip += 1
+			if err := vm.callFunction(int(numArgs)); err != nil {
+				return err
+			}
+
+		case code.OpReturnValue:
+			returnValue := vm.pop()
+			halted = vm.returnFromFrame(returnValue)
+
+		case code.OpReturn:
+			halted = vm.returnFromFrame(nil)
+
+		default:
+			return fmt.Errorf("unknown opcode %d", op)
+		}
+
+		if vm.trace != nil {
+			vm.traceInstruction(frame, ip, depth)
+		}
+		if halted {
+			return nil
+		}
+	}
+}
+
+// traceInstruction logs the instruction of frame at ip, which has just
+// been executed. instructions are indented by how deep the call they are
+// in is
+func (vm *VM) traceInstruction(frame *Frame, ip int, depth int) {
+	ins := frame.Instructions()
+	def, operands, _, err := code.ReadInstruction(ins, ip)
+	if err != nil {
+		return
+	}
+
+	top := "-"
+	if vm.result != nil {
+		top = vm.result.Inspect()
+	} else if vm.sp > 0 && vm.stack[vm.sp-1] != nil {
+		// right after a call the top can be a local that isn't set yet
+		top = vm.stack[vm.sp-1].Inspect()
+	}
+	top = strings.ReplaceAll(top, "\n", " ")
+
+	indent := strings.Repeat("  ", depth-1)
+	fmt.Fprintf(vm.trace, "%s%04d %-24s line %-4d top: %s\n",
+		indent, ip, code.FormatInstruction(def, operands), frame.cl.Fn.SourceMap.Line(ip), top)
+}
+
+// returnFromFrame leaves the current function and pushes its value for the
+// caller, or null if it has none. It returns true when the main program
+// returned, which stops the vm with value as its result
+func (vm *VM) returnFromFrame(value object.Object) bool {
+	frame := vm.popFrame()
+	if vm.framesIndex == 0 {
+		vm.result = value
+		return true
+	}
+	if value == nil {
+		value = Null
+	}
+	// drops the locals and the function itself
+	vm.sp = frame.basePointer - 1
+	vm.stack[vm.sp] = value
+	vm.sp++
+	return false
+}
+
+func (vm *VM) callFunction(numArgs int) error {
+	callee := vm.stack[vm.sp-1-numArgs]
+	if builtin, ok := callee.(*object.Builtin); ok {
+		return vm.callBuiltin(builtin, numArgs)
+	}
+	cl, ok := callee.(*object.Closure)
+	if !ok {
+		return fmt.Errorf("not a function: %s", callee.Type())
+	}
+
+	if numArgs != cl.Fn.NumParameters {
+		return fmt.Errorf("wrong number of arguments to %s: want=%d, got=%d",
+			signature(cl.Fn), cl.Fn.NumParameters, numArgs)
+	}
+
+	// the main program has a frame too, it isn't a call
+	if vm.framesIndex-1 >= evaluator.DefaultMaxCallDepth {
+		return fmt.Errorf("maximum call depth exceeded")
+	}
+
+	frame := NewFrame(cl, vm.sp-numArgs)
+	vm.pushFrame(frame)
+
+	// locals that aren't parameters start out unset
+	vm.grow(frame.basePointer + cl.Fn.NumLocals)
+	for i := vm.sp; i < frame.basePointer+cl.Fn.NumLocals; i++ {
+		vm.stack[i] = nil
+	}
+	vm.sp = frame.basePointer + cl.Fn.NumLocals
+	return nil
+}
+
+// callBuiltin runs a builtin of the evaluator on the arguments on the
+// stack and replaces them and the builtin with its result
+func (vm *VM) callBuiltin(builtin *object.Builtin, numArgs int) error {
+	args := make([]object.Object, numArgs)
+	copy(args, vm.stack[vm.sp-numArgs:vm.sp])
+	vm.sp = vm.sp - numArgs - 1
+
+	result := builtin.Fn(args...)
+	if err, ok := result.(*object.Error); ok {
+		return errors.New(err.Message)
+	}
+	return vm.push(fromEvaluator(result))
+}
+
+// fromEvaluator swaps the booleans and null of the evaluator for the ones
+// of the vm, == compares them by pointer
+func fromEvaluator(obj object.Object) object.Object {
+	switch obj := obj.(type) {
+	case *object.Boolean:
+		return nativeBoolToBooleanObject(obj.Value)
+	case *object.Null:
+		return Null
+	default:
+		return obj
+	}
+}
+
+// signature shows a function the way the evaluator does in errors, with
+// the names of its parameters, the first locals
+func signature(fn *object.CompiledFunction) string {
+	name := fn.Name
+	if name == "" {
+		name = "fn"
+	}
+	params := fn.LocalNames
+	if len(params) > fn.NumParameters {
+		params = params[:fn.NumParameters]
+	}
+	return name + "(" + strings.Join(params, ", ") + ")"
+}
+
+func (vm *VM) pushClosure(constIndex int, numFree int) error {
+	constant := vm.constants[constIndex]
+	function, ok := constant.(*object.CompiledFunction)
+	if !ok {
+		return fmt.Errorf("not a function: %+v", constant)
+	}
+
+	free := make([]object.Object, numFree)
+	for i := 0; i < numFree; i++ {
+		free[i] = vm.stack[vm.sp-numFree+i]
+	}
+	vm.sp = vm.sp - numFree
+
+	closure := &object.Closure{Fn: function, Free: free}
+	return vm.push(closure)
+}
+
+func (vm *VM) executeBinaryOperation(op code.Opcode) error {
+	right := vm.pop()
+	left := vm.pop()
+
+	leftInteger, leftOk := left.(*object.Integer)
+	rightInteger, rightOk := right.(*object.Integer)
+
+	leftString, leftIsString := left.(*object.String)
+	rightString, rightIsString := right.(*object.String)
+
+	switch {
+	case leftOk && rightOk:
+		return vm.executeIntegerOperation(op, leftInteger.Value, rightInteger.Value)
+	case leftIsString && rightIsString:
+		return vm.executeStringOperation(op, leftString.Value, rightString.Value)
+	case op == code.OpEqual:
+		return vm.push(nativeBoolToBooleanObject(left == right))
+	case op == code.OpNotEqual:
+		return vm.push(nativeBoolToBooleanObject(left != right))
+	case left.Type() != right.Type():
+		return fmt.Errorf("type mismatch: %s %s %s", left.Type(), operators[op], right.Type())
+	default:
+		return fmt.Errorf("unknown operator: %s %s %s", left.Type(), operators[op], right.Type())
+	}
+}
+
+// the source operator of each binary opcode, for error messages
+var operators = map[code.Opcode]string{
+	code.OpAdd:         "+",
+	code.OpSub:         "-",
+	code.OpMul:         "*",
+	code.OpDiv:         "/",
+	code.OpMod:         "%",
+	code.OpEqual:       "==",
+	code.OpNotEqual:    "!=",
+	code.OpGreaterThan: ">",
+	code.OpLessThan:    "<",
+}
+
+func (vm *VM) executeIntegerOperation(op code.Opcode, left int64, right int64) error {
+	switch op {
+	case code.OpAdd:
+		return vm.push(newInteger(left + right))
+	case code.OpSub:
+		return vm.push(newInteger(left - right))
+	case code.OpMul:
+		return vm.push(newInteger(left * right))
+	case code.OpDiv:
+		if right == 0 {
+			return fmt.Errorf("division by zero")
+		}
+		return vm.push(newInteger(left / right))
+	case code.OpMod:
+		if right == 0 {
+			return fmt.Errorf("division by zero")
+		}
+		return vm.push(newInteger(left % right))
+	case code.OpEqual:
+		return vm.push(nativeBoolToBooleanObject(left == right))
+	case code.OpNotEqual:
+		return vm.push(nativeBoolToBooleanObject(left != right))
+	case code.OpGreaterThan:
+		return vm.push(nativeBoolToBooleanObject(left > right))
+	case code.OpLessThan:
+		return vm.push(nativeBoolToBooleanObject(left < right))
+	default:
+		return fmt.Errorf("unknown integer operator: %d", op)
+	}
+}
+
+func (vm *VM) executeStringOperation(op code.Opcode, left string, right string) error {
+	switch op {
+	case code.OpAdd:
+		return vm.push(&object.String{Value: left + right})
+	case code.OpEqual:
+		return vm.push(nativeBoolToBooleanObject(left == right))
+	case code.OpNotEqual:
+		return vm.push(nativeBoolToBooleanObject(left != right))
+	default:
+		return fmt.Errorf("unknown operator: %s %s %s", object.STRING_OBJ, operators[op], object.STRING_OBJ)
+	}
+}
+
+// buildHash makes a hash of the keys and values in stack[start:end]. a key
+// that is already in the hash replaces its value
+func (vm *VM) buildHash(start, end int) (*object.Hash, error) {
+	hash := object.NewHash()
+	for i := start; i < end; i += 2 {
+		key, ok := vm.stack[i].(object.Hashable)
+		if !ok {
+			return nil, fmt.Errorf("unusable as hash key: %s", vm.stack[i].Type())
+		}
+		hash.Set(key, vm.stack[i+1])
+	}
+	return hash, nil
+}
+
+// like in the evaluator, indexes outside an array and keys that aren't in
+// a hash give null
+func (vm *VM) executeIndexExpression(left, index object.Object) error {
+	switch left := left.(type) {
+	case *object.Array:
+		i, ok := index.(*object.Integer)
+		if !ok {
+			break
+		}
+		if i.Value < 0 || i.Value >= int64(len(left.Elements)) {
+			return vm.push(Null)
+		}
+		return vm.push(left.Elements[i.Value])
+	case *object.Hash:
+		key, ok := index.(object.Hashable)
+		if !ok {
+			return fmt.Errorf("unusable as hash key: %s", index.Type())
+		}
+		pair, ok := left.Pairs[key.HashKey()]
+		if !ok {
+			return vm.push(Null)
+		}
+		return vm.push(pair.Value)
+	}
+	return fmt.Errorf("index operator not supported: %s[%s]", left.Type(), index.Type())
+}
+
+func nativeBoolToBooleanObject(input bool) *object.Boolean {
+	if input {
+		return True
+	}
+	return False
+}
+
+func isTruthy(obj object.Object) bool {
+	switch obj := obj.(type) {
+	case *object.Boolean:
+		return obj.Value
+	case *object.Null:
+		return false
+	default:
+		return true
+	}
+}
+
+// grow makes sure the stack has at least size slots
+func (vm *VM) grow(size int) {
+	for size > len(vm.stack) {
+		vm.stack = append(vm.stack, make([]object.Object, len(vm.stack))...)
+	}
+}
+
+func (vm *VM) push(o object.Object) error {
+	vm.grow(vm.sp + 1)
+	vm.stack[vm.sp] = o
+	vm.sp++
+	return nil
+}
+
+func (vm *VM) pop() object.Object {
+	o := vm.stack[vm.sp-1]
+	vm.sp--
+	return o
+}
@@ -0,0 +1,327 @@
+package vm
+
+import (
+	"Go-interpreter/ast"
+	"Go-interpreter/compiler"
+	"Go-interpreter/evaluator"
+	"Go-interpreter/lexer"
+	"Go-interpreter/object"
+	"Go-interpreter/parser"
+	"bytes"
+	"testing"
+)
+
+func parse(input string) *ast.Program {
+	l := lexer.New(input)
+	p := parser.New(l)
+	return p.ParseProgram()
+}
+
+// run compiles and runs input. the result is the Inspect of the value or
+// the error, the way the evaluator prints them. errors the compiler finds
+// in the program count too
+func run(t *testing.T, input string) string {
+	t.Helper()
+
+	comp := compiler.New()
+	if err := comp.Compile(parse(input)); err != nil {
+		return "ERROR: " + err.Error()
+	}
+
+	machine := New(comp.Bytecode())
+	if err := machine.Run(); err != nil {
+		return "ERROR: " + err.Error()
+	}
+	if machine.Result() == nil {
+		return "<none>"
+	}
+	return machine.Result().Inspect()
+}
+
+func eval(input string) string {
+	result := evaluator.Eval(parse(input), object.NewEnvironment())
+	if result == nil {
+		return "<none>"
+	}
+	return result.Inspect()
+}
+
+type vmTestCase struct {
+	input    string
+	expected string
+}
+
+func runVmTests(t *testing.T, tests []vmTestCase) {
+	t.Helper()
+
+	for _, tt := range tests {
+		if got := run(t, tt.input); got != tt.expected {
+			t.Errorf("%q: want=%q, got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestIntegerArithmetic(t *testing.T) {
+	runVmTests(t, []vmTestCase{
+		{"1", "1"},
+		{"1 + 2", "3"},
+		{"1 - 2", "-1"},
+		{"50 / 2 * 2 + 10 - 5", "55"},
+		{"7 % 3", "1"},
+		{"-5 + 10", "5"},
+		{"(5 + 10 * 2 + 15 / 3) * 2 + -10", "50"},
+		{"100000 * 100000", "10000000000"},
+	})
+}
+
+func TestBooleanExpressions(t *testing.T) {
+	runVmTests(t, []vmTestCase{
+		{"true", "true"},
+		{"1 < 2", "true"},
+		{"1 > 2", "false"},
+		{"1 == 1", "true"},
+		{"1 != 1", "false"},
+		{"true == false", "false"},
+		{"(1 < 2) == true", "true"},
+		{"!5", "false"},
+		{"!!true", "true"},
+		{"!(if (false) { 5; })", "true"},
+	})
+}
+
+func TestConditionals(t *testing.T) {
+	runVmTests(t, []vmTestCase{
+		{"if (true) { 10 }", "10"},
+		{"if (1 > 2) { 10 } else { 20 }", "20"},
+		{"if (1 > 2) { 10 }", "Null"},
+		{"if (true) { let a = 1; }", "Null"},
+		{"if ((if (false) { 10 })) { 10 } else { 20 }", "20"},
+	})
+}
+
+func TestGlobalLetStatements(t *testing.T) {
+	runVmTests(t, []vmTestCase{
+		{"let one = 1; one", "1"},
+		{"let one = 1; let two = one + one; one + two", "3"},
+		{"let a = 1;", "<none>"},
+		{"let a = 1; let a = a + 1; a", "2"},
+	})
+}
+
+func TestCallingFunctions(t *testing.T) {
+	runVmTests(t, []vmTestCase{
+		{"let f = fn() { 5 + 10; }; f();", "15"},
+		{"let f = fn() { return 99; 100; }; f();", "99"},
+		{"let f = fn() { }; f();", "Null"},
+		{"let f = fn(a, b) { let c = a + b; c }; f(1, 2);", "3"},
+		{"let f = fn(a) { let a = a * 2; a }; f(2);", "4"},
+		{"fn(x) { x }(7)", "7"},
+		{"let f = fn(a, b) { a + b }; f", "fn(a, b) {\n(a + b)\n}"},
+	})
+}
+
+func TestClosuresAndRecursion(t *testing.T) {
+	runVmTests(t, []vmTestCase{
+		{"let newAdder = fn(a) { fn(b) { a + b } }; newAdder(2)(3)", "5"},
+		{`let newClosure = fn(a, b) {
+			let one = fn() { a; };
+			let two = fn() { b; };
+			fn() { one() + two(); };
+		};
+		newClosure(9, 90)();`, "99"},
+		{`let fib = fn(n) { if (n < 2) { return n; } fib(n - 1) + fib(n - 2) };
+		fib(15)`, "610"},
+		{`let wrapper = fn() {
+			let countDown = fn(x) { if (x == 0) { return 0; } countDown(x - 1) };
+			countDown(1);
+		};
+		wrapper();`, "0"},
+	})
+}
+
+func TestDeepRecursion(t *testing.T) {
+	// the stack and the frames grow instead of overflowing
+	runVmTests(t, []vmTestCase{
+		{`let sum = fn(n) { if (n == 0) { 0 } else { n + sum(n - 1) } }; sum(5000)`, "12502500"},
+	})
+}
+
+func TestRuntimeErrors(t *testing.T) {
+	runVmTests(t, []vmTestCase{
+		{"5 + true;", "ERROR: type mismatch: INTEGER + BOOLEAN"},
+		{"-true", "ERROR: unknown operator: -BOOLEAN"},
+		{"true + false; 5", "ERROR: unknown operator: BOOLEAN + BOOLEAN"},
+		{"1 / 0", "ERROR: division by zero"},
+		{"1 % 0", "ERROR: division by zero"},
+		{"foobar", "ERROR: identifier not found: foobar"},
+		{"let f = fn() { if (false) { let x = 1; } x }; f()", "ERROR: identifier not found: x"},
+		{"5()", "ERROR: not a function: INTEGER"},
+		{"fn(a) { a }()", "ERROR: wrong number of arguments to fn(a): want=1, got=0"},
+		{"let f = fn(a, b) { a }; f(1)", "ERROR: wrong number of arguments to f(a, b): want=2, got=1"},
+		{"let f = fn(n) { f(n + 1) + 1 }; f(0)", "ERROR: maximum call depth exceeded"},
+		{`"a" - "b"`, "ERROR: unknown operator: STRING - STRING"},
+		{"{[1]: 2}", "ERROR: unusable as hash key: ARRAY"},
+		{"1[0]", "ERROR: index operator not supported: INTEGER[INTEGER]"},
+		{"len(1)", "ERROR: argument to `len` not supported, got INTEGER"},
+	})
+}
+
+// the vm is meant to be a drop-in replacement for the evaluator
+func TestParityWithEvaluator(t *testing.T) {
+	inputs := []string{
+		"1 + 2 * 3 - 4 / 2 % 3",
+		"!(1 < 2) == false",
+		"if (0) { 1 } else { 2 }",
+		"if (false) { 1 }",
+		"let x = 5; let y = x * 2; x + y",
+		"let x = 5;",
+		"",
+		"return 1; 2",
+		"if (true) { if (true) { return 10; } return 1; }",
+		"let f = fn(x) { return x; x + 10; }; f(1)",
+		"let add = fn(a) { fn(b) { fn(c) { a + b + c } } }; add(1)(2)(3)",
+		"let f = fn(x) { x }; f",
+		"let f = fn() { }; f()",
+		"let fact = fn(n) { if (n < 1) { 1 } else { n * fact(n - 1) } }; fact(10)",
+		"let x = 1; let f = fn() { x }; let x = 2; f()",
+		"5 + true",
+		"5 + true; 5",
+		"true * false",
+		"-fn() { }",
+		"10 / (5 - 5)",
+		"missing + 1",
+		"let f = fn(a) { a }; f(1, 2)",
+		"true()",
+		"let f = fn() { 1 / 0; 5 }; f()",
+		"1 == true",
+		"true == true",
+		"fn() { } == fn() { }",
+		"let x = 1; if (true) { let x = 2; x }",
+		"let x = 1; if (true) { let x = 2; }; x",
+		"if (true) { let y = 2; }; y",
+		"if (true) { y }; let y = 3;",
+		"let f = fn(x) { if (x) { let y = 10; y } else { 0 } }; f(true) + f(false)",
+		"let f = fn() { let a = 1; if (true) { let a = 2; fn() { a } } }; f()()",
+		"let f = fn() { let a = 1; if (true) { let a = 2; }; fn() { a } }; f()()",
+		"1 > 2 ? 1 : 2 > 1 ? 3 : 4",
+		"null ? 1 : 2",
+		"let f = fn(n) { n < 2 ? n : f(n - 1) + f(n - 2) }; f(10)",
+		"null",
+		"null == null",
+		"const X = 1; const X = 2; X",
+		"const X = 1; let X = 2; X",
+		"let X = 1; const X = 2; X",
+		"const X = 1; if (true) { const X = 2; X }",
+		"const X = 1; if (true) { const X = 2; }; X",
+		"const X = 1; let f = fn() { const X = 2; X }; f() + X",
+		"let f = fn() { const a = 1; const a = 2; a }; f()",
+		"null ?? 5",
+		"1 ?? 5",
+		"false ?? 5",
+		"null ?? null ?? 3",
+		"let f = fn() { 1 / 0 }; 1 ?? f()",
+		"let f = fn() { 1 / 0 }; null ?? f()",
+		`let h = {"a": 1}; h["b"] ?? h["a"]`,
+		"let f = fn(x) { x ?? 0 }; f(null) + f(2)",
+		"let f = fn(n) { f(n + 1) + 1 }; f(0)",
+		"let f = fn(n) { if (n == 0) { 0 } else { 1 + f(n - 1) } }; f(9999)",
+		`"a"`,
+		`"mon" + "key"`,
+		`"a" == "a"`,
+		`"a" != "a"`,
+		`"a" < "b"`,
+		`"a" + 1`,
+		"[1, 2 * 2, [3]]",
+		"[1, 2][1]",
+		"[1, 2][2]",
+		"[1, 2][-1]",
+		"[1][true]",
+		`{"a": 1, 2: true, false: "c"}`,
+		`{"a": 1, "a": 2}`,
+		`{"a": 1}["a"]`,
+		`{"a": 1}["b"]`,
+		`{"a": 1}[fn() { }]`,
+		`let h = {"k": fn(x) { x * 2 }}; h["k"](21)`,
+		`len("abc") + len([1, 2]) + len({"a": 1})`,
+		"let a = [1, 2, 3]; [first(a), last(a), rest(a), push(a, 4), a]",
+		"first([])",
+		"rest([])",
+		"is_err(err(1)) == true",
+		"unwrap(ok(1))",
+		"unwrap(err(1))",
+		"len(range(2, 5))",
+		"len",
+		"let len = fn(x) { 0 }; len([1])",
+		"let f = fn(xs) { if (len(xs) == 0) { 0 } else { first(xs) + f(rest(xs)) } }; f([1, 2, 3])",
+		"len(1, 2)",
+	}
+
+	for _, input := range inputs {
+		want := eval(input)
+		got := run(t, input)
+		if got != want {
+			t.Errorf("%q: evaluator=%q, vm=%q", input, want, got)
+		}
+	}
+}
+
+func TestTrace(t *testing.T) {
+	input := `let f = fn(x) {
+	x * 2
+};
+f(3)`
+
+	comp := compiler.New()
+	if err := comp.Compile(parse(input)); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+
+	var out bytes.Buffer
+	machine := New(comp.Bytecode())
+	machine.Trace(&out)
+	if err := machine.Run(); err != nil {
+		t.Fatalf("vm error: %s", err)
+	}
+
+	expected := `0000 OpClosure 1 0            line 1    top: fn(x) { (x * 2) }
+0004 OpSetGlobal 0            line 1    top: -
+0007 OpGetGlobal 0            line 4    top: fn(x) { (x * 2) }
+0010 OpConstant 2             line 4    top: 3
+0013 OpCall 1                 line 4    top: 3
+  0000 OpGetLocal 0             line 2    top: 3
+  0002 OpConstant 0             line 2    top: 2
+  0005 OpMul                    line 2    top: 6
+  0006 OpReturnValue            line 2    top: 6
+0015 OpReturnValue            line 4    top: 6
+`
+	if out.String() != expected {
+		t.Errorf("wrong trace.\nwant=\n%s\ngot=\n%s", expected, out.String())
+	}
+}
+
+func TestRunLoadedBytecode(t *testing.T) {
+	input := `let makeCounter = fn(start) { fn(step) { start + step } };
+let counter = makeCounter(40);
+counter(2)`
+
+	comp := compiler.New()
+	if err := comp.Compile(parse(input)); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+	data, err := comp.Bytecode().MarshalBinary()
+	if err != nil {
+		t.Fatalf("marshal error: %s", err)
+	}
+
+	var loaded compiler.Bytecode
+	if err := loaded.UnmarshalBinary(data); err != nil {
+		t.Fatalf("unmarshal error: %s", err)
+	}
+	machine := New(&loaded)
+	if err := machine.Run(); err != nil {
+		t.Fatalf("vm error: %s", err)
+	}
+	if got := machine.Result().Inspect(); got != "42" {
+		t.Errorf("wrong result. want=42, got=%s", got)
+	}
+}